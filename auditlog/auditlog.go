@@ -0,0 +1,179 @@
+// Package auditlog writes a structured record of every change takethe53
+// submits to Route53, for ingestion by log pipelines and SIEMs.
+package auditlog
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/ryane/takethe53/awsclient"
+)
+
+// SchemaVersion is the version of the Record schema. Fields are only ever
+// added within a version.
+const SchemaVersion = 1
+
+// Results of a change.
+const (
+	ResultSuccess = "success"
+	ResultError   = "error"
+)
+
+// Record is a single change batch submitted to Route53.
+type Record struct {
+	SchemaVersion int       `json:"schema_version"`
+	ID            string    `json:"id"`
+	Time          time.Time `json:"time"`
+	Caller        Caller    `json:"caller"`
+	// Command is what triggered the change, e.g. "takethe53 create www
+	// example.com my-elb" or "POST /v1/aliases".
+	Command      string   `json:"command"`
+	Op           string   `json:"op"`
+	ZoneID       string   `json:"zone_id"`
+	ZoneName     string   `json:"zone_name"`
+	ChangeBatch  []Change `json:"change_batch"`
+	Result       string   `json:"result"`
+	Error        string   `json:"error,omitempty"`
+	ChangeID     string   `json:"change_id,omitempty"`
+	ChangeStatus string   `json:"change_status,omitempty"`
+}
+
+// Caller identifies who made a change.
+type Caller struct {
+	// ARN and Account are the AWS principal, from STS GetCallerIdentity.
+	ARN     string `json:"arn,omitempty"`
+	Account string `json:"account,omitempty"`
+	// User is the local user running takethe53.
+	User string `json:"user,omitempty"`
+	// Token is the name of the server API token of the request.
+	Token string `json:"token,omitempty"`
+}
+
+// Change is a single change of a batch.
+type Change struct {
+	Action        string       `json:"action"`
+	Name          string       `json:"name"`
+	Type          string       `json:"type"`
+	SetIdentifier string       `json:"set_identifier,omitempty"`
+	TTL           int64        `json:"ttl,omitempty"`
+	Values        []string     `json:"values,omitempty"`
+	AliasTarget   *AliasTarget `json:"alias_target,omitempty"`
+}
+
+type AliasTarget struct {
+	DNSName              string `json:"dns_name"`
+	HostedZoneID         string `json:"hosted_zone_id"`
+	EvaluateTargetHealth bool   `json:"evaluate_target_health"`
+}
+
+// Sink stores audit records. Implementations must be safe for concurrent
+// use.
+type Sink interface {
+	Write(line []byte) error
+	Close() error
+}
+
+// Logger writes a record for every change of a client.
+type Logger struct {
+	sink Sink
+
+	// Caller, if set, returns the identity of whoever made the change in
+	// ctx.
+	Caller func(ctx context.Context) Caller
+	// Command, if set, returns what triggered the change in ctx.
+	Command func(ctx context.Context) string
+}
+
+func New(sink Sink) *Logger {
+	return &Logger{sink: sink}
+}
+
+// Handler returns a change handler that records every change, successful or
+// not. Errors are passed to onError so a broken audit log never fails a
+// change that Route53 already accepted.
+func (l *Logger) Handler(onError func(error)) awsclient.ChangeHandler {
+	return func(ctx context.Context, ev *awsclient.ChangeEvent) {
+		if err := l.Write(l.NewRecord(ctx, ev)); err != nil && onError != nil {
+			onError(err)
+		}
+	}
+}
+
+// NewRecord converts a change of the client.
+func (l *Logger) NewRecord(ctx context.Context, ev *awsclient.ChangeEvent) *Record {
+	r := &Record{
+		SchemaVersion: SchemaVersion,
+		ID:            newID(),
+		Time:          ev.Time.UTC(),
+		Op:            ev.Op,
+		ZoneID:        ev.Zone.ID,
+		ZoneName:      ev.Zone.Name,
+		ChangeBatch:   changes(ev.Changes),
+		Result:        ResultSuccess,
+	}
+	if l.Caller != nil {
+		r.Caller = l.Caller(ctx)
+	}
+	if l.Command != nil {
+		r.Command = l.Command(ctx)
+	}
+	if ev.Err != nil {
+		r.Result = ResultError
+		r.Error = ev.Err.Error()
+	}
+	if ev.Status != nil {
+		r.ChangeID = ev.Status.ID
+		r.ChangeStatus = ev.Status.Status
+	}
+	return r
+}
+
+// Write appends a record to the sink as a single line of JSON.
+func (l *Logger) Write(r *Record) error {
+	line, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	return l.sink.Write(append(line, '\n'))
+}
+
+func (l *Logger) Close() error {
+	return l.sink.Close()
+}
+
+func changes(batch []*route53.Change) []Change {
+	out := []Change{}
+	for _, c := range batch {
+		rrs := c.ResourceRecordSet
+		change := Change{
+			Action:        aws.StringValue(c.Action),
+			Name:          aws.StringValue(rrs.Name),
+			Type:          aws.StringValue(rrs.Type),
+			SetIdentifier: aws.StringValue(rrs.SetIdentifier),
+			TTL:           aws.Int64Value(rrs.TTL),
+		}
+		for _, rr := range rrs.ResourceRecords {
+			change.Values = append(change.Values, aws.StringValue(rr.Value))
+		}
+		if rrs.AliasTarget != nil {
+			change.AliasTarget = &AliasTarget{
+				DNSName:              aws.StringValue(rrs.AliasTarget.DNSName),
+				HostedZoneID:         aws.StringValue(rrs.AliasTarget.HostedZoneId),
+				EvaluateTargetHealth: aws.BoolValue(rrs.AliasTarget.EvaluateTargetHealth),
+			}
+		}
+		out = append(out, change)
+	}
+	return out
+}
+
+func newID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,129 @@
+package auditlog
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/ryane/takethe53/awsclient"
+	"github.com/ryane/takethe53/awsclient/fake"
+	"github.com/stretchr/testify/assert"
+)
+
+type callerKey struct{}
+
+func TestHandler(t *testing.T) {
+	dir, err := ioutil.TempDir("", "auditlog")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "audit.jsonl")
+	sink, err := Open(path)
+	assert.Nil(t, err)
+
+	logger := New(sink)
+	logger.Caller = func(ctx context.Context) Caller {
+		token, _ := ctx.Value(callerKey{}).(string)
+		return Caller{ARN: "arn:aws:iam::123456789012:user/ryan", Account: "123456789012", User: "ryan", Token: token}
+	}
+	logger.Command = func(ctx context.Context) string {
+		return "takethe53 create www example.com web-1"
+	}
+
+	r53 := fake.NewRoute53()
+	zoneID, _ := r53.AddZone("example.com")
+	client := awsclient.NewWithServices(r53, fake.NewELB())
+	client.AddChangeHandler(logger.Handler(func(err error) {
+		t.Error(err)
+	}))
+	zone := &awsclient.Zone{ID: zoneID, Name: "example.com."}
+
+	ctx := context.WithValue(context.Background(), callerKey{}, "deploy")
+	change, err := client.SetAliasWithOptions(ctx, zone, "Z35SXDOTRQ7X7K", "web-1.us-east-1.elb.amazonaws.com", "www", awsclient.AliasOptions{})
+	assert.Nil(t, err)
+
+	// fails, the record does not exist
+	_, err = client.ChangeRecordSets(context.Background(), zone, "delete", []*route53.Change{{
+		Action: aws.String(route53.ChangeActionDelete),
+		ResourceRecordSet: &route53.ResourceRecordSet{
+			Name:            aws.String("old.example.com."),
+			Type:            aws.String("CNAME"),
+			TTL:             aws.Int64(300),
+			ResourceRecords: []*route53.ResourceRecord{{Value: aws.String("example.org")}},
+		},
+	}})
+	assert.NotNil(t, err)
+
+	assert.Nil(t, logger.Close())
+
+	f, err := os.Open(path)
+	assert.Nil(t, err)
+	defer f.Close()
+
+	var records []*Record
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var r Record
+		assert.Nil(t, json.Unmarshal(scanner.Bytes(), &r))
+		records = append(records, &r)
+	}
+	assert.Len(t, records, 2)
+
+	r := records[0]
+	assert.Equal(t, SchemaVersion, r.SchemaVersion)
+	assert.NotEmpty(t, r.ID)
+	assert.WithinDuration(t, time.Now(), r.Time, time.Minute)
+	assert.Equal(t, Caller{ARN: "arn:aws:iam::123456789012:user/ryan", Account: "123456789012", User: "ryan", Token: "deploy"}, r.Caller)
+	assert.Equal(t, "takethe53 create www example.com web-1", r.Command)
+	assert.Equal(t, awsclient.OpSetAlias, r.Op)
+	assert.Equal(t, zoneID, r.ZoneID)
+	assert.Equal(t, "example.com.", r.ZoneName)
+	assert.Equal(t, []Change{{
+		Action: "UPSERT",
+		Name:   "www.example.com.",
+		Type:   "A",
+		AliasTarget: &AliasTarget{
+			DNSName:              "web-1.us-east-1.elb.amazonaws.com",
+			HostedZoneID:         "Z35SXDOTRQ7X7K",
+			EvaluateTargetHealth: true,
+		},
+	}}, r.ChangeBatch)
+	assert.Equal(t, ResultSuccess, r.Result)
+	assert.Equal(t, change.ID, r.ChangeID)
+	assert.Equal(t, change.Status, r.ChangeStatus)
+
+	r = records[1]
+	assert.Equal(t, ResultError, r.Result)
+	assert.NotEmpty(t, r.Error)
+	assert.Empty(t, r.ChangeID)
+	assert.Equal(t, []Change{{Action: "DELETE", Name: "old.example.com.", Type: "CNAME", TTL: 300, Values: []string{"example.org"}}}, r.ChangeBatch)
+}
+
+func TestFileSinkAppends(t *testing.T) {
+	dir, err := ioutil.TempDir("", "auditlog")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "audit.jsonl")
+	for _, line := range []string{"one\n", "two\n"} {
+		sink, err := NewFileSink(path)
+		assert.Nil(t, err)
+		assert.Nil(t, sink.Write([]byte(line)))
+		assert.Nil(t, sink.Close())
+	}
+
+	b, err := ioutil.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "one\ntwo\n", string(b))
+
+	info, err := os.Stat(path)
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+}
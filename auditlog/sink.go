@@ -0,0 +1,88 @@
+package auditlog
+
+import (
+	"errors"
+	"io"
+	"net/url"
+	"os"
+	"sync"
+)
+
+var ErrSyslogUnsupported = errors.New("Syslog is not supported on this platform.")
+
+// Open returns the sink for location:
+//
+//	stdout                  standard output
+//	syslog                  the local syslog daemon
+//	syslog://host:514       a remote syslog server over UDP
+//	syslog+tcp://host:514   a remote syslog server over TCP
+//	anything else           a file, appended to
+func Open(location string) (Sink, error) {
+	switch location {
+	case "stdout", "-":
+		return NewWriterSink(os.Stdout), nil
+	case "syslog":
+		return NewSyslogSink("", "")
+	}
+
+	if u, err := url.Parse(location); err == nil {
+		switch u.Scheme {
+		case "syslog", "syslog+udp":
+			return NewSyslogSink("udp", u.Host)
+		case "syslog+tcp":
+			return NewSyslogSink("tcp", u.Host)
+		}
+	}
+
+	return NewFileSink(location)
+}
+
+// FileSink appends records to a file and syncs it after every record.
+type FileSink struct {
+	mu sync.Mutex
+	f  *os.File
+}
+
+func NewFileSink(path string) (*FileSink, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &FileSink{f: f}, nil
+}
+
+func (s *FileSink) Write(line []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.f.Write(line); err != nil {
+		return err
+	}
+	return s.f.Sync()
+}
+
+func (s *FileSink) Close() error {
+	return s.f.Close()
+}
+
+// WriterSink writes records to a writer, e.g. standard output.
+type WriterSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewWriterSink(w io.Writer) *WriterSink {
+	return &WriterSink{w: w}
+}
+
+func (s *WriterSink) Write(line []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.w.Write(line)
+	return err
+}
+
+func (s *WriterSink) Close() error {
+	return nil
+}
@@ -0,0 +1,33 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package auditlog
+
+import (
+	"bytes"
+	"log/syslog"
+)
+
+// SyslogSink sends records to syslog.
+type SyslogSink struct {
+	w *syslog.Writer
+}
+
+// NewSyslogSink connects to the syslog server at raddr, or to the local
+// syslog daemon if network is empty.
+func NewSyslogSink(network, raddr string) (Sink, error) {
+	w, err := syslog.Dial(network, raddr, syslog.LOG_NOTICE|syslog.LOG_AUTH, "takethe53")
+	if err != nil {
+		return nil, err
+	}
+	return &SyslogSink{w: w}, nil
+}
+
+func (s *SyslogSink) Write(line []byte) error {
+	_, err := s.w.Write(bytes.TrimSuffix(line, []byte("\n")))
+	return err
+}
+
+func (s *SyslogSink) Close() error {
+	return s.w.Close()
+}
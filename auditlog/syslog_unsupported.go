@@ -0,0 +1,8 @@
+//go:build windows || plan9
+// +build windows plan9
+
+package auditlog
+
+func NewSyslogSink(network, raddr string) (Sink, error) {
+	return nil, ErrSyslogUnsupported
+}
@@ -0,0 +1,173 @@
+package awsclient
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+)
+
+const (
+	cacheKeyZones         = "zones"
+	cacheKeyLoadBalancers = "loadbalancers"
+	cacheKeyRecordSets    = "rrsets:"
+)
+
+// Cache stores the results of lookups that are expensive to repeat, like
+// listing every hosted zone or load balancer. Implementations expire entries
+// on their own.
+type Cache interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, value []byte)
+	Delete(key string)
+}
+
+type memoryCacheEntry struct {
+	value   []byte
+	expires time.Time
+}
+
+// MemoryCache is a Cache for long running processes like the server.
+type MemoryCache struct {
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[string]memoryCacheEntry
+}
+
+func NewMemoryCache(ttl time.Duration) *MemoryCache {
+	return &MemoryCache{ttl: ttl, entries: map[string]memoryCacheEntry{}}
+}
+
+func (c *MemoryCache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(e.expires) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return e.value, true
+}
+
+func (c *MemoryCache) Set(key string, value []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[key] = memoryCacheEntry{value: value, expires: time.Now().Add(c.ttl)}
+}
+
+func (c *MemoryCache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.entries, key)
+}
+
+// FileCache is a Cache that survives between CLI invocations. Every entry is
+// a file in dir and expires ttl after it was written.
+type FileCache struct {
+	dir string
+	ttl time.Duration
+}
+
+func NewFileCache(dir string, ttl time.Duration) (*FileCache, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &FileCache{dir: dir, ttl: ttl}, nil
+}
+
+func (c *FileCache) Get(key string) ([]byte, bool) {
+	path := c.path(key)
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, false
+	}
+	if time.Since(info.ModTime()) > c.ttl {
+		os.Remove(path)
+		return nil, false
+	}
+
+	value, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	return value, true
+}
+
+func (c *FileCache) Set(key string, value []byte) {
+	// write and rename so concurrent invocations never read a partial entry
+	tmp, err := ioutil.TempFile(c.dir, ".tmp")
+	if err != nil {
+		logrus.WithField("type", "cache").Debug("Error writing cache entry: ", err)
+		return
+	}
+	_, err = tmp.Write(value)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), c.path(key))
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		logrus.WithField("type", "cache").Debug("Error writing cache entry: ", err)
+	}
+}
+
+func (c *FileCache) Delete(key string) {
+	os.Remove(c.path(key))
+}
+
+func (c *FileCache) path(key string) string {
+	sum := sha1.Sum([]byte(key))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:]))
+}
+
+// cached returns the cached value for key in v. On a miss it calls load and
+// caches the value it stored in v.
+func (c *AWSClient) cached(key string, v interface{}, load func() error) error {
+	if c.cache == nil {
+		return load()
+	}
+
+	key = c.cacheNamespace + key
+	if data, ok := c.cache.Get(key); ok {
+		if err := json.Unmarshal(data, v); err == nil {
+			logrus.WithFields(logrus.Fields{"type": "cache", "key": key}).Debug("cache.hit")
+			c.metrics.CacheLookup(true)
+			return nil
+		}
+	}
+	logrus.WithFields(logrus.Fields{"type": "cache", "key": key}).Debug("cache.miss")
+	c.metrics.CacheLookup(false)
+
+	if err := load(); err != nil {
+		return err
+	}
+
+	if data, err := json.Marshal(v); err == nil {
+		c.cache.Set(key, data)
+	}
+	return nil
+}
+
+func (c *AWSClient) invalidate(keys ...string) {
+	if c.cache == nil {
+		return
+	}
+
+	for _, key := range keys {
+		c.cache.Delete(c.cacheNamespace + key)
+	}
+}
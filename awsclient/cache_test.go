@@ -0,0 +1,123 @@
+package awsclient
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/ryane/takethe53/awsclient/fake"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryCacheExpires(t *testing.T) {
+	c := NewMemoryCache(10 * time.Millisecond)
+	c.Set("key", []byte("value"))
+
+	value, ok := c.Get("key")
+	assert.True(t, ok)
+	assert.Equal(t, "value", string(value))
+
+	time.Sleep(15 * time.Millisecond)
+	_, ok = c.Get("key")
+	assert.False(t, ok)
+}
+
+func TestFileCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "takethe53-cache")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	c, err := NewFileCache(dir, time.Minute)
+	assert.Nil(t, err)
+
+	c.Set("key", []byte("value"))
+	value, ok := c.Get("key")
+	assert.True(t, ok)
+	assert.Equal(t, "value", string(value))
+
+	c.Delete("key")
+	_, ok = c.Get("key")
+	assert.False(t, ok)
+}
+
+func TestCacheNamespaceFollowsProfile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "takethe53-credentials")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "credentials")
+	err = ioutil.WriteFile(file, []byte("[a]\naws_access_key_id = AKIAA\naws_secret_access_key = a\n[b]\naws_access_key_id = AKIAB\naws_secret_access_key = b\n"), 0600)
+	assert.Nil(t, err)
+
+	defer os.Setenv("AWS_SHARED_CREDENTIALS_FILE", os.Getenv("AWS_SHARED_CREDENTIALS_FILE"))
+	defer os.Setenv("AWS_PROFILE", os.Getenv("AWS_PROFILE"))
+	os.Setenv("AWS_SHARED_CREDENTIALS_FILE", file)
+
+	namespace := func(profile string) string {
+		os.Setenv("AWS_PROFILE", profile)
+		return NewWithConfig(Config{Region: "us-east-1", Cache: NewMemoryCache(time.Minute)}).cacheNamespace
+	}
+	assert.NotEqual(t, namespace("a"), namespace("b"))
+}
+
+func TestZonesAreCached(t *testing.T) {
+	r53 := fake.NewRoute53()
+	r53.AddZone("example.com")
+	c := NewWithServices(r53, fake.NewELB())
+	c.cache = NewMemoryCache(time.Minute)
+
+	_, err := c.FindZone("example.com")
+	assert.Nil(t, err)
+
+	// a second lookup must not hit the API
+	r53.InjectError("ListHostedZones", awserr.New("Throttling", "Rate exceeded", nil))
+	zone, err := c.FindZone("example.com")
+	assert.Nil(t, err)
+	assert.Equal(t, "example.com.", zone.Name)
+}
+
+func TestCacheMissesReload(t *testing.T) {
+	r53 := fake.NewRoute53()
+	r53.AddZone("example.com")
+	elb := fake.NewELB()
+	c := NewWithServices(r53, elb)
+	c.cache = NewMemoryCache(time.Minute)
+
+	_, err := c.FindZone("example.com")
+	assert.Nil(t, err)
+	_, err = c.FindLoadBalancer("web-1.us-east-1.elb.amazonaws.com")
+	assert.Equal(t, ErrELBNotFound, err)
+
+	// created after the listings were cached
+	r53.AddZone("example.org")
+	elb.AddLoadBalancer("web-1", "web-1.us-east-1.elb.amazonaws.com", "Z35SXDOTRQ7X7K")
+
+	zone, err := c.FindZone("example.org")
+	assert.Nil(t, err)
+	assert.Equal(t, "example.org.", zone.Name)
+	lb, err := c.FindLoadBalancer("web-1.us-east-1.elb.amazonaws.com")
+	assert.Nil(t, err)
+	assert.Equal(t, "web-1", lb.LoadBalancerName)
+}
+
+func TestRecordSetsInvalidatedAfterChange(t *testing.T) {
+	r53 := fake.NewRoute53()
+	zoneID, _ := r53.AddZone("example.com")
+	c := NewWithServices(r53, fake.NewELB())
+	c.cache = NewMemoryCache(time.Minute)
+
+	zone := &Zone{ID: zoneID, Name: "example.com."}
+	rrsets, err := c.RecordSets(zone)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(rrsets))
+
+	_, err = c.SetAlias(zone, "Z3DZX7HGU9N41H", "aerfflakjdfljadlkfjal-77828384.us-east-1.elb.amazonaws.com", "test")
+	assert.Nil(t, err)
+
+	rrsets, err = c.RecordSets(zone)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(rrsets))
+}
@@ -1,21 +1,87 @@
 package awsclient
 
 import (
+	"crypto/tls"
 	"errors"
+	"net/http"
+	"strings"
+	"time"
 
 	"github.com/Sirupsen/logrus"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
 	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudfront"
 	"github.com/aws/aws-sdk-go/service/elb"
+	"github.com/aws/aws-sdk-go/service/elbv2"
 	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/sts"
 )
 
 type AWSClient struct {
 	r53 Route53er
 	elb ELBer
+
+	elbv2      ELBv2er
+	cloudfront CloudFronter
+	s3         S3er
+	sts        STSer
+
+	cache          Cache
+	cacheNamespace string
+
+	ownerID string
+
+	changeHandlers   []ChangeHandler
+	changeValidators []ChangeValidator
+
+	metrics Metrics
+}
+
+// Config holds optional overrides for the AWS service clients. The zero value
+// uses the default SDK configuration. Endpoint overrides make it possible to
+// point the client at a local Route53/ELB compatible emulator.
+type Config struct {
+	Route53Endpoint    string
+	ELBEndpoint        string
+	Region             string
+	InsecureSkipVerify bool
+	AccessKeyID        string
+	SecretAccessKey    string
+	SessionToken       string
+
+	// Profile, if set, reads credentials and the region from this profile
+	// of the shared AWS config files instead of the default one.
+	Profile string
+	// RoleARN, if set, is assumed with the configured credentials, e.g. to
+	// reach zones in another account.
+	RoleARN string
+
+	// Route53RateLimit is the number of Route53 requests per second allowed
+	// by the client side rate limiter. Zero uses DefaultRoute53RateLimit,
+	// a negative value disables the limiter.
+	Route53RateLimit float64
+	Route53RateBurst int
+	// MaxRetries is the number of times a throttled Route53 request is
+	// retried. Zero uses DefaultMaxRetries, a negative value disables
+	// retries.
+	MaxRetries     int
+	RetryBaseDelay time.Duration
+
+	// Cache, if set, caches zone, record set and load balancer lookups.
+	Cache Cache
+
+	// OwnerID, if set, is written to a TXT record next to every alias and
+	// aliases owned by someone else are not modified.
+	OwnerID string
+
+	// Metrics, if set, receives measurements of AWS API calls, retries,
+	// cache lookups and changes.
+	Metrics Metrics
 }
 
 var (
@@ -23,7 +89,11 @@ var (
 )
 
 func New() *AWSClient {
-	sess := session.New()
+	return NewWithConfig(Config{})
+}
+
+func NewWithConfig(cfg Config) *AWSClient {
+	sess := newSession(cfg.Profile)
 	sess.Handlers.Send.PushFront(func(r *request.Request) {
 		logrus.WithFields(logrus.Fields{
 			"service": r.ClientInfo.ServiceName,
@@ -39,14 +109,161 @@ func New() *AWSClient {
 		CredentialsChainVerboseErrors: aws.Bool(true),
 	}
 
-	r53er := route53.New(sess, awsConfig)
-	elber := elb.New(sess, awsConfig)
+	if cfg.Region != "" {
+		awsConfig.Region = aws.String(cfg.Region)
+	} else if cfg.Route53Endpoint != "" || cfg.ELBEndpoint != "" {
+		// emulators don't care about the region but the SDK refuses to sign
+		// requests without one
+		awsConfig.Region = aws.String("us-east-1")
+	}
+
+	if cfg.AccessKeyID != "" {
+		awsConfig.Credentials = credentials.NewStaticCredentials(cfg.AccessKeyID, cfg.SecretAccessKey, cfg.SessionToken)
+	}
+
+	if cfg.InsecureSkipVerify {
+		awsConfig.HTTPClient = &http.Client{
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+			},
+		}
+	}
+
+	identity := cfg.AccessKeyID
+	if identity == "" && cfg.Cache != nil {
+		// credentials from the default chain depend on the environment,
+		// e.g. AWS_PROFILE, so tell accounts apart by the key they resolve to
+		identity = credentialsIdentity(sess.Config.Credentials)
+	}
+
+	if cfg.RoleARN != "" {
+		// the role is assumed with the credentials configured above
+		awsConfig.Credentials = stscreds.NewCredentials(sess.Copy(awsConfig), cfg.RoleARN)
+	}
+
+	client := &AWSClient{
+		cache: cfg.Cache,
+		// lookups against different endpoints or accounts must not share
+		// cache entries
+		cacheNamespace: strings.Join([]string{cfg.Route53Endpoint, cfg.ELBEndpoint, cfg.Region, identity, cfg.Profile, cfg.RoleARN}, "|") + "|",
+		ownerID:        cfg.OwnerID,
+		metrics:        metricsOrNop(cfg.Metrics),
+	}
+	// service clients copy the session handlers when they are created
+	sess.Handlers.Complete.PushBackNamed(client.apiCallHandler())
+
+	// throttled requests are retried by the retry policy only, the SDK's own
+	// retries would neither be rate limited nor counted
+	r53Config := withEndpoint(awsConfig, cfg.Route53Endpoint).Copy().WithMaxRetries(0)
+	client.r53 = newThrottledRoute53(
+		route53.New(sess, r53Config),
+		newRoute53Limiter(cfg),
+		newRetryPolicy(cfg),
+	)
+	client.elb = elb.New(sess, withEndpoint(awsConfig, cfg.ELBEndpoint))
+
+	// the emulators only serve Route53 and classic ELB, leave the other
+	// services out instead of querying the real ones
+	if cfg.Route53Endpoint == "" && cfg.ELBEndpoint == "" {
+		client.elbv2 = elbv2.New(sess, awsConfig)
+		client.cloudfront = cloudfront.New(sess, awsConfig)
+		client.s3 = s3.New(sess, awsConfig)
+		client.sts = sts.New(sess, awsConfig)
+	}
+
+	return client
+}
+
+// newSession returns the SDK session for a shared config profile, or the
+// default session if profile is empty.
+func newSession(profile string) *session.Session {
+	if profile == "" {
+		return session.New()
+	}
+
+	sess, err := session.NewSessionWithOptions(session.Options{
+		Profile:           profile,
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		// like session.New, fail the requests instead of the constructor
+		logrus.WithField("profile", profile).Warn("Error loading AWS profile: ", err)
+		return session.New(&aws.Config{
+			Credentials: credentials.NewCredentials(&credentials.ErrorProvider{Err: err, ProviderName: "SharedConfigProfile"}),
+		})
+	}
+
+	return sess
+}
+
+// credentialsIdentity returns the access key ID creds resolve to, or an
+// empty string if they can't be resolved.
+func credentialsIdentity(creds *credentials.Credentials) string {
+	if creds == nil {
+		return ""
+	}
+	v, err := creds.Get()
+	if err != nil {
+		return ""
+	}
+	return v.AccessKeyID
+}
+
+// NewWithServices returns a client backed by the given service
+// implementations, e.g. the in-memory ones from the fake package.
+func NewWithServices(r53 Route53er, elb ELBer) *AWSClient {
+	return &AWSClient{r53: r53, elb: elb, metrics: nopMetrics{}}
+}
+
+func newRoute53Limiter(cfg Config) *RateLimiter {
+	rate := cfg.Route53RateLimit
+	if rate == 0 {
+		rate = DefaultRoute53RateLimit
+	}
+	if rate < 0 {
+		return nil
+	}
+
+	burst := cfg.Route53RateBurst
+	if burst <= 0 {
+		burst = int(rate)
+	}
 
-	return &AWSClient{r53: r53er, elb: elber}
+	return NewRateLimiter(rate, burst)
+}
+
+func newRetryPolicy(cfg Config) RetryPolicy {
+	policy := RetryPolicy{
+		MaxRetries: cfg.MaxRetries,
+		BaseDelay:  cfg.RetryBaseDelay,
+		MaxDelay:   DefaultRetryMaxDelay,
+		metrics:    metricsOrNop(cfg.Metrics),
+	}
+	if policy.MaxRetries == 0 {
+		policy.MaxRetries = DefaultMaxRetries
+	}
+	if policy.BaseDelay <= 0 {
+		policy.BaseDelay = DefaultRetryBaseDelay
+	}
+
+	return policy
+}
+
+func withEndpoint(cfg *aws.Config, endpoint string) *aws.Config {
+	if endpoint == "" {
+		return cfg
+	}
+
+	return cfg.Copy().WithEndpoint(endpoint)
 }
 
 func checkAWSError(err error) error {
-	awserr := err.(awserr.Error)
+	awserr, ok := err.(awserr.Error)
+	if !ok {
+		return err
+	}
+
 	logrus.WithFields(logrus.Fields{
 		"type":  "aws",
 		"error": awserr,
@@ -1,56 +1,107 @@
 package awsclient
 
 import (
+	"context"
 	"errors"
+	"regexp"
 	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/elb"
 )
 
 type ELBer interface {
-	DescribeLoadBalancersPages(input *elb.DescribeLoadBalancersInput, fn func(p *elb.DescribeLoadBalancersOutput, lastPage bool) (shouldContinue bool)) error
+	DescribeLoadBalancersPagesWithContext(ctx aws.Context, input *elb.DescribeLoadBalancersInput, fn func(p *elb.DescribeLoadBalancersOutput, lastPage bool) (shouldContinue bool), opts ...request.Option) error
 }
 
 type LoadBalancer struct {
+	// Name is the DNS name of the load balancer.
 	Name         string
 	HostedZoneID string
+	// LoadBalancerName is the name the load balancer was created with.
+	LoadBalancerName string
 }
 
 var ErrELBNotFound = errors.New("ELB does not exist.")
 
+// name-1234.us-east-1.elb.amazonaws.com or
+// name-1234.elb.us-east-1.amazonaws.com for network load balancers
+var loadBalancerDNSName = regexp.MustCompile(`\.(?:([a-z0-9-]+)\.elb|elb\.([a-z0-9-]+))\.amazonaws\.com(?:\.cn)?$`)
+
+// LoadBalancerRegion returns the region in the DNS name of a load balancer,
+// or false if dnsName is not the DNS name of a load balancer.
+func LoadBalancerRegion(dnsName string) (string, bool) {
+	m := loadBalancerDNSName.FindStringSubmatch(strings.ToLower(strings.TrimSuffix(dnsName, ".")))
+	if m == nil {
+		return "", false
+	}
+	return m[1] + m[2], true
+}
+
 func (c *AWSClient) LoadBalancers() ([]*LoadBalancer, error) {
-	params := &elb.DescribeLoadBalancersInput{PageSize: aws.Int64(400)}
+	return c.LoadBalancersWithContext(context.Background())
+}
 
+func (c *AWSClient) LoadBalancersWithContext(ctx context.Context) ([]*LoadBalancer, error) {
 	var lbs []*LoadBalancer
-	err := c.elb.DescribeLoadBalancersPages(params, func(o *elb.DescribeLoadBalancersOutput, lastPage bool) bool {
-		for _, lbd := range o.LoadBalancerDescriptions {
-			lbs = append(lbs, &LoadBalancer{
-				Name:         aws.StringValue(lbd.DNSName),
-				HostedZoneID: aws.StringValue(lbd.CanonicalHostedZoneNameID),
-			})
+	err := c.cached(cacheKeyLoadBalancers, &lbs, func() error {
+		lbs = nil
+		params := &elb.DescribeLoadBalancersInput{PageSize: aws.Int64(400)}
+		err := c.elb.DescribeLoadBalancersPagesWithContext(ctx, params, func(o *elb.DescribeLoadBalancersOutput, lastPage bool) bool {
+			for _, lbd := range o.LoadBalancerDescriptions {
+				lbs = append(lbs, &LoadBalancer{
+					Name:             aws.StringValue(lbd.DNSName),
+					HostedZoneID:     aws.StringValue(lbd.CanonicalHostedZoneNameID),
+					LoadBalancerName: aws.StringValue(lbd.LoadBalancerName),
+				})
+			}
+			return !lastPage
+		})
+		if err != nil {
+			return checkAWSError(err)
 		}
-		return !lastPage
+		return nil
 	})
 
 	if err != nil {
-		return nil, checkAWSError(err)
+		return nil, err
 	}
 
 	return lbs, nil
 }
 
 func (c *AWSClient) FindLoadBalancer(dnsName string) (*LoadBalancer, error) {
-	lbs, err := c.LoadBalancers()
+	return c.FindLoadBalancerWithContext(context.Background(), dnsName)
+}
+
+func (c *AWSClient) FindLoadBalancerWithContext(ctx context.Context, dnsName string) (*LoadBalancer, error) {
+	lbs, err := c.LoadBalancersWithContext(ctx)
 	if err != nil {
 		return nil, err
 	}
 
+	lb := findLoadBalancer(lbs, dnsName)
+	if lb == nil && c.cache != nil {
+		// the cached load balancers may be from before it was created
+		c.invalidate(cacheKeyLoadBalancers)
+		if lbs, err = c.LoadBalancersWithContext(ctx); err != nil {
+			return nil, err
+		}
+		lb = findLoadBalancer(lbs, dnsName)
+	}
+	if lb == nil {
+		return nil, ErrELBNotFound
+	}
+
+	return lb, nil
+}
+
+func findLoadBalancer(lbs []*LoadBalancer, dnsName string) *LoadBalancer {
 	for _, lb := range lbs {
 		if strings.EqualFold(dnsName, lb.Name) {
-			return lb, nil
+			return lb
 		}
 	}
-
-	return nil, ErrELBNotFound
+	return nil
 }
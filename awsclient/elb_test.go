@@ -4,6 +4,7 @@ import (
 	"github.com/Sirupsen/logrus"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/elb"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -18,13 +19,14 @@ type mockELB struct {
 	mock.Mock
 }
 
-func (m *mockELB) DescribeLoadBalancersPages(params *elb.DescribeLoadBalancersInput, fn func(*elb.DescribeLoadBalancersOutput, bool) bool) error {
+func (m *mockELB) DescribeLoadBalancersPagesWithContext(ctx aws.Context, params *elb.DescribeLoadBalancersInput, fn func(*elb.DescribeLoadBalancersOutput, bool) bool, opts ...request.Option) error {
 	args := m.Called(params, fn)
 
 	// simulate multiple pages
 	out := &elb.DescribeLoadBalancersOutput{
 		LoadBalancerDescriptions: []*elb.LoadBalancerDescription{
 			&elb.LoadBalancerDescription{
+				LoadBalancerName:          aws.String("ab4xxxxxxxxxxxxxxxxxxxxxxxxxxxxx"),
 				DNSName:                   aws.String("ab4xxxxxxxxxxxxxxxxxxxxxxxxxxxxx-xxxxxxxxx.us-east-1.elb.amazonaws.com"),
 				CanonicalHostedZoneNameID: aws.String("Z3DXXXXXXXXXXX"),
 			},
@@ -64,6 +66,7 @@ func TestLoadBalancers(t *testing.T) {
 
 	assert.Equal(t, "ab4xxxxxxxxxxxxxxxxxxxxxxxxxxxxx-xxxxxxxxx.us-east-1.elb.amazonaws.com", lbs[0].Name)
 	assert.Equal(t, "Z3DXXXXXXXXXXX", lbs[0].HostedZoneID)
+	assert.Equal(t, "ab4xxxxxxxxxxxxxxxxxxxxxxxxxxxxx", lbs[0].LoadBalancerName)
 	assert.Equal(t, "afexxxxxxxxxxxxxxxxxxxxxxxxxxxxx-xxxxxxxxx.us-east-1.elb.amazonaws.com", lbs[1].Name)
 	assert.Equal(t, "Z2HXXXXXXXXXXX", lbs[1].HostedZoneID)
 	assert.Equal(t, "a2bxxxxxxxxxxxxxxxxxxxxxxxxxxxxx-xxxxxxxxx.us-east-1.elb.amazonaws.com", lbs[2].Name)
@@ -106,9 +109,25 @@ func TestFindLoadBalancerNoExist(t *testing.T) {
 	assert.Nil(t, lb, "load balancer should be nil")
 }
 
+func TestLoadBalancerRegion(t *testing.T) {
+	for dnsName, region := range map[string]string{
+		"web-1234.us-east-1.elb.amazonaws.com":            "us-east-1",
+		"dualstack.web-1234.eu-west-1.elb.amazonaws.com.": "eu-west-1",
+		"net-1234.elb.us-east-2.amazonaws.com":            "us-east-2",
+		"web-1234.cn-north-1.elb.amazonaws.com.cn":        "cn-north-1",
+	} {
+		r, ok := LoadBalancerRegion(dnsName)
+		assert.True(t, ok, dnsName)
+		assert.Equal(t, region, r, dnsName)
+	}
+
+	_, ok := LoadBalancerRegion("d111111abcdef8.cloudfront.net")
+	assert.False(t, ok)
+}
+
 func mockDescribeLoadBalancers(m *mockELB, returnParams ...interface{}) {
 	m.Mock.On(
-		"DescribeLoadBalancersPages",
+		"DescribeLoadBalancersPagesWithContext",
 		mock.AnythingOfType("*elb.DescribeLoadBalancersInput"),
 		mock.AnythingOfType("func(*elb.DescribeLoadBalancersOutput, bool) bool"),
 	).Return(returnParams...)
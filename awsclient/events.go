@@ -0,0 +1,107 @@
+package awsclient
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/route53"
+)
+
+// Operation names passed to change handlers.
+const (
+	OpSetAlias    = "set-alias"
+	OpRemoveAlias = "remove-alias"
+	OpMoveAlias   = "move-alias"
+)
+
+// ChangeEvent describes a change batch submitted to Route53.
+type ChangeEvent struct {
+	Op      string
+	Zone    *Zone
+	Changes []*route53.Change
+	// Previous holds the record sets that UPSERT changes replaced.
+	Previous []*route53.ResourceRecordSet
+	// PreviousErr is set when Previous could not be read and is incomplete.
+	PreviousErr error
+	Status      *ChangeStatus
+	Err         error
+	Time        time.Time
+}
+
+// ChangeHandler is called after every change batch, whether it succeeded or
+// not.
+type ChangeHandler func(ctx context.Context, ev *ChangeEvent)
+
+func (c *AWSClient) AddChangeHandler(h ChangeHandler) {
+	c.changeHandlers = append(c.changeHandlers, h)
+}
+
+// ChangeValidator is called before a change batch is submitted, with the
+// Op, Zone and Changes of the event set. An error rejects the batch, it is
+// returned to the caller and passed to the change handlers.
+type ChangeValidator func(ctx context.Context, ev *ChangeEvent) error
+
+func (c *AWSClient) AddChangeValidator(v ChangeValidator) {
+	c.changeValidators = append(c.changeValidators, v)
+}
+
+func (c *AWSClient) validateChange(ctx context.Context, ev *ChangeEvent) error {
+	for _, v := range c.changeValidators {
+		if err := v(ctx, ev); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *AWSClient) notifyChange(ctx context.Context, ev *ChangeEvent) {
+	for _, h := range c.changeHandlers {
+		h(ctx, ev)
+	}
+}
+
+// previousRecordSets returns the current values of the record sets that the
+// UPSERT changes in a batch are about to replace. The error is the first
+// lookup that failed; the record sets of the other names are still returned.
+func (c *AWSClient) previousRecordSets(ctx context.Context, zone *Zone, changes []*route53.Change) ([]*route53.ResourceRecordSet, error) {
+	var lookupErr error
+	var previous []*route53.ResourceRecordSet
+	seen := map[string][]*route53.ResourceRecordSet{}
+
+	for _, change := range changes {
+		if aws.StringValue(change.Action) != route53.ChangeActionUpsert {
+			continue
+		}
+
+		name := strings.ToLower(aws.StringValue(change.ResourceRecordSet.Name))
+		existing, ok := seen[name]
+		if !ok {
+			var err error
+			existing, err = c.recordSetsNamed(ctx, zone, name)
+			if err != nil {
+				logrus.WithFields(logrus.Fields{"type": "aws", "name": name}).Warn("Error reading previous record sets: ", err)
+				if lookupErr == nil {
+					lookupErr = err
+				}
+			}
+			seen[name] = existing
+		}
+
+		for _, rrs := range existing {
+			if sameRecordSetKey(rrs, change.ResourceRecordSet) {
+				previous = append(previous, rrs)
+			}
+		}
+	}
+
+	return previous, lookupErr
+}
+
+func sameRecordSetKey(a, b *route53.ResourceRecordSet) bool {
+	return sameDNSName(aws.StringValue(a.Name), aws.StringValue(b.Name)) &&
+		aws.StringValue(a.Type) == aws.StringValue(b.Type) &&
+		aws.StringValue(a.SetIdentifier) == aws.StringValue(b.SetIdentifier)
+}
@@ -0,0 +1,135 @@
+package fake
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awsutil"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/elb"
+)
+
+// ELB is an in-memory classic ELB backend.
+type ELB struct {
+	errorInjector
+
+	// PageSize caps the number of load balancers returned per page,
+	// regardless of the PageSize requested. Zero means no cap.
+	PageSize int
+
+	mu  sync.Mutex
+	lbs []*elb.LoadBalancerDescription
+}
+
+func NewELB() *ELB {
+	return &ELB{}
+}
+
+// AddLoadBalancer registers a load balancer and returns its description.
+func (f *ELB) AddLoadBalancer(name, dnsName, hostedZoneID string) *elb.LoadBalancerDescription {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	lbd := &elb.LoadBalancerDescription{
+		LoadBalancerName:          aws.String(name),
+		DNSName:                   aws.String(dnsName),
+		CanonicalHostedZoneName:   aws.String(dnsName),
+		CanonicalHostedZoneNameID: aws.String(hostedZoneID),
+		Scheme:                    aws.String("internet-facing"),
+	}
+	f.lbs = append(f.lbs, lbd)
+
+	return awsutil.CopyOf(lbd).(*elb.LoadBalancerDescription)
+}
+
+// RemoveLoadBalancer deletes the load balancer with the given name.
+func (f *ELB) RemoveLoadBalancer(name string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for i, lbd := range f.lbs {
+		if aws.StringValue(lbd.LoadBalancerName) == name {
+			f.lbs = append(f.lbs[:i], f.lbs[i+1:]...)
+			return
+		}
+	}
+}
+
+func (f *ELB) DescribeLoadBalancers(input *elb.DescribeLoadBalancersInput) (*elb.DescribeLoadBalancersOutput, error) {
+	if err := f.injectedError("DescribeLoadBalancers"); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	lbs := f.lbs
+	if len(input.LoadBalancerNames) > 0 {
+		lbs = nil
+		for _, name := range input.LoadBalancerNames {
+			found := false
+			for _, lbd := range f.lbs {
+				if strings.EqualFold(aws.StringValue(lbd.LoadBalancerName), aws.StringValue(name)) {
+					lbs = append(lbs, lbd)
+					found = true
+				}
+			}
+			if !found {
+				return nil, badRequest(elb.ErrCodeAccessPointNotFoundException, "There is no ACTIVE Load Balancer named '"+aws.StringValue(name)+"'")
+			}
+		}
+	}
+
+	start, _ := strconv.Atoi(aws.StringValue(input.Marker))
+	if start > len(lbs) {
+		start = len(lbs)
+	}
+
+	pageSize := int(aws.Int64Value(input.PageSize))
+	if pageSize <= 0 || pageSize > 400 {
+		pageSize = 400
+	}
+	if f.PageSize > 0 && f.PageSize < pageSize {
+		pageSize = f.PageSize
+	}
+
+	end := start + pageSize
+	if end > len(lbs) {
+		end = len(lbs)
+	}
+
+	out := &elb.DescribeLoadBalancersOutput{
+		LoadBalancerDescriptions: []*elb.LoadBalancerDescription{},
+	}
+	for _, lbd := range lbs[start:end] {
+		out.LoadBalancerDescriptions = append(out.LoadBalancerDescriptions, awsutil.CopyOf(lbd).(*elb.LoadBalancerDescription))
+	}
+
+	if end < len(lbs) {
+		out.NextMarker = aws.String(strconv.Itoa(end))
+	}
+
+	return out, nil
+}
+
+func (f *ELB) DescribeLoadBalancersPagesWithContext(ctx aws.Context, input *elb.DescribeLoadBalancersInput, fn func(*elb.DescribeLoadBalancersOutput, bool) bool, opts ...request.Option) error {
+	params := awsutil.CopyOf(input).(*elb.DescribeLoadBalancersInput)
+	for {
+		if err := canceled(ctx); err != nil {
+			return err
+		}
+
+		out, err := f.DescribeLoadBalancers(params)
+		if err != nil {
+			return err
+		}
+
+		lastPage := aws.StringValue(out.NextMarker) == ""
+		if !fn(out, lastPage) || lastPage {
+			return nil
+		}
+		params.Marker = out.NextMarker
+	}
+}
@@ -0,0 +1,24 @@
+package fake
+
+import (
+	"testing"
+
+	"github.com/ryane/takethe53/awsclient"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadBalancers(t *testing.T) {
+	elber := NewELB()
+	elber.PageSize = 1
+	elber.AddLoadBalancer("web", "web-1.us-east-1.elb.amazonaws.com", "Z35SXDOTRQ7X7K")
+	elber.AddLoadBalancer("api", "api-2.us-east-1.elb.amazonaws.com", "Z35SXDOTRQ7X7K")
+
+	c := awsclient.NewWithServices(NewRoute53(), elber)
+	lbs, err := c.LoadBalancers()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(lbs))
+
+	elber.RemoveLoadBalancer("api")
+	_, err = c.FindLoadBalancer("api-2.us-east-1.elb.amazonaws.com")
+	assert.Equal(t, awsclient.ErrELBNotFound, err)
+}
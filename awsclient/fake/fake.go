@@ -0,0 +1,67 @@
+// Package fake provides in-memory implementations of the awsclient.Route53er
+// and awsclient.ELBer interfaces. They keep real state so that code built on
+// top of awsclient.AWSClient can exercise DNS flows without talking to AWS.
+package fake
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+)
+
+// errorInjector queues errors that are returned by the next calls to an
+// operation instead of running it.
+type errorInjector struct {
+	mu     sync.Mutex
+	errors map[string][]error
+}
+
+// InjectError makes the next call to op (the AWS operation name, e.g.
+// "ChangeResourceRecordSets") fail with err. Multiple errors for the same
+// operation are returned in order, one per call.
+func (e *errorInjector) InjectError(op string, err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.errors == nil {
+		e.errors = map[string][]error{}
+	}
+	e.errors[op] = append(e.errors[op], err)
+}
+
+func (e *errorInjector) injectedError(op string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	errs := e.errors[op]
+	if len(errs) == 0 {
+		return nil
+	}
+
+	e.errors[op] = errs[1:]
+	return errs[0]
+}
+
+func newError(code, message string, statusCode int) error {
+	return awserr.NewRequestFailure(awserr.New(code, message, nil), statusCode, "")
+}
+
+func badRequest(code, message string) error {
+	return newError(code, message, http.StatusBadRequest)
+}
+
+func notFound(code, message string) error {
+	return newError(code, message, http.StatusNotFound)
+}
+
+// canceled returns the error the SDK returns for requests made with a done
+// context.
+func canceled(ctx aws.Context) error {
+	if ctx.Err() == nil {
+		return nil
+	}
+	return awserr.New(request.CanceledErrorCode, "request context canceled", ctx.Err())
+}
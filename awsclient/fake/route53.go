@@ -0,0 +1,493 @@
+package fake
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awsutil"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/route53"
+)
+
+const defaultMaxItems = 100
+
+// Route53 is an in-memory Route53 backend. Changes are applied atomically per
+// batch and stay PENDING for SyncDelay before GetChange reports them INSYNC.
+type Route53 struct {
+	errorInjector
+
+	// SyncDelay is how long a change stays PENDING.
+	SyncDelay time.Duration
+	// PageSize caps the number of items returned per page, regardless of
+	// the MaxItems requested. Zero means no cap.
+	PageSize int
+
+	mu      sync.Mutex
+	zones   map[string]*hostedZone
+	changes map[string]*route53.ChangeInfo
+	nextID  int
+}
+
+type hostedZone struct {
+	zone   *route53.HostedZone
+	rrsets []*route53.ResourceRecordSet
+}
+
+func NewRoute53() *Route53 {
+	return &Route53{
+		zones:   map[string]*hostedZone{},
+		changes: map[string]*route53.ChangeInfo{},
+	}
+}
+
+// AddZone creates a hosted zone with its SOA and NS records and returns its
+// ID.
+func (f *Route53) AddZone(name string) (string, error) {
+	out, err := f.CreateHostedZone(&route53.CreateHostedZoneInput{
+		Name:            aws.String(name),
+		CallerReference: aws.String(name),
+	})
+	if err != nil {
+		return "", err
+	}
+	return aws.StringValue(out.HostedZone.Id), nil
+}
+
+// AddRecordSet adds or replaces a record set without creating a change.
+func (f *Route53) AddRecordSet(zoneID string, rrs *route53.ResourceRecordSet) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	hz, ok := f.zones[cleanZoneID(zoneID)]
+	if !ok {
+		return notFound(route53.ErrCodeNoSuchHostedZone, fmt.Sprintf("No hosted zone found with ID: %s", zoneID))
+	}
+
+	hz.rrsets = upsertRecordSet(hz.rrsets, normalizeRecordSet(rrs))
+	return nil
+}
+
+// RecordSets returns a copy of the record sets in a zone.
+func (f *Route53) RecordSets(zoneID string) []*route53.ResourceRecordSet {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	hz, ok := f.zones[cleanZoneID(zoneID)]
+	if !ok {
+		return nil
+	}
+
+	return copyRecordSets(hz.rrsets)
+}
+
+func (f *Route53) CreateHostedZone(input *route53.CreateHostedZoneInput) (*route53.CreateHostedZoneOutput, error) {
+	if err := f.injectedError("CreateHostedZone"); err != nil {
+		return nil, err
+	}
+
+	name := normalizeName(aws.StringValue(input.Name))
+	if name == "." {
+		return nil, badRequest(route53.ErrCodeInvalidDomainName, "Invalid domain name")
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.nextID++
+	id := fmt.Sprintf("/hostedzone/Z%012X", f.nextID)
+
+	hz := &route53.HostedZone{
+		Id:                     aws.String(id),
+		Name:                   aws.String(name),
+		CallerReference:        input.CallerReference,
+		ResourceRecordSetCount: aws.Int64(2),
+		Config:                 input.HostedZoneConfig,
+	}
+
+	ns := []*route53.ResourceRecord{}
+	for i := 1; i <= 4; i++ {
+		ns = append(ns, &route53.ResourceRecord{Value: aws.String(fmt.Sprintf("ns-%d.fake-route53.test.", i))})
+	}
+
+	f.zones[cleanZoneID(id)] = &hostedZone{
+		zone: hz,
+		rrsets: []*route53.ResourceRecordSet{
+			{
+				Name:            aws.String(name),
+				Type:            aws.String(route53.RRTypeNs),
+				TTL:             aws.Int64(172800),
+				ResourceRecords: ns,
+			},
+			{
+				Name: aws.String(name),
+				Type: aws.String(route53.RRTypeSoa),
+				TTL:  aws.Int64(900),
+				ResourceRecords: []*route53.ResourceRecord{
+					{Value: aws.String("ns-1.fake-route53.test. hostmaster.fake-route53.test. 1 7200 900 1209600 86400")},
+				},
+			},
+		},
+	}
+
+	nameServers := []*string{}
+	for _, rr := range ns {
+		nameServers = append(nameServers, rr.Value)
+	}
+
+	return &route53.CreateHostedZoneOutput{
+		HostedZone:    awsutil.CopyOf(hz).(*route53.HostedZone),
+		ChangeInfo:    f.newChange(""),
+		DelegationSet: &route53.DelegationSet{NameServers: nameServers},
+		Location:      aws.String("https://route53.amazonaws.com/2013-04-01" + id),
+	}, nil
+}
+
+func (f *Route53) ListHostedZones(input *route53.ListHostedZonesInput) (*route53.ListHostedZonesOutput, error) {
+	if err := f.injectedError("ListHostedZones"); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	ids := []string{}
+	for id := range f.zones {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	start := 0
+	if marker := cleanZoneID(aws.StringValue(input.Marker)); marker != "" {
+		start = sort.SearchStrings(ids, marker)
+	}
+
+	maxItems := f.maxItems(input.MaxItems)
+	end := start + maxItems
+	if end > len(ids) {
+		end = len(ids)
+	}
+
+	out := &route53.ListHostedZonesOutput{
+		HostedZones: []*route53.HostedZone{},
+		Marker:      input.Marker,
+		MaxItems:    aws.String(strconv.Itoa(maxItems)),
+		IsTruncated: aws.Bool(end < len(ids)),
+	}
+
+	for _, id := range ids[start:end] {
+		hz := awsutil.CopyOf(f.zones[id].zone).(*route53.HostedZone)
+		hz.ResourceRecordSetCount = aws.Int64(int64(len(f.zones[id].rrsets)))
+		out.HostedZones = append(out.HostedZones, hz)
+	}
+
+	if end < len(ids) {
+		out.NextMarker = aws.String(ids[end])
+	}
+
+	return out, nil
+}
+
+func (f *Route53) ListHostedZonesPagesWithContext(ctx aws.Context, input *route53.ListHostedZonesInput, fn func(*route53.ListHostedZonesOutput, bool) bool, opts ...request.Option) error {
+	params := awsutil.CopyOf(input).(*route53.ListHostedZonesInput)
+	for {
+		if err := canceled(ctx); err != nil {
+			return err
+		}
+
+		out, err := f.ListHostedZones(params)
+		if err != nil {
+			return err
+		}
+
+		lastPage := !aws.BoolValue(out.IsTruncated)
+		if !fn(out, lastPage) || lastPage {
+			return nil
+		}
+		params.Marker = out.NextMarker
+	}
+}
+
+func (f *Route53) ListResourceRecordSets(input *route53.ListResourceRecordSetsInput) (*route53.ListResourceRecordSetsOutput, error) {
+	if err := f.injectedError("ListResourceRecordSets"); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	hz, ok := f.zones[cleanZoneID(aws.StringValue(input.HostedZoneId))]
+	if !ok {
+		return nil, notFound(route53.ErrCodeNoSuchHostedZone, fmt.Sprintf("No hosted zone found with ID: %s", aws.StringValue(input.HostedZoneId)))
+	}
+
+	start := 0
+	if input.StartRecordName != nil {
+		startKey := recordKey(normalizeName(aws.StringValue(input.StartRecordName)), aws.StringValue(input.StartRecordType), aws.StringValue(input.StartRecordIdentifier))
+		start = sort.Search(len(hz.rrsets), func(i int) bool {
+			return recordSetKey(hz.rrsets[i]) >= startKey
+		})
+	}
+
+	maxItems := f.maxItems(input.MaxItems)
+	end := start + maxItems
+	if end > len(hz.rrsets) {
+		end = len(hz.rrsets)
+	}
+
+	out := &route53.ListResourceRecordSetsOutput{
+		ResourceRecordSets: copyRecordSets(hz.rrsets[start:end]),
+		MaxItems:           aws.String(strconv.Itoa(maxItems)),
+		IsTruncated:        aws.Bool(end < len(hz.rrsets)),
+	}
+
+	if end < len(hz.rrsets) {
+		next := hz.rrsets[end]
+		out.NextRecordName = next.Name
+		out.NextRecordType = next.Type
+		out.NextRecordIdentifier = next.SetIdentifier
+	}
+
+	return out, nil
+}
+
+func (f *Route53) ListResourceRecordSetsPagesWithContext(ctx aws.Context, input *route53.ListResourceRecordSetsInput, fn func(*route53.ListResourceRecordSetsOutput, bool) bool, opts ...request.Option) error {
+	params := awsutil.CopyOf(input).(*route53.ListResourceRecordSetsInput)
+	for {
+		if err := canceled(ctx); err != nil {
+			return err
+		}
+
+		out, err := f.ListResourceRecordSets(params)
+		if err != nil {
+			return err
+		}
+
+		lastPage := !aws.BoolValue(out.IsTruncated)
+		if !fn(out, lastPage) || lastPage {
+			return nil
+		}
+		params.StartRecordName = out.NextRecordName
+		params.StartRecordType = out.NextRecordType
+		params.StartRecordIdentifier = out.NextRecordIdentifier
+	}
+}
+
+// ChangeResourceRecordSets validates and applies a change batch. Like
+// Route53, either every change in the batch is applied or none is.
+func (f *Route53) ChangeResourceRecordSets(input *route53.ChangeResourceRecordSetsInput) (*route53.ChangeResourceRecordSetsOutput, error) {
+	if err := f.injectedError("ChangeResourceRecordSets"); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	hz, ok := f.zones[cleanZoneID(aws.StringValue(input.HostedZoneId))]
+	if !ok {
+		return nil, notFound(route53.ErrCodeNoSuchHostedZone, fmt.Sprintf("No hosted zone found with ID: %s", aws.StringValue(input.HostedZoneId)))
+	}
+
+	if input.ChangeBatch == nil || len(input.ChangeBatch.Changes) == 0 {
+		return nil, badRequest("InvalidInput", "ChangeBatch must contain at least one change")
+	}
+
+	zoneName := aws.StringValue(hz.zone.Name)
+	rrsets := copyRecordSets(hz.rrsets)
+	var problems []string
+
+	for _, change := range input.ChangeBatch.Changes {
+		if change.ResourceRecordSet == nil {
+			return nil, badRequest("InvalidInput", "ResourceRecordSet is required")
+		}
+
+		rrs := normalizeRecordSet(change.ResourceRecordSet)
+		name := aws.StringValue(rrs.Name)
+		desc := fmt.Sprintf("[name='%s', type='%s']", name, aws.StringValue(rrs.Type))
+
+		if name != zoneName && !strings.HasSuffix(name, "."+zoneName) {
+			problems = append(problems, fmt.Sprintf("RRSet with DNS name %s is not permitted in zone %s", name, zoneName))
+			continue
+		}
+
+		i := indexOfRecordSet(rrsets, rrs)
+		switch aws.StringValue(change.Action) {
+		case route53.ChangeActionCreate:
+			if i >= 0 {
+				problems = append(problems, fmt.Sprintf("Tried to create resource record set %s but it already exists", desc))
+				continue
+			}
+			rrsets = upsertRecordSet(rrsets, rrs)
+		case route53.ChangeActionDelete:
+			if i < 0 {
+				problems = append(problems, fmt.Sprintf("Tried to delete resource record set %s but it was not found", desc))
+				continue
+			}
+			if !reflect.DeepEqual(rrsets[i], rrs) {
+				problems = append(problems, fmt.Sprintf("Tried to delete resource record set %s but the values provided do not match the current values", desc))
+				continue
+			}
+			rrsets = append(rrsets[:i], rrsets[i+1:]...)
+		case route53.ChangeActionUpsert:
+			rrsets = upsertRecordSet(rrsets, rrs)
+		default:
+			return nil, badRequest("InvalidInput", fmt.Sprintf("Invalid change action: %s", aws.StringValue(change.Action)))
+		}
+	}
+
+	if len(problems) > 0 {
+		return nil, badRequest(route53.ErrCodeInvalidChangeBatch, strings.Join(problems, ", "))
+	}
+
+	hz.rrsets = rrsets
+
+	return &route53.ChangeResourceRecordSetsOutput{
+		ChangeInfo: f.newChange(aws.StringValue(input.ChangeBatch.Comment)),
+	}, nil
+}
+
+func (f *Route53) ChangeResourceRecordSetsWithContext(ctx aws.Context, input *route53.ChangeResourceRecordSetsInput, opts ...request.Option) (*route53.ChangeResourceRecordSetsOutput, error) {
+	if err := canceled(ctx); err != nil {
+		return nil, err
+	}
+	return f.ChangeResourceRecordSets(input)
+}
+
+func (f *Route53) GetChange(input *route53.GetChangeInput) (*route53.GetChangeOutput, error) {
+	if err := f.injectedError("GetChange"); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	id := strings.TrimPrefix(aws.StringValue(input.Id), "/change/")
+	ci, ok := f.changes[id]
+	if !ok {
+		return nil, notFound(route53.ErrCodeNoSuchChange, fmt.Sprintf("Could not find resource with ID: %s", id))
+	}
+
+	return &route53.GetChangeOutput{ChangeInfo: f.changeInfo(ci)}, nil
+}
+
+func (f *Route53) GetChangeWithContext(ctx aws.Context, input *route53.GetChangeInput, opts ...request.Option) (*route53.GetChangeOutput, error) {
+	if err := canceled(ctx); err != nil {
+		return nil, err
+	}
+	return f.GetChange(input)
+}
+
+// newChange must be called with f.mu held.
+func (f *Route53) newChange(comment string) *route53.ChangeInfo {
+	f.nextID++
+	id := fmt.Sprintf("C%012X", f.nextID)
+
+	ci := &route53.ChangeInfo{
+		Id:          aws.String("/change/" + id),
+		Status:      aws.String(route53.ChangeStatusPending),
+		SubmittedAt: aws.Time(time.Now().UTC()),
+	}
+	if comment != "" {
+		ci.Comment = aws.String(comment)
+	}
+	f.changes[id] = ci
+
+	return f.changeInfo(ci)
+}
+
+// changeInfo returns a copy of ci with the status it has now.
+func (f *Route53) changeInfo(ci *route53.ChangeInfo) *route53.ChangeInfo {
+	out := awsutil.CopyOf(ci).(*route53.ChangeInfo)
+	if time.Since(aws.TimeValue(ci.SubmittedAt)) >= f.SyncDelay {
+		out.Status = aws.String(route53.ChangeStatusInsync)
+	}
+	return out
+}
+
+func (f *Route53) maxItems(requested *string) int {
+	maxItems, err := strconv.Atoi(aws.StringValue(requested))
+	if err != nil || maxItems <= 0 {
+		maxItems = defaultMaxItems
+	}
+
+	if f.PageSize > 0 && f.PageSize < maxItems {
+		maxItems = f.PageSize
+	}
+
+	return maxItems
+}
+
+func cleanZoneID(id string) string {
+	return strings.TrimPrefix(id, "/hostedzone/")
+}
+
+func normalizeName(name string) string {
+	name = strings.ToLower(name)
+	if !strings.HasSuffix(name, ".") {
+		name += "."
+	}
+	return name
+}
+
+func normalizeRecordSet(rrs *route53.ResourceRecordSet) *route53.ResourceRecordSet {
+	out := awsutil.CopyOf(rrs).(*route53.ResourceRecordSet)
+	out.Name = aws.String(normalizeName(aws.StringValue(rrs.Name)))
+	if out.AliasTarget != nil {
+		out.AliasTarget.DNSName = aws.String(normalizeName(aws.StringValue(out.AliasTarget.DNSName)))
+		if out.AliasTarget.EvaluateTargetHealth == nil {
+			out.AliasTarget.EvaluateTargetHealth = aws.Bool(false)
+		}
+	}
+	return out
+}
+
+func copyRecordSets(rrsets []*route53.ResourceRecordSet) []*route53.ResourceRecordSet {
+	out := make([]*route53.ResourceRecordSet, 0, len(rrsets))
+	for _, rrs := range rrsets {
+		out = append(out, awsutil.CopyOf(rrs).(*route53.ResourceRecordSet))
+	}
+	return out
+}
+
+// recordKey sorts record sets the way Route53 does: by name with the labels
+// reversed, then by type and set identifier.
+func recordKey(name, rrType, setIdentifier string) string {
+	labels := strings.Split(strings.TrimSuffix(name, "."), ".")
+	for i, j := 0, len(labels)-1; i < j; i, j = i+1, j-1 {
+		labels[i], labels[j] = labels[j], labels[i]
+	}
+	return strings.Join(labels, ".") + "\x00" + rrType + "\x00" + setIdentifier
+}
+
+func recordSetKey(rrs *route53.ResourceRecordSet) string {
+	return recordKey(aws.StringValue(rrs.Name), aws.StringValue(rrs.Type), aws.StringValue(rrs.SetIdentifier))
+}
+
+func indexOfRecordSet(rrsets []*route53.ResourceRecordSet, rrs *route53.ResourceRecordSet) int {
+	key := recordSetKey(rrs)
+	for i, r := range rrsets {
+		if recordSetKey(r) == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// upsertRecordSet replaces or inserts rrs, keeping rrsets sorted.
+func upsertRecordSet(rrsets []*route53.ResourceRecordSet, rrs *route53.ResourceRecordSet) []*route53.ResourceRecordSet {
+	if i := indexOfRecordSet(rrsets, rrs); i >= 0 {
+		rrsets[i] = rrs
+		return rrsets
+	}
+
+	rrsets = append(rrsets, rrs)
+	sort.SliceStable(rrsets, func(i, j int) bool {
+		return recordSetKey(rrsets[i]) < recordSetKey(rrsets[j])
+	})
+	return rrsets
+}
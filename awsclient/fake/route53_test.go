@@ -0,0 +1,205 @@
+package fake
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/ryane/takethe53/awsclient"
+	"github.com/stretchr/testify/assert"
+)
+
+func aliasChange(action, name, target string) *route53.Change {
+	return &route53.Change{
+		Action: aws.String(action),
+		ResourceRecordSet: &route53.ResourceRecordSet{
+			Name: aws.String(name),
+			Type: aws.String(route53.RRTypeA),
+			AliasTarget: &route53.AliasTarget{
+				DNSName:              aws.String(target),
+				HostedZoneId:         aws.String("Z35SXDOTRQ7X7K"),
+				EvaluateTargetHealth: aws.Bool(true),
+			},
+		},
+	}
+}
+
+func changeBatch(zoneID string, changes ...*route53.Change) *route53.ChangeResourceRecordSetsInput {
+	return &route53.ChangeResourceRecordSetsInput{
+		HostedZoneId: aws.String(zoneID),
+		ChangeBatch:  &route53.ChangeBatch{Changes: changes},
+	}
+}
+
+func TestAliasFlow(t *testing.T) {
+	r53 := NewRoute53()
+	r53.SyncDelay = 50 * time.Millisecond
+	elber := NewELB()
+	r53.AddZone("example.com")
+	elber.AddLoadBalancer("web", "web-123.us-east-1.elb.amazonaws.com", "Z35SXDOTRQ7X7K")
+
+	c := awsclient.NewWithServices(r53, elber)
+
+	zone, err := c.FindZone("example.com")
+	assert.Nil(t, err)
+
+	lb, err := c.FindLoadBalancer("web-123.us-east-1.elb.amazonaws.com")
+	assert.Nil(t, err)
+
+	change, err := c.SetAlias(zone, lb.HostedZoneID, lb.Name, "www")
+	assert.Nil(t, err)
+	assert.Equal(t, awsclient.ChangeStatusPending, change.Status)
+
+	rec, err := c.FindRecord(zone, "www")
+	assert.Nil(t, err)
+	assert.Equal(t, "www.example.com.", rec.Name)
+	assert.Equal(t, "web-123.us-east-1.elb.amazonaws.com.", rec.DNSName)
+
+	status, err := c.GetChangeStatus(change.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, awsclient.ChangeStatusPending, status.Status)
+
+	time.Sleep(r53.SyncDelay)
+	status, err = c.GetChangeStatus(change.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, awsclient.ChangeStatusInSync, status.Status)
+
+	_, err = c.RemoveAlias(zone, "www")
+	assert.Nil(t, err)
+
+	_, err = c.FindRecord(zone, "www")
+	assert.Equal(t, awsclient.ErrRecordNotFound, err)
+}
+
+func TestChangeBatchIsAtomic(t *testing.T) {
+	r53 := NewRoute53()
+	id, _ := r53.AddZone("example.com.")
+
+	_, err := r53.ChangeResourceRecordSets(changeBatch(id,
+		aliasChange(route53.ChangeActionCreate, "a.example.com", "lb-1.elb.amazonaws.com"),
+	))
+	assert.Nil(t, err)
+
+	// the second change fails, so the first must not be applied
+	_, err = r53.ChangeResourceRecordSets(changeBatch(id,
+		aliasChange(route53.ChangeActionCreate, "b.example.com", "lb-1.elb.amazonaws.com"),
+		aliasChange(route53.ChangeActionCreate, "a.example.com", "lb-2.elb.amazonaws.com"),
+	))
+	assert.NotNil(t, err)
+	assert.Equal(t, route53.ErrCodeInvalidChangeBatch, err.(awserr.Error).Code())
+	assert.Equal(t, 3, len(r53.RecordSets(id)), "SOA, NS and a.example.com")
+
+	_, err = r53.ChangeResourceRecordSets(changeBatch(id,
+		aliasChange(route53.ChangeActionDelete, "a.example.com", "lb-2.elb.amazonaws.com"),
+	))
+	assert.Equal(t, route53.ErrCodeInvalidChangeBatch, err.(awserr.Error).Code(), "delete must match current values")
+
+	_, err = r53.ChangeResourceRecordSets(changeBatch(id,
+		aliasChange(route53.ChangeActionCreate, "a.other.com", "lb-2.elb.amazonaws.com"),
+	))
+	assert.Equal(t, route53.ErrCodeInvalidChangeBatch, err.(awserr.Error).Code(), "name must be in zone")
+}
+
+func TestPagination(t *testing.T) {
+	r53 := NewRoute53()
+	r53.PageSize = 2
+	var id string
+	for _, name := range []string{"a.com", "b.com", "c.com", "d.com", "e.com"} {
+		zoneID, _ := r53.AddZone(name)
+		if name == "c.com" {
+			id = zoneID
+		}
+	}
+	for _, name := range []string{"x", "y", "z"} {
+		r53.AddRecordSet(id, &route53.ResourceRecordSet{
+			Name:            aws.String(name + ".c.com"),
+			Type:            aws.String(route53.RRTypeCname),
+			TTL:             aws.Int64(60),
+			ResourceRecords: []*route53.ResourceRecord{{Value: aws.String("target.c.com")}},
+		})
+	}
+
+	c := awsclient.NewWithServices(r53, NewELB())
+	zones, err := c.Zones()
+	assert.Nil(t, err)
+	assert.Equal(t, 5, len(zones))
+
+	pages := 0
+	var names []string
+	err = r53.ListResourceRecordSetsPagesWithContext(context.Background(), &route53.ListResourceRecordSetsInput{HostedZoneId: aws.String(id)}, func(o *route53.ListResourceRecordSetsOutput, lastPage bool) bool {
+		pages++
+		for _, rrs := range o.ResourceRecordSets {
+			names = append(names, aws.StringValue(rrs.Name)+" "+aws.StringValue(rrs.Type))
+		}
+		return true
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, pages)
+	assert.Equal(t, []string{"c.com. NS", "c.com. SOA", "x.c.com. CNAME", "y.c.com. CNAME", "z.c.com. CNAME"}, names)
+}
+
+func TestPaginationWeightedRecords(t *testing.T) {
+	r53 := NewRoute53()
+	zoneID, _ := r53.AddZone("example.com")
+	for _, id := range []string{"a", "b", "c", "d", "e"} {
+		r53.AddRecordSet(zoneID, &route53.ResourceRecordSet{
+			Name:            aws.String("www.example.com"),
+			Type:            aws.String(route53.RRTypeCname),
+			SetIdentifier:   aws.String(id),
+			Weight:          aws.Int64(1),
+			TTL:             aws.Int64(60),
+			ResourceRecords: []*route53.ResourceRecord{{Value: aws.String(id + ".example.com")}},
+		})
+	}
+
+	pages := 0
+	var ids []string
+	input := &route53.ListResourceRecordSetsInput{HostedZoneId: aws.String(zoneID), MaxItems: aws.String("2")}
+	err := r53.ListResourceRecordSetsPagesWithContext(context.Background(), input, func(o *route53.ListResourceRecordSetsOutput, lastPage bool) bool {
+		pages++
+		for _, rrs := range o.ResourceRecordSets {
+			if rrs.SetIdentifier != nil {
+				ids = append(ids, aws.StringValue(rrs.SetIdentifier))
+			}
+		}
+		return pages < 10
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 4, pages)
+	assert.Equal(t, []string{"a", "b", "c", "d", "e"}, ids)
+
+	c := awsclient.NewWithServices(r53, NewELB())
+	rrsets, err := c.RecordSetsNamed(&awsclient.Zone{ID: zoneID, Name: "example.com."}, "www")
+	assert.Nil(t, err)
+	assert.Equal(t, 5, len(rrsets))
+}
+
+func TestInjectError(t *testing.T) {
+	r53 := NewRoute53()
+	r53.AddZone("example.com")
+	r53.InjectError("ListHostedZones", awserr.New("Throttling", "Rate exceeded", nil))
+
+	c := awsclient.NewWithServices(r53, NewELB())
+	_, err := c.FindZone("example.com")
+	assert.Equal(t, "Throttling", err.(awserr.Error).Code())
+
+	_, err = c.FindZone("example.com")
+	assert.Nil(t, err, "injected errors are only returned once")
+}
+
+func TestAddZoneInvalidName(t *testing.T) {
+	r53 := NewRoute53()
+	for _, name := range []string{"", "."} {
+		_, err := r53.AddZone(name)
+		assert.NotNil(t, err, name)
+	}
+}
+
+func TestGetChangeNoExist(t *testing.T) {
+	c := awsclient.NewWithServices(NewRoute53(), NewELB())
+	_, err := c.GetChangeStatus("/change/C000000000000")
+	assert.Equal(t, awsclient.ErrChangeNotFound, err)
+}
@@ -0,0 +1,49 @@
+package awsclient
+
+import (
+	"context"
+	"errors"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+var ErrIdentityUnavailable = errors.New("Caller identity is not available.")
+
+type STSer interface {
+	GetCallerIdentityWithContext(aws.Context, *sts.GetCallerIdentityInput, ...request.Option) (*sts.GetCallerIdentityOutput, error)
+}
+
+// Identity is the AWS principal the client's credentials belong to.
+type Identity struct {
+	ARN     string
+	Account string
+	UserID  string
+}
+
+// SetSTS sets the service CallerIdentity queries.
+func (c *AWSClient) SetSTS(sts STSer) {
+	c.sts = sts
+}
+
+func (c *AWSClient) CallerIdentity() (*Identity, error) {
+	return c.CallerIdentityWithContext(context.Background())
+}
+
+func (c *AWSClient) CallerIdentityWithContext(ctx context.Context) (*Identity, error) {
+	if c.sts == nil {
+		return nil, ErrIdentityUnavailable
+	}
+
+	out, err := c.sts.GetCallerIdentityWithContext(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return nil, checkAWSError(err)
+	}
+
+	return &Identity{
+		ARN:     aws.StringValue(out.Arn),
+		Account: aws.StringValue(out.Account),
+		UserID:  aws.StringValue(out.UserId),
+	}, nil
+}
@@ -0,0 +1,40 @@
+package awsclient
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/ryane/takethe53/awsclient/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type mockSTS struct {
+	mock.Mock
+}
+
+func (m *mockSTS) GetCallerIdentityWithContext(ctx aws.Context, params *sts.GetCallerIdentityInput, opts ...request.Option) (*sts.GetCallerIdentityOutput, error) {
+	args := m.Called(params)
+	return args.Get(0).(*sts.GetCallerIdentityOutput), args.Error(1)
+}
+
+func TestCallerIdentity(t *testing.T) {
+	client := NewWithServices(fake.NewRoute53(), fake.NewELB())
+
+	_, err := client.CallerIdentity()
+	assert.Equal(t, ErrIdentityUnavailable, err)
+
+	m := &mockSTS{}
+	m.On("GetCallerIdentityWithContext", &sts.GetCallerIdentityInput{}).Return(&sts.GetCallerIdentityOutput{
+		Arn:     aws.String("arn:aws:iam::123456789012:user/ryan"),
+		Account: aws.String("123456789012"),
+		UserId:  aws.String("AIDAEXAMPLE"),
+	}, nil)
+	client.SetSTS(m)
+
+	identity, err := client.CallerIdentity()
+	assert.Nil(t, err)
+	assert.Equal(t, &Identity{ARN: "arn:aws:iam::123456789012:user/ryan", Account: "123456789012", UserID: "AIDAEXAMPLE"}, identity)
+}
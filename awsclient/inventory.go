@@ -0,0 +1,114 @@
+package awsclient
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/cloudfront"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+type ELBv2er interface {
+	DescribeLoadBalancersPagesWithContext(aws.Context, *elbv2.DescribeLoadBalancersInput, func(*elbv2.DescribeLoadBalancersOutput, bool) bool, ...request.Option) error
+}
+
+type CloudFronter interface {
+	ListDistributionsPagesWithContext(aws.Context, *cloudfront.ListDistributionsInput, func(*cloudfront.ListDistributionsOutput, bool) bool, ...request.Option) error
+}
+
+type S3er interface {
+	ListBucketsWithContext(aws.Context, *s3.ListBucketsInput, ...request.Option) (*s3.ListBucketsOutput, error)
+}
+
+// Inventory lists the resources of an account that DNS records point at.
+type Inventory struct {
+	// LoadBalancers are the DNS names of classic, application and network
+	// load balancers in the client's region.
+	LoadBalancers []string
+	// Distributions are the domain names of CloudFront distributions.
+	Distributions []string
+	// Buckets are the names of S3 buckets.
+	Buckets []string
+}
+
+// SetInventoryServices sets the services Inventory queries besides classic
+// ELB. Services that are nil are skipped.
+func (c *AWSClient) SetInventoryServices(elbv2 ELBv2er, cloudfront CloudFronter, s3 S3er) {
+	c.elbv2 = elbv2
+	c.cloudfront = cloudfront
+	c.s3 = s3
+}
+
+// LoadBalancerDNSNames returns the DNS names of the classic, application and
+// network load balancers in the client's region.
+func (c *AWSClient) LoadBalancerDNSNames() ([]string, error) {
+	return c.LoadBalancerDNSNamesWithContext(context.Background())
+}
+
+func (c *AWSClient) LoadBalancerDNSNamesWithContext(ctx context.Context) ([]string, error) {
+	lbs, err := c.LoadBalancersWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, lb := range lbs {
+		names = append(names, lb.Name)
+	}
+
+	if c.elbv2 != nil {
+		err := c.elbv2.DescribeLoadBalancersPagesWithContext(ctx, &elbv2.DescribeLoadBalancersInput{PageSize: aws.Int64(400)}, func(o *elbv2.DescribeLoadBalancersOutput, lastPage bool) bool {
+			for _, lb := range o.LoadBalancers {
+				names = append(names, aws.StringValue(lb.DNSName))
+			}
+			return !lastPage
+		})
+		if err != nil {
+			return nil, checkAWSError(err)
+		}
+	}
+
+	return names, nil
+}
+
+func (c *AWSClient) Inventory() (*Inventory, error) {
+	return c.InventoryWithContext(context.Background())
+}
+
+func (c *AWSClient) InventoryWithContext(ctx context.Context) (*Inventory, error) {
+	inv := &Inventory{}
+
+	lbs, err := c.LoadBalancerDNSNamesWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	inv.LoadBalancers = lbs
+
+	if c.cloudfront != nil {
+		err := c.cloudfront.ListDistributionsPagesWithContext(ctx, &cloudfront.ListDistributionsInput{}, func(o *cloudfront.ListDistributionsOutput, lastPage bool) bool {
+			if o.DistributionList != nil {
+				for _, d := range o.DistributionList.Items {
+					inv.Distributions = append(inv.Distributions, aws.StringValue(d.DomainName))
+				}
+			}
+			return !lastPage
+		})
+		if err != nil {
+			return nil, checkAWSError(err)
+		}
+	}
+
+	if c.s3 != nil {
+		out, err := c.s3.ListBucketsWithContext(ctx, &s3.ListBucketsInput{})
+		if err != nil {
+			return nil, checkAWSError(err)
+		}
+		for _, b := range out.Buckets {
+			inv.Buckets = append(inv.Buckets, aws.StringValue(b.Name))
+		}
+	}
+
+	return inv, nil
+}
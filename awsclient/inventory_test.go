@@ -0,0 +1,71 @@
+package awsclient
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/cloudfront"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/ryane/takethe53/awsclient/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type mockInventory struct {
+	mock.Mock
+}
+
+func (m *mockInventory) DescribeLoadBalancersPagesWithContext(ctx aws.Context, params *elbv2.DescribeLoadBalancersInput, fn func(*elbv2.DescribeLoadBalancersOutput, bool) bool, opts ...request.Option) error {
+	args := m.Called(params)
+	fn(&elbv2.DescribeLoadBalancersOutput{
+		LoadBalancers: []*elbv2.LoadBalancer{
+			{DNSName: aws.String("internal-app-1234.us-east-1.elb.amazonaws.com")},
+		},
+	}, true)
+	return args.Error(0)
+}
+
+func (m *mockInventory) ListDistributionsPagesWithContext(ctx aws.Context, params *cloudfront.ListDistributionsInput, fn func(*cloudfront.ListDistributionsOutput, bool) bool, opts ...request.Option) error {
+	args := m.Called(params)
+	fn(&cloudfront.ListDistributionsOutput{
+		DistributionList: &cloudfront.DistributionList{
+			Items: []*cloudfront.DistributionSummary{
+				{DomainName: aws.String("d111111abcdef8.cloudfront.net")},
+			},
+		},
+	}, true)
+	return args.Error(0)
+}
+
+func (m *mockInventory) ListBucketsWithContext(ctx aws.Context, params *s3.ListBucketsInput, opts ...request.Option) (*s3.ListBucketsOutput, error) {
+	args := m.Called(params)
+	return &s3.ListBucketsOutput{
+		Buckets: []*s3.Bucket{{Name: aws.String("www.example.com")}},
+	}, args.Error(0)
+}
+
+func TestInventory(t *testing.T) {
+	lbs := fake.NewELB()
+	lbs.AddLoadBalancer("web", testELBDNSName, testELBZoneID)
+
+	c := NewWithServices(fake.NewRoute53(), lbs)
+	inv, err := c.Inventory()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{testELBDNSName}, inv.LoadBalancers)
+	assert.Empty(t, inv.Distributions, "services that are not set are skipped")
+
+	m := &mockInventory{}
+	m.On("DescribeLoadBalancersPagesWithContext", mock.Anything).Return(nil)
+	m.On("ListDistributionsPagesWithContext", mock.Anything).Return(nil)
+	m.On("ListBucketsWithContext", mock.Anything).Return(nil)
+	c.SetInventoryServices(m, m, m)
+
+	inv, err = c.Inventory()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{testELBDNSName, "internal-app-1234.us-east-1.elb.amazonaws.com"}, inv.LoadBalancers)
+	assert.Equal(t, []string{"d111111abcdef8.cloudfront.net"}, inv.Distributions)
+	assert.Equal(t, []string{"www.example.com"}, inv.Buckets)
+	m.AssertExpectations(t)
+}
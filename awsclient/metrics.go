@@ -0,0 +1,65 @@
+package awsclient
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+)
+
+// Metrics receives measurements from the client. Implementations must be safe
+// for concurrent use. The metrics package has a Prometheus implementation.
+type Metrics interface {
+	// APICall is called once per AWS API request, after all retries of the
+	// SDK. code is the AWS error code or "" on success.
+	APICall(service, op, code string, d time.Duration)
+	// Retry is called when a throttled Route53 request is retried.
+	Retry(op, code string)
+	// CacheLookup is called for every lookup in the cache.
+	CacheLookup(hit bool)
+	// ChangeSubmitted is called for every change batch submitted.
+	ChangeSubmitted(op string, err error)
+	// ChangeInSync is called when WaitUntilInSync sees a change reach
+	// INSYNC, with the time since the change was submitted.
+	ChangeInSync(d time.Duration)
+}
+
+type nopMetrics struct{}
+
+func (nopMetrics) APICall(service, op, code string, d time.Duration) {}
+func (nopMetrics) Retry(op, code string)                             {}
+func (nopMetrics) CacheLookup(hit bool)                              {}
+func (nopMetrics) ChangeSubmitted(op string, err error)              {}
+func (nopMetrics) ChangeInSync(d time.Duration)                      {}
+
+func metricsOrNop(m Metrics) Metrics {
+	if m == nil {
+		return nopMetrics{}
+	}
+	return m
+}
+
+// SetMetrics replaces the client's metrics.
+func (c *AWSClient) SetMetrics(m Metrics) {
+	c.metrics = metricsOrNop(m)
+	if t, ok := c.r53.(*throttledRoute53); ok {
+		t.retry.metrics = c.metrics
+	}
+}
+
+// apiCallHandler reports every completed request to the client's metrics.
+func (c *AWSClient) apiCallHandler() request.NamedHandler {
+	return request.NamedHandler{
+		Name: "takethe53.metrics",
+		Fn: func(r *request.Request) {
+			code := ""
+			if r.Error != nil {
+				code = "Unknown"
+				if awsErr, ok := r.Error.(awserr.Error); ok {
+					code = awsErr.Code()
+				}
+			}
+			c.metrics.APICall(r.ClientInfo.ServiceName, r.Operation.Name, code, time.Since(r.Time))
+		},
+	}
+}
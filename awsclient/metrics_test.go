@@ -0,0 +1,100 @@
+package awsclient
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/ryane/takethe53/awsclient/fake"
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingMetrics struct {
+	mu      sync.Mutex
+	calls   []string
+	retries []string
+	hits    int
+	misses  int
+	changes map[string]int
+	inSync  []time.Duration
+}
+
+func newRecordingMetrics() *recordingMetrics {
+	return &recordingMetrics{changes: map[string]int{}}
+}
+
+func (m *recordingMetrics) APICall(service, op, code string, d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls = append(m.calls, service+" "+op+" "+code)
+}
+
+func (m *recordingMetrics) Retry(op, code string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.retries = append(m.retries, op+" "+code)
+}
+
+func (m *recordingMetrics) CacheLookup(hit bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if hit {
+		m.hits++
+	} else {
+		m.misses++
+	}
+}
+
+func (m *recordingMetrics) ChangeSubmitted(op string, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	m.changes[op+" "+result]++
+}
+
+func (m *recordingMetrics) ChangeInSync(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.inSync = append(m.inSync, d)
+}
+
+func TestMetrics(t *testing.T) {
+	r53 := fake.NewRoute53()
+	r53.SyncDelay = 10 * time.Millisecond
+	zoneID, _ := r53.AddZone("example.com")
+	r53.InjectError("ListHostedZones", awserr.New("Throttling", "Rate exceeded", nil))
+
+	m := newRecordingMetrics()
+	c := newThrottledTestClient(r53, 3)
+	c.cache = NewMemoryCache(time.Minute)
+	c.SetMetrics(m)
+
+	_, err := c.FindZone("example.com")
+	assert.Nil(t, err)
+	_, err = c.FindZone("example.com")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"ListHostedZones Throttling"}, m.retries)
+	assert.Equal(t, 1, m.misses)
+	assert.Equal(t, 1, m.hits)
+
+	zone := &Zone{ID: zoneID, Name: "example.com."}
+	change, err := c.SetAlias(zone, testELBZoneID, testELBDNSName, "www")
+	assert.Nil(t, err)
+	_, err = c.RemoveAlias(zone, "nope")
+	assert.Equal(t, ErrRecordNotFound, err, "nothing is submitted")
+	_, err = c.SetAliasWithOptions(context.Background(), zone, testELBZoneID, testELBDNSName, "www", AliasOptions{IfNotExists: true})
+	assert.Equal(t, ErrConflict, err)
+	assert.Equal(t, map[string]int{OpSetAlias + " success": 1, OpSetAlias + " error": 1}, m.changes)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_, err = c.WaitUntilInSync(ctx, change.ID, 5*time.Millisecond)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(m.inSync))
+	assert.True(t, m.inSync[0] >= r53.SyncDelay)
+}
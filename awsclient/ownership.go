@@ -0,0 +1,146 @@
+package awsclient
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/route53"
+)
+
+// Ownership of a record is stored in a TXT record with the same name, in the
+// same format external-dns uses:
+//
+//	"heritage=takethe53,takethe53/owner=<owner id>"
+const (
+	ownershipHeritage = "heritage=takethe53"
+	ownershipOwnerKey = "takethe53/owner="
+	ownershipTTL      = 300
+)
+
+var ErrNotOwner = errors.New("Record is owned by someone else. Use --force to override.")
+
+// Owner returns the owner ID recorded for a set of record sets with the same
+// name, or "" if none of them is managed by takethe53.
+func Owner(rrsets []*route53.ResourceRecordSet) string {
+	if txt := ownershipRecordSet(rrsets); txt != nil {
+		for _, rr := range txt.ResourceRecords {
+			if owner, ok := parseOwnership(aws.StringValue(rr.Value)); ok {
+				return owner
+			}
+		}
+	}
+	return ""
+}
+
+// IsOwnershipRecord reports whether rrs is a TXT record that only holds
+// takethe53 ownership information.
+func IsOwnershipRecord(rrs *route53.ResourceRecordSet) bool {
+	if aws.StringValue(rrs.Type) != route53.RRTypeTxt || len(rrs.ResourceRecords) != 1 {
+		return false
+	}
+	_, ok := parseOwnership(aws.StringValue(rrs.ResourceRecords[0].Value))
+	return ok
+}
+
+// checkOwner returns ErrNotOwner if rrsets are owned by anyone but c. Without
+// an owner ID only unowned records can be modified.
+func (c *AWSClient) checkOwner(rrsets []*route53.ResourceRecordSet) error {
+	if Owner(rrsets) != c.ownerID {
+		return ErrNotOwner
+	}
+	return nil
+}
+
+// claimOwnership returns the change that records c.ownerID as the owner of
+// name, keeping any unrelated values of an existing TXT record.
+func (c *AWSClient) claimOwnership(name string, existing []*route53.ResourceRecordSet) *route53.Change {
+	values := []*route53.ResourceRecord{
+		{Value: aws.String(ownershipValue(c.ownerID))},
+	}
+	ttl := int64(ownershipTTL)
+
+	if txt := findRecordSet(existing, route53.RRTypeTxt); txt != nil {
+		values = append(values, otherTXTValues(txt)...)
+		ttl = aws.Int64Value(txt.TTL)
+	}
+
+	return &route53.Change{
+		Action: aws.String(route53.ChangeActionUpsert),
+		ResourceRecordSet: &route53.ResourceRecordSet{
+			Name:            aws.String(name),
+			Type:            aws.String(route53.RRTypeTxt),
+			TTL:             aws.Int64(ttl),
+			ResourceRecords: values,
+		},
+	}
+}
+
+// releaseOwnership returns the change that removes the ownership value from
+// the TXT record of a name, or nil if there is none.
+func releaseOwnership(existing []*route53.ResourceRecordSet) *route53.Change {
+	txt := ownershipRecordSet(existing)
+	if txt == nil {
+		return nil
+	}
+
+	others := otherTXTValues(txt)
+	if len(others) == 0 {
+		return &route53.Change{
+			Action:            aws.String(route53.ChangeActionDelete),
+			ResourceRecordSet: txt,
+		}
+	}
+
+	return &route53.Change{
+		Action: aws.String(route53.ChangeActionUpsert),
+		ResourceRecordSet: &route53.ResourceRecordSet{
+			Name:            txt.Name,
+			Type:            txt.Type,
+			TTL:             txt.TTL,
+			ResourceRecords: others,
+		},
+	}
+}
+
+func ownershipRecordSet(rrsets []*route53.ResourceRecordSet) *route53.ResourceRecordSet {
+	txt := findRecordSet(rrsets, route53.RRTypeTxt)
+	if txt == nil {
+		return nil
+	}
+
+	for _, rr := range txt.ResourceRecords {
+		if _, ok := parseOwnership(aws.StringValue(rr.Value)); ok {
+			return txt
+		}
+	}
+	return nil
+}
+
+func otherTXTValues(txt *route53.ResourceRecordSet) []*route53.ResourceRecord {
+	var others []*route53.ResourceRecord
+	for _, rr := range txt.ResourceRecords {
+		if _, ok := parseOwnership(aws.StringValue(rr.Value)); !ok {
+			others = append(others, rr)
+		}
+	}
+	return others
+}
+
+func ownershipValue(owner string) string {
+	return `"` + ownershipHeritage + "," + ownershipOwnerKey + owner + `"`
+}
+
+func parseOwnership(value string) (string, bool) {
+	fields := strings.Split(strings.Trim(value, `"`), ",")
+	if len(fields) < 2 || fields[0] != ownershipHeritage {
+		return "", false
+	}
+
+	for _, f := range fields[1:] {
+		if strings.HasPrefix(f, ownershipOwnerKey) {
+			return strings.TrimPrefix(f, ownershipOwnerKey), true
+		}
+	}
+	return "", false
+}
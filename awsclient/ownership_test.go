@@ -0,0 +1,129 @@
+package awsclient
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/ryane/takethe53/awsclient/fake"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testELBZoneID  = "Z3DZX7HGU9N41H"
+	testELBDNSName = "aerfflakjdfljadlkfjal-77828384.us-east-1.elb.amazonaws.com"
+)
+
+func newOwnedTestClient(r53 *fake.Route53, owner string) *AWSClient {
+	c := NewWithServices(r53, fake.NewELB())
+	c.ownerID = owner
+	return c
+}
+
+func TestOwnership(t *testing.T) {
+	ctx := context.Background()
+	r53 := fake.NewRoute53()
+	zoneID, _ := r53.AddZone("example.com")
+	zone := &Zone{ID: zoneID, Name: "example.com."}
+
+	teamA := newOwnedTestClient(r53, "team-a")
+	teamB := newOwnedTestClient(r53, "team-b")
+
+	_, err := teamA.SetAlias(zone, testELBZoneID, testELBDNSName, "www")
+	assert.Nil(t, err)
+
+	existing, err := teamA.recordSetsNamed(ctx, zone, "www.example.com.")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(existing), "alias and ownership record")
+	assert.Equal(t, "team-a", Owner(existing))
+
+	// the owner can update its own record
+	_, err = teamA.SetAlias(zone, testELBZoneID, testELBDNSName, "www")
+	assert.Nil(t, err)
+
+	_, err = teamB.SetAlias(zone, testELBZoneID, testELBDNSName, "www")
+	assert.Equal(t, ErrNotOwner, err)
+
+	_, err = teamB.RemoveAlias(zone, "www")
+	assert.Equal(t, ErrNotOwner, err)
+
+	_, err = teamB.SetAliasWithOptions(ctx, zone, testELBZoneID, testELBDNSName, "www", AliasOptions{Force: true})
+	assert.Nil(t, err)
+
+	existing, _ = teamB.recordSetsNamed(ctx, zone, "www.example.com.")
+	assert.Equal(t, "team-b", Owner(existing))
+
+	_, err = teamA.RemoveAlias(zone, "www")
+	assert.Equal(t, ErrNotOwner, err)
+
+	_, err = teamB.RemoveAlias(zone, "www")
+	assert.Nil(t, err)
+
+	existing, _ = teamB.recordSetsNamed(ctx, zone, "www.example.com.")
+	assert.Equal(t, 0, len(existing), "alias and ownership record are deleted")
+}
+
+func TestOwnershipOfUnmanagedRecords(t *testing.T) {
+	ctx := context.Background()
+	r53 := fake.NewRoute53()
+	zoneID, _ := r53.AddZone("example.com")
+	zone := &Zone{ID: zoneID, Name: "example.com."}
+
+	// an alias and an SPF record created outside of takethe53
+	NewWithServices(r53, fake.NewELB()).SetAlias(zone, testELBZoneID, testELBDNSName, "www")
+	r53.AddRecordSet(zoneID, &route53.ResourceRecordSet{
+		Name:            aws.String("www.example.com."),
+		Type:            aws.String(route53.RRTypeTxt),
+		TTL:             aws.Int64(60),
+		ResourceRecords: []*route53.ResourceRecord{{Value: aws.String(`"v=spf1 -all"`)}},
+	})
+
+	c := newOwnedTestClient(r53, "team-a")
+	_, err := c.SetAlias(zone, testELBZoneID, testELBDNSName, "www")
+	assert.Equal(t, ErrNotOwner, err)
+
+	_, err = c.SetAliasWithOptions(ctx, zone, testELBZoneID, testELBDNSName, "www", AliasOptions{Force: true})
+	assert.Nil(t, err)
+
+	existing, _ := c.recordSetsNamed(ctx, zone, "www.example.com.")
+	txt := findRecordSet(existing, route53.RRTypeTxt)
+	assert.Equal(t, 2, len(txt.ResourceRecords), "the SPF value is kept")
+
+	_, err = c.RemoveAlias(zone, "www")
+	assert.Nil(t, err)
+
+	existing, _ = c.recordSetsNamed(ctx, zone, "www.example.com.")
+	assert.Equal(t, 1, len(existing))
+	assert.Equal(t, `"v=spf1 -all"`, aws.StringValue(existing[0].ResourceRecords[0].Value))
+}
+
+func TestOwnershipWithoutOwnerID(t *testing.T) {
+	ctx := context.Background()
+	r53 := fake.NewRoute53()
+	zoneID, _ := r53.AddZone("example.com")
+	zone := &Zone{ID: zoneID, Name: "example.com."}
+
+	_, err := newOwnedTestClient(r53, "team-a").SetAlias(zone, testELBZoneID, testELBDNSName, "www")
+	assert.Nil(t, err)
+
+	anonymous := NewWithServices(r53, fake.NewELB())
+	_, err = anonymous.SetAlias(zone, testELBZoneID, testELBDNSName, "www")
+	assert.Equal(t, ErrNotOwner, err)
+
+	_, err = anonymous.RemoveAlias(zone, "www")
+	assert.Equal(t, ErrNotOwner, err)
+
+	_, err = anonymous.MoveAliasWithOptions(ctx, zone, "www", "www2", AliasOptions{})
+	assert.Equal(t, ErrNotOwner, err)
+
+	// unowned records can still be managed without an owner ID
+	_, err = anonymous.SetAlias(zone, testELBZoneID, testELBDNSName, "api")
+	assert.Nil(t, err)
+
+	_, err = anonymous.RemoveAliasWithOptions(ctx, zone, "www", AliasOptions{Force: true})
+	assert.Nil(t, err)
+
+	existing, _ := anonymous.recordSetsNamed(ctx, zone, "www.example.com.")
+	assert.Equal(t, 0, len(existing), "the ownership record is released too")
+}
@@ -1,12 +1,16 @@
 package awsclient
 
 import (
+	"context"
 	"errors"
+	"reflect"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/route53"
 )
 
@@ -14,6 +18,11 @@ var (
 	ErrZoneNotFound   = errors.New("Zone does not exist.")
 	ErrRecordNotFound = errors.New("Record does not exist.")
 	ErrChangeNotFound = errors.New("Change does not exist.")
+	ErrConflict       = errors.New("Record does not match the expected state. It was changed by someone else.")
+	ErrRecordExists   = errors.New("Record already exists.")
+	ErrSameRecord     = errors.New("Source and destination are the same record.")
+
+	ErrConflictingAliasOptions = errors.New("IfNotExists and ExpectTarget can't be combined.")
 )
 
 const (
@@ -22,10 +31,10 @@ const (
 )
 
 type Route53er interface {
-	ListHostedZonesPages(*route53.ListHostedZonesInput, func(*route53.ListHostedZonesOutput, bool) bool) error
-	ChangeResourceRecordSets(input *route53.ChangeResourceRecordSetsInput) (*route53.ChangeResourceRecordSetsOutput, error)
-	ListResourceRecordSetsPages(*route53.ListResourceRecordSetsInput, func(*route53.ListResourceRecordSetsOutput, bool) bool) error
-	GetChange(*route53.GetChangeInput) (*route53.GetChangeOutput, error)
+	ListHostedZonesPagesWithContext(aws.Context, *route53.ListHostedZonesInput, func(*route53.ListHostedZonesOutput, bool) bool, ...request.Option) error
+	ChangeResourceRecordSetsWithContext(aws.Context, *route53.ChangeResourceRecordSetsInput, ...request.Option) (*route53.ChangeResourceRecordSetsOutput, error)
+	ListResourceRecordSetsPagesWithContext(aws.Context, *route53.ListResourceRecordSetsInput, func(*route53.ListResourceRecordSetsOutput, bool) bool, ...request.Option) error
+	GetChangeWithContext(aws.Context, *route53.GetChangeInput, ...request.Option) (*route53.GetChangeOutput, error)
 }
 
 type Zone struct {
@@ -40,6 +49,21 @@ type Record struct {
 	EvaluateTargetHealth bool
 }
 
+// AliasOptions changes how SetAlias and RemoveAlias treat existing records.
+type AliasOptions struct {
+	// Force overwrites or deletes records owned by someone else.
+	Force bool
+	// IfNotExists only creates the alias if there is no record yet.
+	IfNotExists bool
+	// ExpectTarget only updates the alias if it currently points at this
+	// DNS name.
+	ExpectTarget string
+}
+
+func (o AliasOptions) conditional() bool {
+	return o.IfNotExists || o.ExpectTarget != ""
+}
+
 type ChangeStatus struct {
 	ID          string
 	Status      string
@@ -48,47 +72,123 @@ type ChangeStatus struct {
 }
 
 func (c *AWSClient) Zones() ([]*Zone, error) {
-	params := &route53.ListHostedZonesInput{MaxItems: aws.String("100")}
+	return c.ZonesWithContext(context.Background())
+}
 
+func (c *AWSClient) ZonesWithContext(ctx context.Context) ([]*Zone, error) {
 	var zones []*Zone
-	err := c.r53.ListHostedZonesPages(params, func(o *route53.ListHostedZonesOutput, lastPage bool) bool {
-		for _, hz := range o.HostedZones {
-			zones = append(zones, &Zone{
-				Name: aws.StringValue(hz.Name),
-				ID:   aws.StringValue(hz.Id),
-			})
+	err := c.cached(cacheKeyZones, &zones, func() error {
+		zones = nil
+		params := &route53.ListHostedZonesInput{MaxItems: aws.String("100")}
+		err := c.r53.ListHostedZonesPagesWithContext(ctx, params, func(o *route53.ListHostedZonesOutput, lastPage bool) bool {
+			for _, hz := range o.HostedZones {
+				zones = append(zones, &Zone{
+					Name: aws.StringValue(hz.Name),
+					ID:   aws.StringValue(hz.Id),
+				})
+			}
+			return !lastPage
+		})
+		if err != nil {
+			return checkAWSError(err)
 		}
-		return !lastPage
+		return nil
 	})
 
 	if err != nil {
-		return nil, checkAWSError(err)
+		return nil, err
 	}
 
 	return zones, nil
 }
 
+// Ping makes the cheapest Route53 call there is to check that the endpoint
+// is reachable and the credentials are valid.
+func (c *AWSClient) Ping(ctx context.Context) error {
+	params := &route53.ListHostedZonesInput{MaxItems: aws.String("1")}
+	err := c.r53.ListHostedZonesPagesWithContext(ctx, params, func(o *route53.ListHostedZonesOutput, lastPage bool) bool {
+		return false
+	})
+	if err != nil {
+		return checkAWSError(err)
+	}
+	return nil
+}
+
+// RecordSets returns every record set in a zone.
+func (c *AWSClient) RecordSets(zone *Zone) ([]*route53.ResourceRecordSet, error) {
+	return c.RecordSetsWithContext(context.Background(), zone)
+}
+
+func (c *AWSClient) RecordSetsWithContext(ctx context.Context, zone *Zone) ([]*route53.ResourceRecordSet, error) {
+	var rrsets []*route53.ResourceRecordSet
+	err := c.cached(cacheKeyRecordSets+zone.ID, &rrsets, func() error {
+		rrsets = nil
+		params := &route53.ListResourceRecordSetsInput{
+			HostedZoneId: aws.String(zone.ID),
+			MaxItems:     aws.String("100"),
+		}
+		err := c.r53.ListResourceRecordSetsPagesWithContext(ctx, params, func(o *route53.ListResourceRecordSetsOutput, lastPage bool) bool {
+			rrsets = append(rrsets, o.ResourceRecordSets...)
+			return !lastPage
+		})
+		if err != nil {
+			return checkAWSError(err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return rrsets, nil
+}
+
 func (c *AWSClient) FindZone(name string) (*Zone, error) {
+	return c.FindZoneWithContext(context.Background(), name)
+}
+
+func (c *AWSClient) FindZoneWithContext(ctx context.Context, name string) (*Zone, error) {
 	zoneName := name
 	if !strings.HasSuffix(zoneName, ".") {
 		zoneName += "."
 	}
 
-	zones, err := c.Zones()
+	zones, err := c.ZonesWithContext(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	for _, z := range zones {
-		if strings.EqualFold(zoneName, z.Name) {
-			return z, nil
+	zone := findZone(zones, zoneName)
+	if zone == nil && c.cache != nil {
+		// the cached zones may be from before the zone was created
+		c.invalidate(cacheKeyZones)
+		if zones, err = c.ZonesWithContext(ctx); err != nil {
+			return nil, err
 		}
+		zone = findZone(zones, zoneName)
+	}
+	if zone == nil {
+		return nil, ErrZoneNotFound
 	}
 
-	return nil, ErrZoneNotFound
+	return zone, nil
+}
+
+func findZone(zones []*Zone, name string) *Zone {
+	for _, z := range zones {
+		if strings.EqualFold(name, z.Name) {
+			return z
+		}
+	}
+	return nil
 }
 
 func (c *AWSClient) FindRecord(zone *Zone, alias string) (*Record, error) {
+	return c.FindRecordWithContext(context.Background(), zone, alias)
+}
+
+func (c *AWSClient) FindRecordWithContext(ctx context.Context, zone *Zone, alias string) (*Record, error) {
 	aliasDnsName := aliasDnsName(alias, zone)
 
 	params := &route53.ListResourceRecordSetsInput{
@@ -97,9 +197,9 @@ func (c *AWSClient) FindRecord(zone *Zone, alias string) (*Record, error) {
 	}
 
 	var rec *Record
-	err := c.r53.ListResourceRecordSetsPages(params, func(o *route53.ListResourceRecordSetsOutput, lastPage bool) bool {
+	err := c.r53.ListResourceRecordSetsPagesWithContext(ctx, params, func(o *route53.ListResourceRecordSetsOutput, lastPage bool) bool {
 		for _, rrs := range o.ResourceRecordSets {
-			if strings.EqualFold(aws.StringValue(rrs.Name), aliasDnsName) {
+			if rrs.AliasTarget != nil && strings.EqualFold(aws.StringValue(rrs.Name), aliasDnsName) {
 				rec = &Record{
 					Name:                 aws.StringValue(rrs.Name),
 					DNSName:              aws.StringValue(rrs.AliasTarget.DNSName),
@@ -124,60 +224,266 @@ func (c *AWSClient) FindRecord(zone *Zone, alias string) (*Record, error) {
 }
 
 func (c *AWSClient) SetAlias(zone *Zone, hzid, elbDnsName, alias string) (*ChangeStatus, error) {
+	return c.SetAliasWithContext(context.Background(), zone, hzid, elbDnsName, alias)
+}
+
+func (c *AWSClient) SetAliasWithContext(ctx context.Context, zone *Zone, hzid, elbDnsName, alias string) (*ChangeStatus, error) {
+	return c.SetAliasWithOptions(ctx, zone, hzid, elbDnsName, alias, AliasOptions{})
+}
+
+func (c *AWSClient) SetAliasWithOptions(ctx context.Context, zone *Zone, hzid, elbDnsName, alias string, opts AliasOptions) (*ChangeStatus, error) {
+	changes, err := c.SetAliasChanges(ctx, zone, hzid, elbDnsName, alias, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	status, err := c.ChangeRecordSets(ctx, zone, OpSetAlias, changes)
+	if err != nil && opts.conditional() && isInvalidChangeBatch(err) {
+		// the record changed between our read and the write
+		return nil, ErrConflict
+	}
+
+	return status, err
+}
+
+// SetAliasChanges returns the changes SetAliasWithOptions submits, without
+// submitting them.
+func (c *AWSClient) SetAliasChanges(ctx context.Context, zone *Zone, hzid, elbDnsName, alias string, opts AliasOptions) ([]*route53.Change, error) {
+	if opts.IfNotExists && opts.ExpectTarget != "" {
+		return nil, ErrConflictingAliasOptions
+	}
+
 	aliasDnsName := aliasDnsName(alias, zone)
-	params := &route53.ChangeResourceRecordSetsInput{
-		HostedZoneId: aws.String(zone.ID),
-		ChangeBatch: &route53.ChangeBatch{
-			Changes: []*route53.Change{
-				{
-					Action: aws.String("UPSERT"),
-					ResourceRecordSet: &route53.ResourceRecordSet{
-						Name: aws.String(aliasDnsName),
-						Type: aws.String("A"),
-						AliasTarget: &route53.AliasTarget{
-							HostedZoneId:         aws.String(hzid),
-							DNSName:              aws.String(elbDnsName),
-							EvaluateTargetHealth: aws.Bool(true),
-						},
-					},
-				},
-			},
+	rrs := &route53.ResourceRecordSet{
+		Name: aws.String(aliasDnsName),
+		Type: aws.String("A"),
+		AliasTarget: &route53.AliasTarget{
+			HostedZoneId:         aws.String(hzid),
+			DNSName:              aws.String(elbDnsName),
+			EvaluateTargetHealth: aws.Bool(true),
 		},
 	}
-	out, err := c.r53.ChangeResourceRecordSets(params)
+
+	var changes []*route53.Change
+	switch {
+	case opts.IfNotExists:
+		changes = []*route53.Change{
+			{Action: aws.String(route53.ChangeActionCreate), ResourceRecordSet: rrs},
+		}
+	case opts.ExpectTarget != "":
+		// delete the expected record and create the new one in the same
+		// batch, Route53 rejects the whole batch if the delete doesn't match
+		rec, err := c.FindRecordWithContext(ctx, zone, alias)
+		if err == ErrRecordNotFound || (err == nil && !sameDNSName(rec.DNSName, opts.ExpectTarget)) {
+			return nil, ErrConflict
+		}
+		if err != nil {
+			return nil, err
+		}
+		changes = []*route53.Change{
+			{Action: aws.String(route53.ChangeActionDelete), ResourceRecordSet: rec.ResourceRecordSet()},
+			{Action: aws.String(route53.ChangeActionCreate), ResourceRecordSet: rrs},
+		}
+	default:
+		changes = []*route53.Change{
+			{Action: aws.String("UPSERT"), ResourceRecordSet: rrs},
+		}
+	}
+
+	// records owned by someone are protected even without an owner ID
+	existing, err := c.recordSetsNamed(ctx, zone, aliasDnsName)
 	if err != nil {
-		return nil, checkAWSError(err)
+		return nil, err
 	}
 
-	return changeInfoToChangeStatus(out.ChangeInfo), nil
+	// a CREATE can't overwrite anything
+	if !opts.IfNotExists && !opts.Force && findRecordSet(existing, route53.RRTypeA) != nil {
+		if err := c.checkOwner(existing); err != nil {
+			return nil, err
+		}
+	}
+
+	if c.ownerID == "" {
+		return changes, nil
+	}
+
+	if opts.IfNotExists && findRecordSet(existing, route53.RRTypeA) != nil {
+		return nil, ErrConflict
+	}
+
+	return append(changes, c.claimOwnership(aliasDnsName, existing)), nil
 }
 
 func (c *AWSClient) RemoveAlias(zone *Zone, alias string) (*ChangeStatus, error) {
-	rec, err := c.FindRecord(zone, alias)
+	return c.RemoveAliasWithContext(context.Background(), zone, alias)
+}
+
+func (c *AWSClient) RemoveAliasWithContext(ctx context.Context, zone *Zone, alias string) (*ChangeStatus, error) {
+	return c.RemoveAliasWithOptions(ctx, zone, alias, AliasOptions{})
+}
+
+func (c *AWSClient) RemoveAliasWithOptions(ctx context.Context, zone *Zone, alias string, opts AliasOptions) (*ChangeStatus, error) {
+	changes, err := c.RemoveAliasChanges(ctx, zone, alias, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.ChangeRecordSets(ctx, zone, OpRemoveAlias, changes)
+}
+
+// RemoveAliasChanges returns the changes RemoveAliasWithOptions submits,
+// without submitting them.
+func (c *AWSClient) RemoveAliasChanges(ctx context.Context, zone *Zone, alias string, opts AliasOptions) ([]*route53.Change, error) {
+	rec, err := c.FindRecordWithContext(ctx, zone, alias)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := []*route53.Change{
+		{
+			Action:            aws.String(route53.ChangeActionDelete),
+			ResourceRecordSet: rec.ResourceRecordSet(),
+		},
+	}
+
+	existing, err := c.recordSetsNamed(ctx, zone, rec.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	if !opts.Force {
+		if err := c.checkOwner(existing); err != nil {
+			return nil, err
+		}
+	}
+
+	if release := releaseOwnership(existing); release != nil {
+		changes = append(changes, release)
+	}
+
+	return changes, nil
+}
+
+func (c *AWSClient) MoveAlias(zone *Zone, from, to string) (*ChangeStatus, error) {
+	return c.MoveAliasWithOptions(context.Background(), zone, from, to, AliasOptions{})
+}
+
+// MoveAliasWithOptions renames an alias within zone. The old record is
+// deleted and the new one created in the same change batch, so there is no
+// moment in which both or neither exist. Only opts.Force is used.
+func (c *AWSClient) MoveAliasWithOptions(ctx context.Context, zone *Zone, from, to string, opts AliasOptions) (*ChangeStatus, error) {
+	changes, err := c.MoveAliasChanges(ctx, zone, from, to, opts)
 	if err != nil {
 		return nil, err
 	}
 
+	status, err := c.ChangeRecordSets(ctx, zone, OpMoveAlias, changes)
+	if err != nil && isInvalidChangeBatch(err) && c.changedSince(ctx, zone, changes) {
+		// either name changed between our read and the write
+		return nil, ErrConflict
+	}
+
+	return status, err
+}
+
+// MoveAliasChanges returns the changes MoveAliasWithOptions submits, without
+// submitting them.
+func (c *AWSClient) MoveAliasChanges(ctx context.Context, zone *Zone, from, to string, opts AliasOptions) ([]*route53.Change, error) {
+	name := aliasDnsName(to, zone)
+	if sameDNSName(aliasDnsName(from, zone), name) {
+		return nil, ErrSameRecord
+	}
+
+	source, err := c.recordSetsNamed(ctx, zone, aliasDnsName(from, zone))
+	if err != nil {
+		return nil, err
+	}
+
+	var aliases []*route53.ResourceRecordSet
+	for _, rrs := range source {
+		if rrs.AliasTarget != nil {
+			aliases = append(aliases, rrs)
+		}
+	}
+	if len(aliases) == 0 {
+		return nil, ErrRecordNotFound
+	}
+
+	existing, err := c.recordSetsNamed(ctx, zone, name)
+	if err != nil {
+		return nil, err
+	}
+	if findRecordSet(existing, route53.RRTypeCname) != nil {
+		return nil, ErrRecordExists
+	}
+	for _, rrs := range aliases {
+		for _, e := range existing {
+			if aws.StringValue(e.Type) == aws.StringValue(rrs.Type) && aws.StringValue(e.SetIdentifier) == aws.StringValue(rrs.SetIdentifier) {
+				return nil, ErrRecordExists
+			}
+		}
+	}
+
+	if !opts.Force {
+		if err := c.checkOwner(source); err != nil {
+			return nil, err
+		}
+	}
+
+	// every alias record set of the name moves, like the A and AAAA record
+	// sets or the members of a weighted group
+	var changes []*route53.Change
+	for _, rrs := range aliases {
+		moved := *rrs
+		moved.Name = aws.String(name)
+		changes = append(changes,
+			&route53.Change{Action: aws.String(route53.ChangeActionDelete), ResourceRecordSet: rrs},
+			&route53.Change{Action: aws.String(route53.ChangeActionCreate), ResourceRecordSet: &moved},
+		)
+	}
+
+	// deleting the old records also releases their ownership
+	if release := releaseOwnership(source); release != nil {
+		changes = append(changes, release)
+	}
+
+	if c.ownerID != "" {
+		changes = append(changes, c.claimOwnership(name, existing))
+	}
+
+	return changes, nil
+}
+
+// ChangeRecordSets submits a change batch to zone. op names the operation
+// for change handlers.
+func (c *AWSClient) ChangeRecordSets(ctx context.Context, zone *Zone, op string, changes []*route53.Change) (*ChangeStatus, error) {
+	ev := &ChangeEvent{Op: op, Zone: zone, Changes: changes}
+	if err := c.validateChange(ctx, ev); err != nil {
+		ev.Err = err
+		ev.Time = time.Now()
+		c.notifyChange(ctx, ev)
+		return nil, err
+	}
+
+	if len(c.changeHandlers) > 0 {
+		ev.Previous, ev.PreviousErr = c.previousRecordSets(ctx, zone, changes)
+	}
+
+	ev.Status, ev.Err = c.changeRecordSets(ctx, zone, changes)
+	ev.Time = time.Now()
+	c.metrics.ChangeSubmitted(op, ev.Err)
+	c.notifyChange(ctx, ev)
+
+	return ev.Status, ev.Err
+}
+
+func (c *AWSClient) changeRecordSets(ctx context.Context, zone *Zone, changes []*route53.Change) (*ChangeStatus, error) {
 	params := &route53.ChangeResourceRecordSetsInput{
 		HostedZoneId: aws.String(zone.ID),
-		ChangeBatch: &route53.ChangeBatch{
-			Changes: []*route53.Change{
-				{
-					Action: aws.String(route53.ChangeActionDelete),
-					ResourceRecordSet: &route53.ResourceRecordSet{
-						Name: aws.String(rec.Name),
-						Type: aws.String("A"),
-						AliasTarget: &route53.AliasTarget{
-							DNSName:              aws.String(rec.DNSName),
-							HostedZoneId:         aws.String(rec.HostedZoneID),
-							EvaluateTargetHealth: aws.Bool(rec.EvaluateTargetHealth),
-						},
-					},
-				},
-			},
-		},
+		ChangeBatch:  &route53.ChangeBatch{Changes: changes},
 	}
-	out, err := c.r53.ChangeResourceRecordSets(params)
+	out, err := c.r53.ChangeResourceRecordSetsWithContext(ctx, params)
+	c.invalidate(cacheKeyRecordSets + zone.ID)
 	if err != nil {
 		return nil, checkAWSError(err)
 	}
@@ -185,12 +491,130 @@ func (c *AWSClient) RemoveAlias(zone *Zone, alias string) (*ChangeStatus, error)
 	return changeInfoToChangeStatus(out.ChangeInfo), nil
 }
 
+// RecordSetsNamed returns every record set of alias in zone, like the A and
+// AAAA record sets of a name.
+func (c *AWSClient) RecordSetsNamed(zone *Zone, alias string) ([]*route53.ResourceRecordSet, error) {
+	return c.RecordSetsNamedWithContext(context.Background(), zone, alias)
+}
+
+func (c *AWSClient) RecordSetsNamedWithContext(ctx context.Context, zone *Zone, alias string) ([]*route53.ResourceRecordSet, error) {
+	return c.recordSetsNamed(ctx, zone, aliasDnsName(alias, zone))
+}
+
+// recordSetsNamed returns every record set with the given name.
+func (c *AWSClient) recordSetsNamed(ctx context.Context, zone *Zone, name string) ([]*route53.ResourceRecordSet, error) {
+	params := &route53.ListResourceRecordSetsInput{
+		HostedZoneId:    aws.String(zone.ID),
+		StartRecordName: aws.String(name),
+		MaxItems:        aws.String("100"),
+	}
+
+	var rrsets []*route53.ResourceRecordSet
+	err := c.r53.ListResourceRecordSetsPagesWithContext(ctx, params, func(o *route53.ListResourceRecordSetsOutput, lastPage bool) bool {
+		for _, rrs := range o.ResourceRecordSets {
+			if !sameDNSName(aws.StringValue(rrs.Name), name) {
+				// record sets are sorted by name, so there are no more
+				return false
+			}
+			rrsets = append(rrsets, rrs)
+		}
+		return !lastPage
+	})
+
+	if err != nil {
+		return nil, checkAWSError(err)
+	}
+
+	return rrsets, nil
+}
+
+// changedSince reports whether the record sets that changes delete or create
+// were modified since the changes were built, which makes Route53 reject the
+// batch. A failed lookup counts as changed.
+func (c *AWSClient) changedSince(ctx context.Context, zone *Zone, changes []*route53.Change) bool {
+	current := map[string][]*route53.ResourceRecordSet{}
+	for _, change := range changes {
+		rrs := change.ResourceRecordSet
+		name := strings.ToLower(aws.StringValue(rrs.Name))
+		rrsets, ok := current[name]
+		if !ok {
+			var err error
+			if rrsets, err = c.recordSetsNamed(ctx, zone, name); err != nil {
+				return true
+			}
+			current[name] = rrsets
+		}
+
+		var found *route53.ResourceRecordSet
+		for _, r := range rrsets {
+			if sameRecordSetKey(r, rrs) {
+				found = r
+			}
+		}
+
+		switch aws.StringValue(change.Action) {
+		case route53.ChangeActionDelete:
+			if found == nil || !reflect.DeepEqual(found, rrs) {
+				return true
+			}
+		case route53.ChangeActionCreate:
+			if found != nil || findRecordSet(rrsets, route53.RRTypeCname) != nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isInvalidChangeBatch(err error) bool {
+	awserr, ok := err.(awserr.Error)
+	return ok && awserr.Code() == route53.ErrCodeInvalidChangeBatch
+}
+
+func sameDNSName(a, b string) bool {
+	return strings.EqualFold(strings.TrimSuffix(UnescapeName(a), "."), strings.TrimSuffix(UnescapeName(b), "."))
+}
+
+// UnescapeName decodes the \ooo octal escapes Route53 uses in the record
+// names it returns, like \052 for the * of a wildcard record.
+func UnescapeName(name string) string {
+	if !strings.Contains(name, `\`) {
+		return name
+	}
+
+	var b []byte
+	for i := 0; i < len(name); i++ {
+		if name[i] == '\\' && i+3 < len(name) {
+			if n, err := strconv.ParseUint(name[i+1:i+4], 8, 8); err == nil {
+				b = append(b, byte(n))
+				i += 3
+				continue
+			}
+		}
+		b = append(b, name[i])
+	}
+	return string(b)
+}
+
+func findRecordSet(rrsets []*route53.ResourceRecordSet, rrType string) *route53.ResourceRecordSet {
+	for _, rrs := range rrsets {
+		if aws.StringValue(rrs.Type) == rrType {
+			return rrs
+		}
+	}
+	return nil
+}
+
 func (c *AWSClient) GetChangeStatus(id string) (*ChangeStatus, error) {
+	return c.GetChangeStatusWithContext(context.Background(), id)
+}
+
+func (c *AWSClient) GetChangeStatusWithContext(ctx context.Context, id string) (*ChangeStatus, error) {
 	params := &route53.GetChangeInput{
 		Id: aws.String(id),
 	}
 
-	output, err := c.r53.GetChange(params)
+	output, err := c.r53.GetChangeWithContext(ctx, params)
 	if err != nil {
 		if awserr, ok := err.(awserr.Error); ok && awserr.Code() == "NoSuchChange" {
 			return nil, ErrChangeNotFound
@@ -202,6 +626,42 @@ func (c *AWSClient) GetChangeStatus(id string) (*ChangeStatus, error) {
 	return changeInfoToChangeStatus(output.ChangeInfo), nil
 }
 
+// WaitUntilInSync polls the status of a change every interval until it is
+// INSYNC or ctx is done.
+func (c *AWSClient) WaitUntilInSync(ctx context.Context, id string, interval time.Duration) (*ChangeStatus, error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+
+		status, err := c.GetChangeStatusWithContext(ctx, id)
+		if err != nil {
+			// the SDK reports a deadline or cancelation during the request
+			// as RequestCanceled
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, err
+		}
+		if status.Status == ChangeStatusInSync {
+			if !status.SubmittedAt.IsZero() {
+				c.metrics.ChangeInSync(time.Since(status.SubmittedAt))
+			}
+			return status, nil
+		}
+	}
+}
+
+// RecordName returns the fully qualified name of alias in the zone.
+func (z *Zone) RecordName(alias string) string {
+	return aliasDnsName(alias, z)
+}
+
 func aliasDnsName(alias string, zone *Zone) string {
 	aliasDnsName := alias
 
@@ -216,6 +676,19 @@ func aliasDnsName(alias string, zone *Zone) string {
 	return aliasDnsName
 }
 
+// ResourceRecordSet returns the alias record set of r.
+func (r *Record) ResourceRecordSet() *route53.ResourceRecordSet {
+	return &route53.ResourceRecordSet{
+		Name: aws.String(r.Name),
+		Type: aws.String("A"),
+		AliasTarget: &route53.AliasTarget{
+			DNSName:              aws.String(r.DNSName),
+			HostedZoneId:         aws.String(r.HostedZoneID),
+			EvaluateTargetHealth: aws.Bool(r.EvaluateTargetHealth),
+		},
+	}
+}
+
 func changeInfoToChangeStatus(ci *route53.ChangeInfo) *ChangeStatus {
 	return &ChangeStatus{
 		ID:          aws.StringValue(ci.Id),
@@ -1,6 +1,8 @@
 package awsclient
 
 import (
+	"context"
+	"fmt"
 	"testing"
 	"time"
 
@@ -8,7 +10,9 @@ import (
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/ryane/takethe53/awsclient/fake"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -21,7 +25,7 @@ type mockRoute53 struct {
 	mock.Mock
 }
 
-func (m *mockRoute53) ListHostedZonesPages(params *route53.ListHostedZonesInput, fn func(*route53.ListHostedZonesOutput, bool) bool) error {
+func (m *mockRoute53) ListHostedZonesPagesWithContext(ctx aws.Context, params *route53.ListHostedZonesInput, fn func(*route53.ListHostedZonesOutput, bool) bool, opts ...request.Option) error {
 	args := m.Called(params, fn)
 
 	// simulate multiple pages
@@ -45,13 +49,13 @@ func (m *mockRoute53) ListHostedZonesPages(params *route53.ListHostedZonesInput,
 	return args.Error(0)
 }
 
-func (m *mockRoute53) ChangeResourceRecordSets(input *route53.ChangeResourceRecordSetsInput) (*route53.ChangeResourceRecordSetsOutput, error) {
+func (m *mockRoute53) ChangeResourceRecordSetsWithContext(ctx aws.Context, input *route53.ChangeResourceRecordSetsInput, opts ...request.Option) (*route53.ChangeResourceRecordSetsOutput, error) {
 	args := m.Called(input)
 
 	return args.Get(0).(*route53.ChangeResourceRecordSetsOutput), args.Error(1)
 }
 
-func (m *mockRoute53) ListResourceRecordSetsPages(params *route53.ListResourceRecordSetsInput, fn func(*route53.ListResourceRecordSetsOutput, bool) bool) error {
+func (m *mockRoute53) ListResourceRecordSetsPagesWithContext(ctx aws.Context, params *route53.ListResourceRecordSetsInput, fn func(*route53.ListResourceRecordSetsOutput, bool) bool, opts ...request.Option) error {
 	args := m.Called(params, fn)
 
 	// simulate multiple pages
@@ -96,7 +100,7 @@ func (m *mockRoute53) ListResourceRecordSetsPages(params *route53.ListResourceRe
 	return args.Error(0)
 }
 
-func (m *mockRoute53) GetChange(input *route53.GetChangeInput) (*route53.GetChangeOutput, error) {
+func (m *mockRoute53) GetChangeWithContext(ctx aws.Context, input *route53.GetChangeInput, opts ...request.Option) (*route53.GetChangeOutput, error) {
 	args := m.Called(input)
 	return args.Get(0).(*route53.GetChangeOutput), args.Error(1)
 }
@@ -199,6 +203,7 @@ func TestSetAlias(t *testing.T) {
 	c := New()
 	r53 := &mockRoute53{}
 	c.r53 = r53
+	mockListResourceRecordSets(r53, nil)
 
 	output := &route53.ChangeResourceRecordSetsOutput{
 		ChangeInfo: &route53.ChangeInfo{
@@ -209,7 +214,7 @@ func TestSetAlias(t *testing.T) {
 	}
 
 	r53.Mock.On(
-		"ChangeResourceRecordSets",
+		"ChangeResourceRecordSetsWithContext",
 		mock.AnythingOfType("*route53.ChangeResourceRecordSetsInput"),
 	).Return(output, nil)
 
@@ -240,7 +245,7 @@ func TestRemoveAlias(t *testing.T) {
 	}
 
 	r53.Mock.On(
-		"ChangeResourceRecordSets",
+		"ChangeResourceRecordSetsWithContext",
 		mock.AnythingOfType("*route53.ChangeResourceRecordSetsInput"),
 	).Return(output, nil)
 
@@ -270,7 +275,7 @@ func TestGetChangeStatus(t *testing.T) {
 		},
 	}
 
-	r53.Mock.On("GetChange", mock.AnythingOfType("*route53.GetChangeInput")).Return(out, nil)
+	r53.Mock.On("GetChangeWithContext", mock.AnythingOfType("*route53.GetChangeInput")).Return(out, nil)
 
 	status, err := c.GetChangeStatus(id)
 	assert.Nil(t, err, "error should be nil")
@@ -285,7 +290,7 @@ func TestGetChangeStatusNoExist(t *testing.T) {
 
 	id := "11111"
 	r53.Mock.On(
-		"GetChange", mock.AnythingOfType("*route53.GetChangeInput"),
+		"GetChangeWithContext", mock.AnythingOfType("*route53.GetChangeInput"),
 	).Return(
 		&route53.GetChangeOutput{},
 		awserr.New("NoSuchChange", "Could not find resource with ID: 11111", nil),
@@ -296,9 +301,160 @@ func TestGetChangeStatusNoExist(t *testing.T) {
 	assert.Nil(t, status, "status should be nil")
 }
 
+func TestWaitUntilInSync(t *testing.T) {
+	r53 := fake.NewRoute53()
+	r53.SyncDelay = 20 * time.Millisecond
+	zoneID, _ := r53.AddZone("example.com")
+	c := NewWithServices(r53, fake.NewELB())
+
+	zone := &Zone{ID: zoneID, Name: "example.com."}
+	change, err := c.SetAlias(zone, "Z3DZX7HGU9N41H", "aerfflakjdfljadlkfjal-77828384.us-east-1.elb.amazonaws.com", "test")
+	assert.Nil(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	status, err := c.WaitUntilInSync(ctx, change.ID, 5*time.Millisecond)
+	assert.Nil(t, err)
+	assert.Equal(t, ChangeStatusInSync, status.Status)
+}
+
+func TestWaitUntilInSyncTimeout(t *testing.T) {
+	r53 := fake.NewRoute53()
+	r53.SyncDelay = time.Hour
+	zoneID, _ := r53.AddZone("example.com")
+	c := NewWithServices(r53, fake.NewELB())
+
+	zone := &Zone{ID: zoneID, Name: "example.com."}
+	change, err := c.SetAlias(zone, "Z3DZX7HGU9N41H", "aerfflakjdfljadlkfjal-77828384.us-east-1.elb.amazonaws.com", "test")
+	assert.Nil(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = c.WaitUntilInSync(ctx, change.ID, 5*time.Millisecond)
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
+
+// cancelingRoute53 cancels the context during GetChange, like a deadline or
+// Ctrl-C that lands while the request is in flight.
+type cancelingRoute53 struct {
+	*fake.Route53
+	cancel context.CancelFunc
+}
+
+func (r *cancelingRoute53) GetChangeWithContext(ctx aws.Context, input *route53.GetChangeInput, opts ...request.Option) (*route53.GetChangeOutput, error) {
+	r.cancel()
+	return nil, awserr.New(request.CanceledErrorCode, "request context canceled", context.Canceled)
+}
+
+func TestWaitUntilInSyncCanceledDuringRequest(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c := NewWithServices(&cancelingRoute53{Route53: fake.NewRoute53(), cancel: cancel}, fake.NewELB())
+
+	_, err := c.WaitUntilInSync(ctx, "C1", time.Millisecond)
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestRecordSetsNamed(t *testing.T) {
+	r53 := fake.NewRoute53()
+	zoneID, _ := r53.AddZone("example.com")
+	zone := &Zone{ID: zoneID, Name: "example.com."}
+	c := NewWithServices(r53, fake.NewELB())
+
+	for _, name := range []string{"api.example.com.", "www.example.com.", "wwww.example.com."} {
+		r53.AddRecordSet(zone.ID, &route53.ResourceRecordSet{
+			Name:            aws.String(name),
+			Type:            aws.String(route53.RRTypeCname),
+			TTL:             aws.Int64(60),
+			ResourceRecords: []*route53.ResourceRecord{{Value: aws.String("example.org")}},
+		})
+	}
+
+	rrsets, err := c.RecordSetsNamed(zone, "www")
+	assert.Nil(t, err)
+	if assert.Len(t, rrsets, 1) {
+		assert.Equal(t, "www.example.com.", aws.StringValue(rrsets[0].Name))
+		assert.Equal(t, route53.RRTypeCname, aws.StringValue(rrsets[0].Type))
+	}
+
+	rrsets, err = c.RecordSetsNamed(zone, "missing.example.com.")
+	assert.Nil(t, err)
+	assert.Empty(t, rrsets)
+}
+
+func TestUnescapeName(t *testing.T) {
+	assert.Equal(t, "*.example.com.", UnescapeName(`\052.example.com.`))
+	assert.Equal(t, "www.example.com.", UnescapeName("www.example.com."))
+	assert.Equal(t, `a\9.example.com.`, UnescapeName(`a\9.example.com.`))
+	assert.True(t, sameDNSName(`\052.Example.com.`, "*.example.com"))
+}
+
+func TestSetAliasIfNotExists(t *testing.T) {
+	ctx := context.Background()
+	r53 := fake.NewRoute53()
+	zoneID, _ := r53.AddZone("example.com")
+	zone := &Zone{ID: zoneID, Name: "example.com."}
+	c := NewWithServices(r53, fake.NewELB())
+
+	opts := AliasOptions{IfNotExists: true}
+	_, err := c.SetAliasWithOptions(ctx, zone, testELBZoneID, testELBDNSName, "test", opts)
+	assert.Nil(t, err)
+
+	_, err = c.SetAliasWithOptions(ctx, zone, testELBZoneID, "other.us-east-1.elb.amazonaws.com", "test", opts)
+	assert.Equal(t, ErrConflict, err)
+
+	rec, _ := c.FindRecord(zone, "test")
+	assert.Equal(t, testELBDNSName+".", rec.DNSName)
+}
+
+func TestSetAliasExpectTarget(t *testing.T) {
+	ctx := context.Background()
+	r53 := fake.NewRoute53()
+	zoneID, _ := r53.AddZone("example.com")
+	zone := &Zone{ID: zoneID, Name: "example.com."}
+	c := NewWithServices(r53, fake.NewELB())
+
+	_, err := c.SetAliasWithOptions(ctx, zone, testELBZoneID, "new.us-east-1.elb.amazonaws.com", "test", AliasOptions{ExpectTarget: testELBDNSName})
+	assert.Equal(t, ErrConflict, err, "record doesn't exist")
+
+	_, err = c.SetAlias(zone, testELBZoneID, testELBDNSName, "test")
+	assert.Nil(t, err)
+
+	_, err = c.SetAliasWithOptions(ctx, zone, testELBZoneID, "new.us-east-1.elb.amazonaws.com", "test", AliasOptions{ExpectTarget: "wrong.us-east-1.elb.amazonaws.com"})
+	assert.Equal(t, ErrConflict, err)
+
+	_, err = c.SetAliasWithOptions(ctx, zone, testELBZoneID, "new.us-east-1.elb.amazonaws.com", "test", AliasOptions{ExpectTarget: testELBDNSName})
+	assert.Nil(t, err)
+
+	rec, _ := c.FindRecord(zone, "test")
+	assert.Equal(t, "new.us-east-1.elb.amazonaws.com.", rec.DNSName)
+}
+
+func TestSetAliasConflictFromRoute53(t *testing.T) {
+	c := New()
+	r53 := &mockRoute53{}
+	c.r53 = r53
+	mockListResourceRecordSets(r53, nil)
+
+	r53.Mock.On(
+		"ChangeResourceRecordSetsWithContext",
+		mock.AnythingOfType("*route53.ChangeResourceRecordSetsInput"),
+	).Return(
+		&route53.ChangeResourceRecordSetsOutput{},
+		awserr.New("InvalidChangeBatch", "Tried to create resource record set [name='test.example2.com.', type='A'] but it already exists", nil),
+	)
+
+	zone := &Zone{ID: "/hostedzone/ZID12342", Name: "example2.com."}
+	_, err := c.SetAliasWithOptions(context.Background(), zone, testELBZoneID, testELBDNSName, "test", AliasOptions{IfNotExists: true})
+	assert.Equal(t, ErrConflict, err)
+
+	_, err = c.SetAlias(zone, testELBZoneID, testELBDNSName, "test")
+	assert.NotEqual(t, ErrConflict, err, "only conditional writes are conflicts")
+}
+
 func mockListHostedZones(m *mockRoute53, returnParams ...interface{}) {
 	m.Mock.On(
-		"ListHostedZonesPages",
+		"ListHostedZonesPagesWithContext",
 		mock.AnythingOfType("*route53.ListHostedZonesInput"),
 		mock.AnythingOfType("func(*route53.ListHostedZonesOutput, bool) bool"),
 	).Return(returnParams...)
@@ -306,8 +462,123 @@ func mockListHostedZones(m *mockRoute53, returnParams ...interface{}) {
 
 func mockListResourceRecordSets(m *mockRoute53, returnParams ...interface{}) {
 	m.Mock.On(
-		"ListResourceRecordSetsPages",
+		"ListResourceRecordSetsPagesWithContext",
 		mock.AnythingOfType("*route53.ListResourceRecordSetsInput"),
 		mock.AnythingOfType("func(*route53.ListResourceRecordSetsOutput, bool) bool"),
 	).Return(returnParams...)
 }
+
+func TestMoveAlias(t *testing.T) {
+	ctx := context.Background()
+	r53 := fake.NewRoute53()
+	zoneID, _ := r53.AddZone("example.com")
+	zone := &Zone{ID: zoneID, Name: "example.com."}
+	c := newOwnedTestClient(r53, "team-a")
+
+	_, err := c.MoveAlias(zone, "old", "new")
+	assert.Equal(t, ErrRecordNotFound, err)
+
+	_, err = c.SetAlias(zone, testELBZoneID, testELBDNSName, "old")
+	assert.Nil(t, err)
+	_, err = c.SetAlias(zone, testELBZoneID, testELBDNSName, "taken")
+	assert.Nil(t, err)
+
+	_, err = c.MoveAlias(zone, "old", "old.example.com.")
+	assert.Equal(t, ErrSameRecord, err)
+
+	_, err = c.MoveAlias(zone, "old", "taken")
+	assert.Equal(t, ErrRecordExists, err)
+
+	_, err = c.MoveAlias(zone, "old", "new")
+	assert.Nil(t, err)
+
+	_, err = c.FindRecord(zone, "old")
+	assert.Equal(t, ErrRecordNotFound, err)
+	rec, err := c.FindRecord(zone, "new")
+	assert.Nil(t, err)
+	assert.Equal(t, testELBDNSName+".", rec.DNSName)
+
+	existing, _ := c.recordSetsNamed(ctx, zone, "old.example.com.")
+	assert.Equal(t, 0, len(existing), "ownership record is released")
+	existing, _ = c.recordSetsNamed(ctx, zone, "new.example.com.")
+	assert.Equal(t, "team-a", Owner(existing))
+
+	_, err = newOwnedTestClient(r53, "team-b").MoveAlias(zone, "new", "other")
+	assert.Equal(t, ErrNotOwner, err)
+}
+
+func aliasRecordSet(name, rrType, setIdentifier string, weight int64) *route53.ResourceRecordSet {
+	rrs := &route53.ResourceRecordSet{
+		Name: aws.String(name),
+		Type: aws.String(rrType),
+		AliasTarget: &route53.AliasTarget{
+			HostedZoneId:         aws.String(testELBZoneID),
+			DNSName:              aws.String(testELBDNSName + "."),
+			EvaluateTargetHealth: aws.Bool(true),
+		},
+	}
+	if setIdentifier != "" {
+		rrs.SetIdentifier = aws.String(setIdentifier)
+		rrs.Weight = aws.Int64(weight)
+	}
+	return rrs
+}
+
+func TestMoveAliasRecordSets(t *testing.T) {
+	ctx := context.Background()
+	r53 := fake.NewRoute53()
+	zoneID, _ := r53.AddZone("example.com")
+	zone := &Zone{ID: zoneID, Name: "example.com."}
+	c := NewWithServices(r53, fake.NewELB())
+
+	r53.AddRecordSet(zoneID, aliasRecordSet("old.example.com.", route53.RRTypeA, "blue", 10))
+	r53.AddRecordSet(zoneID, aliasRecordSet("old.example.com.", route53.RRTypeA, "green", 90))
+	r53.AddRecordSet(zoneID, aliasRecordSet("old.example.com.", route53.RRTypeAaaa, "", 0))
+
+	_, err := c.MoveAlias(zone, "old", "new")
+	assert.Nil(t, err)
+
+	old, _ := c.recordSetsNamed(ctx, zone, "old.example.com.")
+	assert.Equal(t, 0, len(old))
+
+	moved, _ := c.recordSetsNamed(ctx, zone, "new.example.com.")
+	var keys []string
+	for _, rrs := range moved {
+		keys = append(keys, fmt.Sprintf("%s %s %d", aws.StringValue(rrs.Type), aws.StringValue(rrs.SetIdentifier), aws.Int64Value(rrs.Weight)))
+	}
+	assert.Equal(t, []string{"A blue 10", "A green 90", "AAAA  0"}, keys)
+}
+
+// racingRoute53 runs race before submitting a change batch, like someone
+// changing the zone between our read and the write.
+type racingRoute53 struct {
+	*fake.Route53
+	race func()
+}
+
+func (r *racingRoute53) ChangeResourceRecordSetsWithContext(ctx aws.Context, input *route53.ChangeResourceRecordSetsInput, opts ...request.Option) (*route53.ChangeResourceRecordSetsOutput, error) {
+	if r.race != nil {
+		r.race()
+		r.race = nil
+	}
+	return r.Route53.ChangeResourceRecordSetsWithContext(ctx, input, opts...)
+}
+
+func TestMoveAliasInvalidChangeBatch(t *testing.T) {
+	r53 := &racingRoute53{Route53: fake.NewRoute53()}
+	zoneID, _ := r53.AddZone("example.com")
+	zone := &Zone{ID: zoneID, Name: "example.com."}
+	c := NewWithServices(r53, fake.NewELB())
+	r53.AddRecordSet(zoneID, aliasRecordSet("old.example.com.", route53.RRTypeA, "", 0))
+
+	invalid := awserr.New(route53.ErrCodeInvalidChangeBatch, "Invalid request", nil)
+	r53.InjectError("ChangeResourceRecordSets", invalid)
+	_, err := c.MoveAlias(zone, "old", "new")
+	assert.Equal(t, invalid, err, "nothing changed, so it isn't a conflict")
+
+	r53.race = func() {
+		r53.AddRecordSet(zoneID, aliasRecordSet("new.example.com.", route53.RRTypeA, "", 0))
+	}
+	_, err = c.MoveAlias(zone, "old", "new")
+	assert.Equal(t, ErrConflict, err)
+}
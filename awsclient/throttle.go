@@ -0,0 +1,261 @@
+package awsclient
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/route53"
+)
+
+const (
+	DefaultRoute53RateLimit = 5
+	DefaultMaxRetries       = 5
+	DefaultRetryBaseDelay   = 200 * time.Millisecond
+	DefaultRetryMaxDelay    = 10 * time.Second
+)
+
+// RateLimiter is a token bucket. It is safe for concurrent use so a single
+// limiter can be shared by everything that talks to the same account.
+type RateLimiter struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter allows rate requests per second with bursts of up to burst
+// requests.
+func NewRateLimiter(rate float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// Wait blocks until a token is available or ctx is done.
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	if l == nil || l.rate <= 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+
+	l.tokens--
+	var wait time.Duration
+	if l.tokens < 0 {
+		wait = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	l.mu.Unlock()
+
+	if err := sleep(ctx, wait); err != nil {
+		// give the token back, the request is never made
+		l.mu.Lock()
+		l.tokens++
+		l.mu.Unlock()
+		return err
+	}
+
+	return nil
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// RetryPolicy retries throttled requests with exponential backoff and full
+// jitter.
+type RetryPolicy struct {
+	MaxRetries int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+
+	metrics Metrics
+}
+
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	d := p.BaseDelay << uint(attempt)
+	if d <= 0 || (p.MaxDelay > 0 && d > p.MaxDelay) {
+		d = p.MaxDelay
+	}
+	if d <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(d)))
+}
+
+// do runs fn until it succeeds, fails with an error that is not a throttling
+// error, runs out of retries or ctx is done.
+func (p RetryPolicy) do(ctx context.Context, op string, fn func() error) error {
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if nr, ok := err.(noRetry); ok {
+			return nr.err
+		}
+		if err == nil || attempt >= p.MaxRetries || !isThrottleError(err) {
+			return err
+		}
+
+		code := err.(awserr.Error).Code()
+		if p.metrics != nil {
+			p.metrics.Retry(op, code)
+		}
+
+		delay := p.delay(attempt)
+		logrus.WithFields(logrus.Fields{
+			"type":    "aws",
+			"op":      op,
+			"code":    code,
+			"attempt": attempt + 1,
+			"delay":   delay,
+		}).Warn("retry.aws: ", op)
+		if err := sleep(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
+
+func isThrottleError(err error) bool {
+	awserr, ok := err.(awserr.Error)
+	if !ok {
+		return false
+	}
+
+	switch awserr.Code() {
+	case "Throttling", "ThrottlingException", "RequestThrottled", "RequestLimitExceeded", route53.ErrCodePriorRequestNotComplete:
+		return true
+	}
+
+	return false
+}
+
+// throttledRoute53 rate limits and retries every call to the wrapped
+// Route53er. Paged calls request one page at a time, so each page waits on
+// the limiter and is retried on its own.
+type throttledRoute53 struct {
+	r53     Route53er
+	limiter *RateLimiter
+	retry   RetryPolicy
+}
+
+func newThrottledRoute53(r53 Route53er, limiter *RateLimiter, retry RetryPolicy) *throttledRoute53 {
+	return &throttledRoute53{r53: r53, limiter: limiter, retry: retry}
+}
+
+func (t *throttledRoute53) ListHostedZonesPagesWithContext(ctx aws.Context, input *route53.ListHostedZonesInput, fn func(*route53.ListHostedZonesOutput, bool) bool, opts ...request.Option) error {
+	params := *input
+	for {
+		var page *route53.ListHostedZonesOutput
+		err := t.retry.do(ctx, "ListHostedZones", func() error {
+			if err := t.limiter.Wait(ctx); err != nil {
+				return noRetry{err}
+			}
+			return t.r53.ListHostedZonesPagesWithContext(ctx, &params, func(o *route53.ListHostedZonesOutput, lastPage bool) bool {
+				page = o
+				return false
+			}, opts...)
+		})
+		if err != nil || page == nil {
+			return err
+		}
+
+		lastPage := !aws.BoolValue(page.IsTruncated)
+		if !fn(page, lastPage) || lastPage {
+			return nil
+		}
+		params.Marker = page.NextMarker
+	}
+}
+
+func (t *throttledRoute53) ListResourceRecordSetsPagesWithContext(ctx aws.Context, input *route53.ListResourceRecordSetsInput, fn func(*route53.ListResourceRecordSetsOutput, bool) bool, opts ...request.Option) error {
+	params := *input
+	for {
+		var page *route53.ListResourceRecordSetsOutput
+		err := t.retry.do(ctx, "ListResourceRecordSets", func() error {
+			if err := t.limiter.Wait(ctx); err != nil {
+				return noRetry{err}
+			}
+			return t.r53.ListResourceRecordSetsPagesWithContext(ctx, &params, func(o *route53.ListResourceRecordSetsOutput, lastPage bool) bool {
+				page = o
+				return false
+			}, opts...)
+		})
+		if err != nil || page == nil {
+			return err
+		}
+
+		lastPage := !aws.BoolValue(page.IsTruncated)
+		if !fn(page, lastPage) || lastPage {
+			return nil
+		}
+		params.StartRecordName = page.NextRecordName
+		params.StartRecordType = page.NextRecordType
+		params.StartRecordIdentifier = page.NextRecordIdentifier
+	}
+}
+
+func (t *throttledRoute53) ChangeResourceRecordSetsWithContext(ctx aws.Context, input *route53.ChangeResourceRecordSetsInput, opts ...request.Option) (*route53.ChangeResourceRecordSetsOutput, error) {
+	var out *route53.ChangeResourceRecordSetsOutput
+	err := t.retry.do(ctx, "ChangeResourceRecordSets", func() error {
+		if err := t.limiter.Wait(ctx); err != nil {
+			return noRetry{err}
+		}
+
+		var err error
+		out, err = t.r53.ChangeResourceRecordSetsWithContext(ctx, input, opts...)
+		return err
+	})
+	return out, err
+}
+
+func (t *throttledRoute53) GetChangeWithContext(ctx aws.Context, input *route53.GetChangeInput, opts ...request.Option) (*route53.GetChangeOutput, error) {
+	var out *route53.GetChangeOutput
+	err := t.retry.do(ctx, "GetChange", func() error {
+		if err := t.limiter.Wait(ctx); err != nil {
+			return noRetry{err}
+		}
+
+		var err error
+		out, err = t.r53.GetChangeWithContext(ctx, input, opts...)
+		return err
+	})
+	return out, err
+}
+
+// noRetry wraps an error that must be returned to the caller as is.
+type noRetry struct {
+	err error
+}
+
+func (e noRetry) Error() string {
+	return e.err.Error()
+}
@@ -0,0 +1,112 @@
+package awsclient
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/ryane/takethe53/awsclient/fake"
+	"github.com/stretchr/testify/assert"
+)
+
+func newThrottledTestClient(r53 *fake.Route53, maxRetries int) *AWSClient {
+	retry := RetryPolicy{MaxRetries: maxRetries, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+	return NewWithServices(newThrottledRoute53(r53, nil, retry), fake.NewELB())
+}
+
+func TestRetryThrottledRequests(t *testing.T) {
+	r53 := fake.NewRoute53()
+	r53.AddZone("example.com")
+	r53.InjectError("ListHostedZones", awserr.New("Throttling", "Rate exceeded", nil))
+	r53.InjectError("ListHostedZones", awserr.New("PriorRequestNotComplete", "The request was rejected because Route 53 was still processing a prior request.", nil))
+
+	c := newThrottledTestClient(r53, 3)
+	zone, err := c.FindZone("example.com")
+	assert.Nil(t, err)
+	assert.Equal(t, "example.com.", zone.Name)
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	r53 := fake.NewRoute53()
+	for i := 0; i < 3; i++ {
+		r53.InjectError("GetChange", awserr.New("Throttling", "Rate exceeded", nil))
+	}
+
+	c := newThrottledTestClient(r53, 2)
+	_, err := c.GetChangeStatus("C1")
+	assert.Equal(t, "Throttling", err.(awserr.Error).Code())
+}
+
+func TestNoRetryForOtherErrors(t *testing.T) {
+	r53 := fake.NewRoute53()
+	r53.AddZone("example.com")
+	r53.InjectError("ListHostedZones", awserr.New("AccessDenied", "denied", nil))
+
+	c := newThrottledTestClient(r53, 3)
+	_, err := c.FindZone("example.com")
+	assert.Equal(t, "AccessDenied", err.(awserr.Error).Code())
+}
+
+// laterPageThrottlingRoute53 throttles the first request for a page after the
+// first one.
+type laterPageThrottlingRoute53 struct {
+	*fake.Route53
+	throttled bool
+}
+
+func (r *laterPageThrottlingRoute53) ListResourceRecordSetsPagesWithContext(ctx aws.Context, input *route53.ListResourceRecordSetsInput, fn func(*route53.ListResourceRecordSetsOutput, bool) bool, opts ...request.Option) error {
+	if input.StartRecordName != nil && !r.throttled {
+		r.throttled = true
+		return awserr.New("Throttling", "Rate exceeded", nil)
+	}
+	return r.Route53.ListResourceRecordSetsPagesWithContext(ctx, input, fn, opts...)
+}
+
+func TestRetryLaterPages(t *testing.T) {
+	r53 := &laterPageThrottlingRoute53{Route53: fake.NewRoute53()}
+	zoneID, _ := r53.AddZone("example.com")
+	zone := &Zone{ID: zoneID, Name: "example.com."}
+	for i := 0; i < 250; i++ {
+		r53.AddRecordSet(zone.ID, &route53.ResourceRecordSet{
+			Name:            aws.String(fmt.Sprintf("host-%03d.example.com.", i)),
+			Type:            aws.String(route53.RRTypeA),
+			TTL:             aws.Int64(60),
+			ResourceRecords: []*route53.ResourceRecord{{Value: aws.String("10.0.0.1")}},
+		})
+	}
+
+	retry := RetryPolicy{MaxRetries: 1, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+	c := NewWithServices(newThrottledRoute53(r53, nil, retry), fake.NewELB())
+	rrsets, err := c.RecordSets(zone)
+	assert.Nil(t, err)
+	assert.True(t, r53.throttled)
+
+	names := map[string]bool{}
+	for _, rrs := range rrsets {
+		names[aws.StringValue(rrs.Name)+aws.StringValue(rrs.Type)] = true
+	}
+	assert.Equal(t, len(rrsets), len(names), "no page is delivered twice")
+	assert.Equal(t, 252, len(rrsets), "250 records plus SOA and NS")
+}
+
+func TestSDKRetriesDisabled(t *testing.T) {
+	c := NewWithConfig(Config{Route53Endpoint: "http://127.0.0.1:1"})
+	r53 := c.r53.(*throttledRoute53).r53.(*route53.Route53)
+	assert.Equal(t, 0, aws.IntValue(r53.Config.MaxRetries))
+}
+
+func TestRateLimiter(t *testing.T) {
+	l := NewRateLimiter(100, 1)
+
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		l.Wait(context.Background())
+	}
+
+	assert.True(t, time.Since(start) >= 25*time.Millisecond, "3 requests over the burst need at least 30ms at 100 req/s")
+}
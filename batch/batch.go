@@ -0,0 +1,299 @@
+// Package batch applies many alias changes at once, in as few change batches
+// as Route53 allows.
+package batch
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/ryane/takethe53/awsclient"
+	"github.com/ryane/takethe53/recordset"
+)
+
+// Op is the operation passed to change handlers.
+const Op = "batch"
+
+// Result is the outcome of a line.
+type Result struct {
+	Line *Line
+	// Name is the fully qualified record name.
+	Name     string
+	Changes  []*route53.Change
+	ChangeID string
+	Status   string
+	Err      error
+}
+
+// Plan holds the change batches for a set of lines.
+type Plan struct {
+	// Results are in the order of the lines.
+	Results []*Result
+	Zones   []*ZonePlan
+}
+
+// ZonePlan holds the batches of a zone.
+type ZonePlan struct {
+	Zone    *awsclient.Zone
+	Batches []*Batch
+}
+
+// Batch is a single ChangeResourceRecordSets call.
+type Batch struct {
+	Results []*Result
+	Changes []*route53.Change
+}
+
+// Options change how a plan is prepared and applied.
+type Options struct {
+	// Force modifies records owned by someone else.
+	Force bool
+	// Concurrency is how many zones are prepared and applied at the same
+	// time, 1 if zero.
+	Concurrency int
+	// BatchSize is the most a batch may weigh, recordset.MaxBatchSize if
+	// zero.
+	BatchSize int
+}
+
+func (o Options) concurrency() int {
+	if o.Concurrency <= 0 {
+		return 1
+	}
+	return o.Concurrency
+}
+
+// Prepare looks up the zones, load balancers and records of the lines and
+// groups the changes into batches per zone. Lines that fail have their Err
+// set and are left out of the batches.
+func Prepare(ctx context.Context, client *awsclient.AWSClient, lines []*Line, opts Options) (*Plan, error) {
+	plan := &Plan{}
+
+	lbs, err := loadBalancers(ctx, client, lines)
+	if err != nil {
+		return nil, err
+	}
+
+	byZone := map[string][]*Result{}
+	var zoneNames []string
+	for _, line := range lines {
+		r := &Result{Line: line}
+		plan.Results = append(plan.Results, r)
+
+		key := strings.ToLower(strings.TrimSuffix(line.Zone, "."))
+		if _, ok := byZone[key]; !ok {
+			zoneNames = append(zoneNames, key)
+		}
+		byZone[key] = append(byZone[key], r)
+	}
+	sort.Strings(zoneNames)
+
+	plan.Zones = make([]*ZonePlan, len(zoneNames))
+	forEach(len(zoneNames), opts.concurrency(), func(i int) {
+		plan.Zones[i] = prepareZone(ctx, client, zoneNames[i], byZone[zoneNames[i]], lbs, opts)
+	})
+
+	// zones that could not be found have no plan
+	var zones []*ZonePlan
+	for _, z := range plan.Zones {
+		if z != nil {
+			zones = append(zones, z)
+		}
+	}
+	plan.Zones = zones
+
+	return plan, nil
+}
+
+func prepareZone(ctx context.Context, client *awsclient.AWSClient, zoneName string, results []*Result, lbs map[string]*awsclient.LoadBalancer, opts Options) *ZonePlan {
+	zone, err := client.FindZoneWithContext(ctx, zoneName)
+	if err != nil {
+		for _, r := range results {
+			r.Err = err
+		}
+		return nil
+	}
+
+	// Route53 rejects batches that change the same record twice
+	seen := map[string]*Line{}
+	var prepared []*Result
+	for _, r := range results {
+		line := r.Line
+		r.Name = zone.RecordName(line.Alias)
+
+		key := strings.ToLower(r.Name)
+		if first, ok := seen[key]; ok {
+			r.Err = fmt.Errorf("Duplicate of line %d.", first.Number)
+			continue
+		}
+		seen[key] = line
+
+		switch line.Action {
+		case ActionRemove:
+			r.Changes, r.Err = client.RemoveAliasChanges(ctx, zone, line.Alias, awsclient.AliasOptions{Force: opts.Force})
+		default:
+			lb, ok := lbs[strings.ToLower(strings.TrimSuffix(line.Target, "."))]
+			if !ok {
+				r.Err = awsclient.ErrELBNotFound
+				continue
+			}
+			aliasOpts := awsclient.AliasOptions{Force: opts.Force, IfNotExists: line.Action == ActionCreate}
+			r.Changes, r.Err = client.SetAliasChanges(ctx, zone, lb.HostedZoneID, lb.Name, line.Alias, aliasOpts)
+		}
+		if r.Err == nil {
+			prepared = append(prepared, r)
+		}
+	}
+
+	zp := &ZonePlan{Zone: zone}
+	size := opts.BatchSize
+	if size <= 0 {
+		size = recordset.MaxBatchSize
+	}
+
+	// keep the changes of a line in the same batch
+	b, weight := &Batch{}, 0
+	for _, r := range prepared {
+		w := 0
+		for _, c := range r.Changes {
+			w += recordset.Weight(c)
+		}
+		if len(b.Results) > 0 && weight+w > size {
+			zp.Batches = append(zp.Batches, b)
+			b, weight = &Batch{}, 0
+		}
+		b.Results = append(b.Results, r)
+		b.Changes = append(b.Changes, r.Changes...)
+		weight += w
+	}
+	if len(b.Results) > 0 {
+		zp.Batches = append(zp.Batches, b)
+	}
+
+	return zp
+}
+
+// loadBalancers returns the load balancers by DNS name, only listing them if
+// a line needs one.
+func loadBalancers(ctx context.Context, client *awsclient.AWSClient, lines []*Line) (map[string]*awsclient.LoadBalancer, error) {
+	lbs := map[string]*awsclient.LoadBalancer{}
+	for _, line := range lines {
+		if line.Action == ActionRemove {
+			continue
+		}
+
+		all, err := client.LoadBalancersWithContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, lb := range all {
+			lbs[strings.ToLower(strings.TrimSuffix(lb.Name, "."))] = lb
+		}
+		break
+	}
+	return lbs, nil
+}
+
+// Apply submits the batches, zones concurrently and the batches of a zone
+// in order.
+func (p *Plan) Apply(ctx context.Context, client *awsclient.AWSClient, opts Options) {
+	forEach(len(p.Zones), opts.concurrency(), func(i int) {
+		zp := p.Zones[i]
+		for _, b := range zp.Batches {
+			status, err := client.ChangeRecordSets(ctx, zp.Zone, Op, b.Changes)
+			for _, r := range b.Results {
+				if err != nil {
+					r.Err = err
+					continue
+				}
+				r.ChangeID = status.ID
+				r.Status = status.Status
+			}
+		}
+	})
+}
+
+// Wait waits until every submitted change is INSYNC or ctx is done. Changes
+// that are still pending keep their PENDING status.
+func (p *Plan) Wait(ctx context.Context, client *awsclient.AWSClient, interval time.Duration) {
+	pending := map[string][]*Result{}
+	var ids []string
+	for _, r := range p.Results {
+		if r.Err != nil || r.ChangeID == "" || r.Status == awsclient.ChangeStatusInSync {
+			continue
+		}
+		if _, ok := pending[r.ChangeID]; !ok {
+			ids = append(ids, r.ChangeID)
+		}
+		pending[r.ChangeID] = append(pending[r.ChangeID], r)
+	}
+
+	forEach(len(ids), len(ids), func(i int) {
+		if _, err := client.WaitUntilInSync(ctx, ids[i], interval); err != nil {
+			return
+		}
+		for _, r := range pending[ids[i]] {
+			r.Status = awsclient.ChangeStatusInSync
+		}
+	})
+}
+
+// Pending returns the IDs of the submitted changes that are not INSYNC yet.
+func (p *Plan) Pending() []string {
+	seen := map[string]bool{}
+	var ids []string
+	for _, r := range p.Results {
+		if r.Err != nil || r.ChangeID == "" || r.Status == awsclient.ChangeStatusInSync || seen[r.ChangeID] {
+			continue
+		}
+		seen[r.ChangeID] = true
+		ids = append(ids, r.ChangeID)
+	}
+	return ids
+}
+
+// Changes returns the changes of all batches.
+func (p *Plan) Changes() []*route53.Change {
+	var changes []*route53.Change
+	for _, zp := range p.Zones {
+		for _, b := range zp.Batches {
+			changes = append(changes, b.Changes...)
+		}
+	}
+	return changes
+}
+
+// Failed returns the number of lines that failed.
+func (p *Plan) Failed() int {
+	n := 0
+	for _, r := range p.Results {
+		if r.Err != nil {
+			n++
+		}
+	}
+	return n
+}
+
+// forEach calls f for 0..n-1 with at most concurrency calls at a time.
+func forEach(n, concurrency int, f func(i int)) {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, concurrency)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			f(i)
+		}(i)
+	}
+	wg.Wait()
+}
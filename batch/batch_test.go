@@ -0,0 +1,161 @@
+package batch
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ryane/takethe53/awsclient"
+	"github.com/ryane/takethe53/awsclient/fake"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	lines, err := Parse(strings.NewReader(`action,alias,zone,target
+# migrate www
+upsert,www,example.com,web-1.us-east-1.elb.amazonaws.com
+
+Remove, old, example.com
+`), "")
+	assert.Nil(t, err)
+	assert.Equal(t, []*Line{
+		{Number: 3, Action: ActionUpsert, Alias: "www", Zone: "example.com", Target: "web-1.us-east-1.elb.amazonaws.com"},
+		{Number: 5, Action: ActionRemove, Alias: "old", Zone: "example.com"},
+	}, lines)
+
+	lines, err = Parse(strings.NewReader(`{"action": "create", "alias": "api", "zone": "example.org", "target": "web-2.us-east-1.elb.amazonaws.com"}
+{"action": "remove", "alias": "old", "zone": "example.org"}`), "")
+	assert.Nil(t, err)
+	assert.Len(t, lines, 2)
+	assert.Equal(t, &Line{Number: 1, Action: ActionCreate, Alias: "api", Zone: "example.org", Target: "web-2.us-east-1.elb.amazonaws.com"}, lines[0])
+
+	_, err = Parse(strings.NewReader("create,www,example.com\n"), FormatCSV)
+	assert.EqualError(t, err, "Line 1: create needs a target.")
+
+	_, err = Parse(strings.NewReader("upsert,www,example.com,a\nrename,www,example.com,b\n"), FormatCSV)
+	assert.EqualError(t, err, `Line 2: unknown action "rename", expected create, upsert or remove.`)
+
+	_, err = Parse(strings.NewReader("upsert,www\n"), FormatCSV)
+	assert.NotNil(t, err)
+
+	_, err = Parse(strings.NewReader("upsert,www,example.com,a\n"), "xml")
+	assert.NotNil(t, err)
+}
+
+func newClient() (*awsclient.AWSClient, *fake.Route53) {
+	r53 := fake.NewRoute53()
+	r53.AddZone("example.com")
+	r53.AddZone("example.org")
+
+	elb := fake.NewELB()
+	elb.AddLoadBalancer("web-1", "web-1.us-east-1.elb.amazonaws.com", "Z35SXDOTRQ7X7K")
+	elb.AddLoadBalancer("web-2", "web-2.us-east-1.elb.amazonaws.com", "Z35SXDOTRQ7X7K")
+
+	return awsclient.NewWithServices(r53, elb), r53
+}
+
+func TestBatch(t *testing.T) {
+	client, r53 := newClient()
+	r53.SyncDelay = 20 * time.Millisecond
+
+	com, err := client.FindZone("example.com")
+	assert.Nil(t, err)
+	_, err = client.SetAlias(com, "Z35SXDOTRQ7X7K", "web-1.us-east-1.elb.amazonaws.com", "old")
+	assert.Nil(t, err)
+
+	var mu sync.Mutex
+	batches := map[string]int{}
+	client.AddChangeHandler(func(ctx context.Context, ev *awsclient.ChangeEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		assert.Equal(t, Op, ev.Op)
+		batches[ev.Zone.Name]++
+	})
+
+	lines, err := Parse(strings.NewReader(`upsert,www,example.com,web-1.us-east-1.elb.amazonaws.com
+create,api,example.com,web-2.us-east-1.elb.amazonaws.com
+remove,old,example.com
+upsert,www,example.org,web-2.us-east-1.elb.amazonaws.com
+upsert,WWW.example.com.,example.com,web-2.us-east-1.elb.amazonaws.com
+upsert,www,example.net,web-1.us-east-1.elb.amazonaws.com
+upsert,cdn,example.org,missing.us-east-1.elb.amazonaws.com
+remove,missing,example.org
+`), FormatCSV)
+	assert.Nil(t, err)
+
+	opts := Options{Concurrency: 2}
+	plan, err := Prepare(context.Background(), client, lines, opts)
+	assert.Nil(t, err)
+	assert.Len(t, plan.Zones, 2)
+	assert.Len(t, plan.Changes(), 4)
+	assert.Equal(t, 4, plan.Failed())
+
+	plan.Apply(context.Background(), client, opts)
+	assert.Len(t, plan.Pending(), 2, "one change per zone")
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	plan.Wait(ctx, client, 5*time.Millisecond)
+	assert.Empty(t, plan.Pending())
+
+	// one batch per zone
+	assert.Equal(t, map[string]int{"example.com.": 1, "example.org.": 1}, batches)
+
+	results := plan.Results
+	for _, r := range results[:4] {
+		assert.Nil(t, r.Err, "line %d", r.Line.Number)
+		assert.Equal(t, awsclient.ChangeStatusInSync, r.Status, "line %d", r.Line.Number)
+	}
+	assert.Equal(t, "www.example.com.", results[0].Name)
+	assert.Equal(t, results[0].ChangeID, results[2].ChangeID)
+	assert.NotEqual(t, results[0].ChangeID, results[3].ChangeID)
+
+	assert.EqualError(t, results[4].Err, "Duplicate of line 1.")
+	assert.Equal(t, awsclient.ErrZoneNotFound, results[5].Err)
+	assert.Equal(t, awsclient.ErrELBNotFound, results[6].Err)
+	assert.Equal(t, awsclient.ErrRecordNotFound, results[7].Err)
+
+	rec, err := client.FindRecord(com, "api")
+	assert.Nil(t, err)
+	assert.Equal(t, "web-2.us-east-1.elb.amazonaws.com.", rec.DNSName)
+	_, err = client.FindRecord(com, "old")
+	assert.Equal(t, awsclient.ErrRecordNotFound, err)
+}
+
+func TestBatchSize(t *testing.T) {
+	client, _ := newClient()
+
+	var input []string
+	for _, alias := range []string{"a", "b", "c", "d", "e"} {
+		input = append(input, "upsert,"+alias+",example.com,web-1.us-east-1.elb.amazonaws.com")
+	}
+	lines, err := Parse(strings.NewReader(strings.Join(input, "\n")), FormatCSV)
+	assert.Nil(t, err)
+
+	// an UPSERT weighs 2
+	plan, err := Prepare(context.Background(), client, lines, Options{BatchSize: 4})
+	assert.Nil(t, err)
+	assert.Len(t, plan.Zones[0].Batches, 3)
+	assert.Len(t, plan.Zones[0].Batches[2].Results, 1)
+
+	plan.Apply(context.Background(), client, Options{})
+	assert.Equal(t, 0, plan.Failed())
+}
+
+func TestBatchFailure(t *testing.T) {
+	client, _ := newClient()
+	com, _ := client.FindZone("example.com")
+	_, err := client.SetAlias(com, "Z35SXDOTRQ7X7K", "web-1.us-east-1.elb.amazonaws.com", "www")
+	assert.Nil(t, err)
+
+	// the create fails because the record exists, and takes its batch with it
+	lines, err := Parse(strings.NewReader("create,www,example.com,web-2.us-east-1.elb.amazonaws.com\nupsert,api,example.com,web-2.us-east-1.elb.amazonaws.com\n"), FormatCSV)
+	assert.Nil(t, err)
+	plan, err := Prepare(context.Background(), client, lines, Options{})
+	assert.Nil(t, err)
+	plan.Apply(context.Background(), client, Options{})
+
+	assert.Equal(t, 2, plan.Failed())
+	assert.Equal(t, plan.Results[0].Err, plan.Results[1].Err)
+}
@@ -0,0 +1,130 @@
+package batch
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// Actions of a line.
+const (
+	// ActionCreate creates the alias, failing if the record exists.
+	ActionCreate = "create"
+	// ActionUpsert creates or updates the alias.
+	ActionUpsert = "upsert"
+	// ActionRemove deletes the alias.
+	ActionRemove = "remove"
+)
+
+// Input formats.
+const (
+	FormatCSV   = "csv"
+	FormatJSONL = "jsonl"
+)
+
+// Line is a single change of the input.
+type Line struct {
+	Number int    `json:"-"`
+	Action string `json:"action"`
+	Alias  string `json:"alias"`
+	Zone   string `json:"zone"`
+	// Target is the DNS name of the load balancer, not needed for remove.
+	Target string `json:"target"`
+}
+
+func (l *Line) validate() error {
+	switch l.Action {
+	case ActionCreate, ActionUpsert:
+		if l.Target == "" {
+			return fmt.Errorf("Line %d: %s needs a target.", l.Number, l.Action)
+		}
+	case ActionRemove:
+	default:
+		return fmt.Errorf("Line %d: unknown action %q, expected create, upsert or remove.", l.Number, l.Action)
+	}
+	if l.Alias == "" || l.Zone == "" {
+		return fmt.Errorf("Line %d: alias and zone are required.", l.Number)
+	}
+	return nil
+}
+
+// Parse reads lines in format, or guesses the format from the first line if
+// format is empty. CSV lines are "action,alias,zone,target" with an
+// optional header, JSONL lines are objects with the same fields. Empty lines
+// and lines starting with # are skipped.
+func Parse(r io.Reader, format string) ([]*Line, error) {
+	input, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if format == "" {
+		format = FormatCSV
+		if bytes.HasPrefix(bytes.TrimSpace(input), []byte("{")) {
+			format = FormatJSONL
+		}
+	}
+
+	var lines []*Line
+	scanner := bufio.NewScanner(bytes.NewReader(input))
+	for n := 1; scanner.Scan(); n++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		var line *Line
+		switch format {
+		case FormatCSV:
+			line, err = parseCSV(text)
+		case FormatJSONL:
+			line = &Line{}
+			err = json.Unmarshal([]byte(text), line)
+		default:
+			return nil, fmt.Errorf("Unknown format %q, expected csv or jsonl.", format)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Line %d: %s", n, err)
+		}
+		if line == nil {
+			// header
+			continue
+		}
+
+		line.Number = n
+		line.Action = strings.ToLower(strings.TrimSpace(line.Action))
+		if err := line.validate(); err != nil {
+			return nil, err
+		}
+		lines = append(lines, line)
+	}
+
+	return lines, scanner.Err()
+}
+
+func parseCSV(text string) (*Line, error) {
+	r := csv.NewReader(strings.NewReader(text))
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	fields, err := r.Read()
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) < 3 || len(fields) > 4 {
+		return nil, fmt.Errorf("expected action,alias,zone,target, got %d fields.", len(fields))
+	}
+	if strings.EqualFold(fields[0], "action") {
+		return nil, nil
+	}
+
+	line := &Line{Action: fields[0], Alias: fields[1], Zone: fields[2]}
+	if len(fields) == 4 {
+		line.Target = fields[3]
+	}
+	return line, nil
+}
@@ -0,0 +1,151 @@
+// Copyright © 2016 Ryan Eschinger <ryanesc@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/ryane/takethe53/awsclient"
+	"github.com/ryane/takethe53/dangling"
+	"github.com/spf13/cobra"
+)
+
+type auditParams struct {
+	output  string
+	planDir string
+}
+
+var aParams auditParams
+
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Audit Route53 records",
+	Long:  `Audit Route53 records`,
+}
+
+var auditDanglingCmd = &cobra.Command{
+	Use:   "dangling",
+	Short: "Find records that point at resources that no longer exist",
+	Long: `Find alias and CNAME records in every zone that point at load balancers,
+CloudFront distributions or S3 buckets that no longer exist. Such records can
+be taken over by anyone who creates a resource with the same name.
+
+Load balancers are looked up in the region of their DNS name. S3 buckets are
+only found if they belong to the account. Exits with status 2 if there are
+dangling records.
+
+With --plan-dir, a change batch that deletes the dangling records is written
+for every zone, ready for "aws route53 change-resource-record-sets".`,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := commandContext()
+
+		cfg := awsConfig()
+		cfg.Cache = nil
+		client := newClientWithConfig(cfg)
+
+		inventory := func(ctx context.Context, region string) (*awsclient.Inventory, error) {
+			if region == "" || region == cfg.Region {
+				return client.InventoryWithContext(ctx)
+			}
+			regionCfg := cfg
+			regionCfg.Region = region
+			return awsclient.NewWithConfig(regionCfg).InventoryWithContext(ctx)
+		}
+
+		findings, err := dangling.Audit(ctx, client, inventory)
+		if err != nil {
+			logger(auditFields()).Fatal("Error auditing records: ", err)
+		}
+
+		switch aParams.output {
+		case "json":
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			enc.Encode(findings)
+		default:
+			w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+			fmt.Fprintln(w, "ZONE\tNAME\tTYPE\tKIND\tTARGET")
+			for _, f := range findings {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", f.ZoneName, f.Name, f.Type, f.Kind, f.Target)
+			}
+			w.Flush()
+		}
+
+		if len(findings) == 0 {
+			return
+		}
+
+		if aParams.planDir != "" {
+			if err := writeRemovePlan(aParams.planDir, findings); err != nil {
+				logger(auditFields()).Fatal("Error writing remove plan: ", err)
+			}
+		}
+		os.Exit(2)
+	},
+}
+
+// writeRemovePlan writes a change batch per zone to dir and prints the AWS
+// CLI commands that apply them.
+func writeRemovePlan(dir string, findings []*dangling.Finding) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	plan := dangling.RemovePlan(findings)
+	zoneIDs := make([]string, 0, len(plan))
+	for id := range plan {
+		zoneIDs = append(zoneIDs, id)
+	}
+	sort.Strings(zoneIDs)
+
+	fmt.Fprintln(os.Stderr, "\nTo remove the dangling records:")
+	for _, id := range zoneIDs {
+		data, err := dangling.MarshalPlan(plan[id])
+		if err != nil {
+			return err
+		}
+
+		shortID := strings.TrimPrefix(id, "/hostedzone/")
+		path := filepath.Join(dir, shortID+".json")
+		if err := ioutil.WriteFile(path, append(data, '\n'), 0600); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "aws route53 change-resource-record-sets --hosted-zone-id %s --change-batch file://%s\n", shortID, path)
+	}
+	return nil
+}
+
+func auditFields() logrus.Fields {
+	return logrus.Fields{
+		"op": "audit",
+	}
+}
+
+func init() {
+	RootCmd.AddCommand(auditCmd)
+	auditCmd.AddCommand(auditDanglingCmd)
+
+	auditDanglingCmd.Flags().StringVarP(&aParams.output, "output", "o", "text", "output format. text|json")
+	auditDanglingCmd.Flags().StringVar(&aParams.planDir, "plan-dir", "", "write change batches that remove the dangling records to this directory")
+}
@@ -0,0 +1,180 @@
+// Copyright © 2016 Ryan Eschinger <ryanesc@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/ryane/takethe53/batch"
+	"github.com/ryane/takethe53/policy"
+	"github.com/spf13/cobra"
+)
+
+type batchParams struct {
+	file        string
+	format      string
+	force       bool
+	dryRun      bool
+	yes         bool
+	concurrency int
+	timeout     time.Duration
+}
+
+var bParams batchParams
+
+var batchCmd = &cobra.Command{
+	Use:   "batch",
+	Short: "Create and remove many aliases at once",
+	Long: `Create and remove many aliases from a file or stdin. Each line is
+"action,alias,zone,target" in CSV, or an object with these fields in JSONL.
+The action is create (fails if the record exists), upsert or remove; remove
+needs no target.
+
+The changes are grouped per zone into as few change batches as Route53
+allows, zones are changed concurrently, and the result is reported per line.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := commandContext()
+
+		// the confirmation is read from stdin, which can't both hold the
+		// changes and answer the prompt
+		if !bParams.yes && !bParams.dryRun && (bParams.file == "-" || !interactive()) {
+			logger(batchFields()).Fatal("Can't ask for confirmation when the changes are read from stdin or stdin is not a terminal, use --yes to apply them.")
+		}
+
+		var in io.Reader = os.Stdin
+		if bParams.file != "-" {
+			f, err := os.Open(bParams.file)
+			if err != nil {
+				logger(batchFields()).Fatal(err)
+			}
+			defer f.Close()
+			in = f
+			if bParams.format == "" && strings.HasSuffix(bParams.file, ".csv") {
+				bParams.format = batch.FormatCSV
+			}
+			if bParams.format == "" && (strings.HasSuffix(bParams.file, ".jsonl") || strings.HasSuffix(bParams.file, ".json")) {
+				bParams.format = batch.FormatJSONL
+			}
+		}
+
+		lines, err := batch.Parse(in, bParams.format)
+		if err != nil {
+			logger(batchFields()).Fatal(err)
+		}
+		if len(lines) == 0 {
+			fmt.Println("Nothing to change.")
+			return
+		}
+
+		client := newClient()
+		opts := batch.Options{Force: bParams.force, Concurrency: bParams.concurrency}
+		plan, err := batch.Prepare(ctx, client, lines, opts)
+		if err != nil {
+			logger(batchFields()).Fatal("Error preparing changes: ", err)
+		}
+
+		changes := plan.Changes()
+		printChanges(changes)
+		fmt.Println(changeSummary(changes))
+
+		if bParams.dryRun || len(changes) == 0 {
+			printBatchResults(plan)
+			if plan.Failed() > 0 {
+				os.Exit(1)
+			}
+			return
+		}
+
+		if !bParams.yes {
+			if names := protectedNames(changes); len(names) > 0 {
+				fmt.Printf("Protected by policy: %s\n", strings.Join(names, ", "))
+			}
+			if failed := plan.Failed(); failed > 0 {
+				fmt.Printf("%d lines can't be applied and will be skipped.\n", failed)
+			}
+			if !confirm(fmt.Sprintf("Apply %d lines?", len(lines)-plan.Failed())) {
+				fmt.Println("Canceled.")
+				os.Exit(1)
+			}
+			ctx = policy.WithConfirmation(ctx)
+		}
+
+		plan.Apply(ctx, client, opts)
+
+		fmt.Print("Pending...  ")
+		waitCtx, cancel := context.WithTimeout(ctx, bParams.timeout)
+		plan.Wait(waitCtx, client, 2*time.Second)
+		if n := webhookNotifier(); n != nil && waitCtx.Err() == context.DeadlineExceeded {
+			for _, id := range plan.Pending() {
+				n.SyncTimeout(id)
+			}
+		}
+		cancel()
+		fmt.Println()
+
+		printBatchResults(plan)
+		if plan.Failed() > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+// printBatchResults prints the outcome of every line.
+func printBatchResults(plan *batch.Plan) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "LINE\tACTION\tNAME\tRESULT")
+	for _, r := range plan.Results {
+		name := r.Name
+		if name == "" {
+			name = r.Line.Alias
+		}
+
+		result := "planned"
+		switch {
+		case r.Err != nil:
+			result = "error: " + r.Err.Error()
+		case r.Status != "":
+			result = r.Status + " " + r.ChangeID
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", r.Line.Number, r.Line.Action, name, result)
+	}
+	w.Flush()
+}
+
+func batchFields() logrus.Fields {
+	return logrus.Fields{
+		"op":   "batch",
+		"file": bParams.file,
+	}
+}
+
+func init() {
+	RootCmd.AddCommand(batchCmd)
+
+	batchCmd.Flags().StringVar(&bParams.file, "file", "-", "file with the changes, - for stdin")
+	batchCmd.Flags().StringVar(&bParams.format, "format", "", "input format, csv or jsonl (default is by file extension or content)")
+	batchCmd.Flags().BoolVar(&bParams.force, "force", false, "modify records even if they are owned by someone else")
+	batchCmd.Flags().BoolVar(&bParams.dryRun, "dry-run", false, "only print the changes")
+	batchCmd.Flags().BoolVarP(&bParams.yes, "yes", "y", false, "apply without asking for confirmation")
+	batchCmd.Flags().IntVar(&bParams.concurrency, "concurrency", 4, "how many zones to change at the same time")
+	batchCmd.Flags().DurationVar(&bParams.timeout, "timeout", 5*time.Minute, "how long to wait for the changes to be INSYNC")
+}
@@ -0,0 +1,250 @@
+// Copyright © 2016 Ryan Eschinger <ryanesc@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/ryane/takethe53/awsclient"
+	"github.com/ryane/takethe53/recordset"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// completionCacheTTL is how long completion caches lookups when the
+	// lookup cache is disabled.
+	completionCacheTTL = 5 * time.Minute
+	// completionTimeout bounds the AWS lookups of a single completion.
+	completionTimeout = 5 * time.Second
+)
+
+var completionCmd = &cobra.Command{
+	Use:   "completion bash|zsh|fish",
+	Short: "Print a shell completion script",
+	Long: `Print a shell completion script. Besides commands and flags it completes
+hosted zone names, existing aliases and load balancer DNS names. Lookups are
+cached for --cache-ttl, or 5 minutes if the cache is disabled.
+
+  bash: source <(takethe53 completion bash)
+  zsh:  takethe53 completion zsh > "${fpath[1]}/_takethe53"
+  fish: takethe53 completion fish > ~/.config/fish/completions/takethe53.fish`,
+	ValidArgs: []string{"bash", "zsh", "fish"},
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) < 1 {
+			cmd.Usage()
+			os.Exit(1)
+		}
+
+		var err error
+		switch args[0] {
+		case "bash":
+			err = RootCmd.GenBashCompletionV2(os.Stdout, true)
+		case "zsh":
+			err = RootCmd.GenZshCompletion(os.Stdout)
+		case "fish":
+			err = RootCmd.GenFishCompletion(os.Stdout, true)
+		default:
+			cmd.Usage()
+			os.Exit(1)
+		}
+		if err != nil {
+			logrus.Fatal("Error writing completion script: ", err)
+		}
+	},
+}
+
+// completer suggests values for one argument, given the arguments before it.
+type completer func(ctx context.Context, client *awsclient.AWSClient, args []string) ([]string, error)
+
+// completeArgs returns a completion function that completes the i-th
+// argument with completers[i]. nil completers and arguments past the last
+// one complete nothing.
+func completeArgs(completers ...completer) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) >= len(completers) || completers[len(args)] == nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		// warnings would end up in the middle of the command line
+		logrus.SetOutput(ioutil.Discard)
+
+		ctx, cancel := context.WithTimeout(context.Background(), completionTimeout)
+		defer cancel()
+
+		values, err := completers[len(args)](ctx, completionClient(), args)
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+
+		var matches []string
+		for _, v := range values {
+			if strings.HasPrefix(strings.ToLower(v), strings.ToLower(toComplete)) {
+				matches = append(matches, v)
+			}
+		}
+		return matches, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// completionClient returns a client that always caches lookups, so
+// completion stays fast when pressing tab repeatedly.
+func completionClient() *awsclient.AWSClient {
+	cfg := awsConfig()
+	if cfg.Cache == nil {
+		cfg.Cache = fileCache(completionCacheTTL)
+	}
+	return awsclient.NewWithConfig(cfg)
+}
+
+// completeZones suggests hosted zone names.
+func completeZones(ctx context.Context, client *awsclient.AWSClient, args []string) ([]string, error) {
+	zones, err := client.ZonesWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, z := range zones {
+		names = append(names, strings.TrimSuffix(z.Name, "."))
+	}
+	return names, nil
+}
+
+// completeAliases suggests the aliases of every zone, relative to their zone.
+func completeAliases(ctx context.Context, client *awsclient.AWSClient, args []string) ([]string, error) {
+	aliases, err := zoneAliases(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	var names []string
+	for _, zoneAliases := range aliases {
+		for _, alias := range zoneAliases {
+			if !seen[alias] {
+				seen[alias] = true
+				names = append(names, alias)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// completeZonesWithAlias suggests the zones that have the alias given as the
+// first argument.
+func completeZonesWithAlias(ctx context.Context, client *awsclient.AWSClient, args []string) ([]string, error) {
+	aliases, err := zoneAliases(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for zone, zoneAliases := range aliases {
+		for _, alias := range zoneAliases {
+			if alias == args[0] {
+				names = append(names, strings.TrimSuffix(zone, "."))
+				break
+			}
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// completeLoadBalancers suggests load balancer DNS names.
+func completeLoadBalancers(ctx context.Context, client *awsclient.AWSClient, args []string) ([]string, error) {
+	lbs, err := client.LoadBalancersWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, lb := range lbs {
+		names = append(names, lb.Name)
+	}
+	return names, nil
+}
+
+// zoneAliases returns the names of the alias records of every zone, relative
+// to the zone, by zone name. The zones are listed concurrently; zones that
+// could not be listed before the deadline are left out, and an error is only
+// returned if none could be.
+func zoneAliases(ctx context.Context, client *awsclient.AWSClient) (map[string][]string, error) {
+	zones, err := client.ZonesWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		firstErr error
+		listed   int
+	)
+	aliases := map[string][]string{}
+	for _, z := range zones {
+		wg.Add(1)
+		go func(z *awsclient.Zone) {
+			defer wg.Done()
+
+			rrsets, err := client.RecordSetsWithContext(ctx, z)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				return
+			}
+			listed++
+			for _, rrs := range rrsets {
+				if rrs.AliasTarget != nil {
+					aliases[z.Name] = append(aliases[z.Name], aliasCompletion(aws.StringValue(rrs.Name), z.Name))
+				}
+			}
+		}(z)
+	}
+	wg.Wait()
+
+	if listed == 0 && firstErr != nil {
+		return nil, firstErr
+	}
+	return aliases, nil
+}
+
+// aliasCompletion returns the alias argument for a record name: relative to
+// the zone, or the zone name itself for the apex.
+func aliasCompletion(name, zoneName string) string {
+	name = awsclient.UnescapeName(name)
+	alias := recordset.RelativeName(name, zoneName)
+	if alias == "@" {
+		return strings.TrimSuffix(zoneName, ".")
+	}
+	return alias
+}
+
+func init() {
+	RootCmd.AddCommand(completionCmd)
+}
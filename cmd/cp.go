@@ -0,0 +1,181 @@
+// Copyright © 2016 Ryan Eschinger <ryanesc@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/ryane/takethe53/awsclient"
+	"github.com/ryane/takethe53/policy"
+	"github.com/ryane/takethe53/recordset"
+	"github.com/spf13/cobra"
+)
+
+type copyParams struct {
+	fromZone  string
+	toZone    string
+	filters   []string
+	toProfile string
+	toRoleARN string
+	overwrite bool
+	dryRun    bool
+	yes       bool
+}
+
+var cpParams copyParams
+
+var cpCmd = &cobra.Command{
+	Use:   "cp <zone_name> <target_zone_name>",
+	Short: "Copy records from one zone to another",
+	Long: `Copy records from one zone to another, in the same or another AWS account.
+Names are rewritten for the target zone, and aliases to records of the source
+zone point at the same names in the target zone. Records that already exist
+in the target zone with different values are skipped unless --overwrite is
+set; nothing is deleted. The SOA and NS records at the zone apex and
+ownership TXT records are not copied.
+
+Use --to-profile or --to-role-arn to write to a zone in another account.`,
+	ValidArgsFunction: completeArgs(completeZones, completeZones),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := commandContext()
+
+		if len(args) < 2 {
+			cmd.Usage()
+			os.Exit(1)
+		}
+		cpParams.fromZone = args[0]
+		cpParams.toZone = args[1]
+
+		source := newClient()
+
+		target := newClientWithConfig(accountConfig(cpParams.toProfile, cpParams.toRoleARN))
+
+		from, err := source.FindZoneWithContext(ctx, cpParams.fromZone)
+		if err != nil {
+			logger(cpFields()).Fatal("Error finding zone: ", err)
+		}
+		to, err := target.FindZoneWithContext(ctx, cpParams.toZone)
+		if err != nil {
+			logger(cpFields()).Fatal("Error finding target zone: ", err)
+		}
+		if from.ID == to.ID {
+			logger(cpFields()).Fatal("Source and target are the same zone.")
+		}
+
+		rrsets, err := source.RecordSetsWithContext(ctx, from)
+		if err != nil {
+			logger(cpFields()).Fatal("Error listing record sets: ", err)
+		}
+		rrsets = recordset.Match(from.Name, cpParams.filters, copyable(from.Name, rrsets))
+		if len(rrsets) == 0 {
+			fmt.Println("No records to copy.")
+			return
+		}
+
+		current, err := target.RecordSetsWithContext(ctx, to)
+		if err != nil {
+			logger(cpFields()).Fatal("Error listing target record sets: ", err)
+		}
+
+		desired := recordset.Rename(rrsets, from.Name, from.ID, to.Name, to.ID)
+		changes, skipped := recordset.Merge(current, desired, cpParams.overwrite)
+		for _, rrs := range skipped {
+			fmt.Printf("Skipping %s %s, it exists with different values. Use --overwrite to replace it.\n", aws.StringValue(rrs.Name), aws.StringValue(rrs.Type))
+		}
+		if len(changes) == 0 {
+			fmt.Println("Target zone already has these records.")
+			return
+		}
+
+		printChanges(changes)
+		fmt.Println(changeSummary(changes))
+		if cpParams.dryRun {
+			return
+		}
+		names := protectedNames(changes)
+		if !cpParams.yes && (overwrites(changes) || len(names) > 0) {
+			if len(names) > 0 {
+				fmt.Printf("Protected by policy: %s\n", strings.Join(names, ", "))
+			}
+			if !confirm(fmt.Sprintf("Copy these records to %s?", to.Name)) {
+				fmt.Println("Canceled.")
+				os.Exit(1)
+			}
+			ctx = policy.WithConfirmation(ctx)
+		}
+
+		batches := recordset.Batches(changes, recordset.MaxBatchSize)
+		var submitted []*awsclient.ChangeStatus
+		for i, batch := range batches {
+			change, err := target.ChangeRecordSets(ctx, to, "copy", batch)
+			if err != nil {
+				if i > 0 {
+					logger(cpFields()).Fatalf("Error applying batch %d of %d, the first %d were applied: %s", i+1, len(batches), i, err)
+				}
+				logger(cpFields()).Fatalf("Error applying batch %d of %d: %s", i+1, len(batches), err)
+			}
+			submitted = append(submitted, change)
+		}
+
+		fmt.Print("Pending...  ")
+		waitForChangesSync(ctx, target, submitted, 60, cpFields())
+	},
+}
+
+// copyable returns the record sets of a zone that can be copied to another
+// zone.
+func copyable(zoneName string, rrsets []*route53.ResourceRecordSet) []*route53.ResourceRecordSet {
+	var out []*route53.ResourceRecordSet
+	for _, rrs := range recordset.Editable(zoneName, rrsets) {
+		if !awsclient.IsOwnershipRecord(rrs) {
+			out = append(out, rrs)
+		}
+	}
+	return out
+}
+
+// overwrites reports whether changes replace existing records.
+func overwrites(changes []*route53.Change) bool {
+	for _, c := range changes {
+		if aws.StringValue(c.Action) != route53.ChangeActionCreate {
+			return true
+		}
+	}
+	return false
+}
+
+func cpFields() logrus.Fields {
+	return logrus.Fields{
+		"op":     "cp",
+		"zone":   cpParams.fromZone,
+		"target": cpParams.toZone,
+	}
+}
+
+func init() {
+	RootCmd.AddCommand(cpCmd)
+
+	cpCmd.Flags().StringSliceVar(&cpParams.filters, "filter", nil, "only copy records whose names match this pattern, e.g. \"*.app\" (repeatable)")
+	cpCmd.Flags().StringVar(&cpParams.toProfile, "to-profile", "", "AWS profile of the target account")
+	cpCmd.Flags().StringVar(&cpParams.toRoleARN, "to-role-arn", "", "IAM role to assume for the target account")
+	cpCmd.Flags().BoolVar(&cpParams.overwrite, "overwrite", false, "replace records that exist in the target zone with different values")
+	cpCmd.Flags().BoolVar(&cpParams.dryRun, "dry-run", false, "print the changes without applying them")
+	cpCmd.Flags().BoolVarP(&cpParams.yes, "yes", "y", false, "overwrite records without asking for confirmation")
+}
@@ -19,7 +19,9 @@ import (
 	"os"
 
 	"github.com/Sirupsen/logrus"
-	"github.com/ryane/takethe53/awsclient"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/ryane/takethe53/recordset"
 	"github.com/spf13/cobra"
 )
 
@@ -28,46 +30,79 @@ type createParams struct {
 	zoneName     string
 	elbDnsName   string
 	hostedZoneID string
+	yes          bool
 }
 
 var cParams createParams
 
 var createCmd = &cobra.Command{
-	Use:   "create <alias> <zone_name> <elb_dns_name>",
-	Short: "Create or update a Route53 alias for an ELB",
-	Long:  `Create or update a Route53 alias for an ELB`,
+	Use:               "create <alias> <zone_name> <elb_dns_name>",
+	Short:             "Create or update a Route53 alias for an ELB",
+	Long:              `Create or update a Route53 alias for an ELB`,
+	ValidArgsFunction: completeArgs(nil, completeZones, completeLoadBalancers),
 	Run: func(cmd *cobra.Command, args []string) {
-		client := awsclient.New()
+		client := newClient()
+		ctx := commandContext()
 
 		if len(args) < 3 {
 			cmd.Usage()
 			os.Exit(1)
 		}
 
-		cParams = createParams{
-			alias:      args[0],
-			zoneName:   args[1],
-			elbDnsName: args[2],
-		}
+		cParams.alias = args[0]
+		cParams.zoneName = args[1]
+		cParams.elbDnsName = args[2]
 
-		zone, err := client.FindZone(cParams.zoneName)
+		zone, err := client.FindZoneWithContext(ctx, cParams.zoneName)
 		if err != nil {
 			logger(createFields()).Fatal("Error finding zone: ", err)
 		}
 
-		lb, err := client.FindLoadBalancer(cParams.elbDnsName)
+		lb, err := client.FindLoadBalancerWithContext(ctx, cParams.elbDnsName)
 		if err != nil {
 			logger(createFields()).Fatal("Error finding load balancer: ", err)
 		}
 
 		cParams.hostedZoneID = lb.HostedZoneID
-		change, err := client.SetAlias(zone, cParams.hostedZoneID, lb.Name, cParams.alias)
+		opts := aliasOptions(cmd)
+
+		if !opts.IfNotExists {
+			existing, err := client.RecordSetsNamedWithContext(ctx, zone, cParams.alias)
+			if err != nil {
+				logger(createFields()).Fatal("Error finding records: ", err)
+			}
+
+			// any A or CNAME record at the name is replaced, unless it
+			// already is an alias of the load balancer
+			var overwritten []*route53.ResourceRecordSet
+			for _, rrs := range existing {
+				switch aws.StringValue(rrs.Type) {
+				case route53.RRTypeA:
+					if rrs.AliasTarget == nil || !recordset.SameName(aws.StringValue(rrs.AliasTarget.DNSName), lb.Name) {
+						overwritten = append(overwritten, rrs)
+					}
+				case route53.RRTypeCname:
+					overwritten = append(overwritten, rrs)
+				}
+			}
+
+			if len(overwritten) > 0 {
+				var ok bool
+				ctx, ok = confirmRecordChange(ctx, fmt.Sprintf("Overwrite this record with ALIAS %s?", lb.Name), zone, overwritten, cParams.yes)
+				if !ok {
+					fmt.Println("Canceled.")
+					os.Exit(1)
+				}
+			}
+		}
+
+		change, err := client.SetAliasWithOptions(ctx, zone, cParams.hostedZoneID, lb.Name, cParams.alias, opts)
 		if err != nil {
 			logger(createFields()).Fatal("Error setting alias: ", err)
 		}
 
 		fmt.Print("Pending...  ")
-		waitForChangeSync(client, change, 60, createFields())
+		waitForChangeSync(ctx, client, change, 60, createFields())
 	},
 }
 
@@ -83,4 +118,9 @@ func createFields() logrus.Fields {
 
 func init() {
 	RootCmd.AddCommand(createCmd)
+
+	createCmd.Flags().Bool("force", false, "modify the record even if it is owned by someone else")
+	createCmd.Flags().Bool("if-not-exists", false, "only create the alias if the record does not exist yet")
+	createCmd.Flags().String("expect-target", "", "only update the alias if it currently points at this DNS name")
+	createCmd.Flags().BoolVarP(&cParams.yes, "yes", "y", false, "overwrite an existing record without asking for confirmation")
 }
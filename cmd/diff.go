@@ -0,0 +1,211 @@
+// Copyright © 2016 Ryan Eschinger <ryanesc@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/ryane/takethe53/awsclient"
+	"github.com/ryane/takethe53/recordset"
+	"github.com/spf13/cobra"
+)
+
+type diffParams struct {
+	zoneA    string
+	zoneB    string
+	profileA string
+	roleARNA string
+	profileB string
+	roleARNB string
+	output   string
+}
+
+var dfParams diffParams
+
+var diffCmd = &cobra.Command{
+	Use:   "diff <zone_name> <other_zone_name>",
+	Short: "Compare the records of two zones",
+	Long: `Compare the records of two zones, e.g. staging and production, and print the
+records that only exist in one of them or differ. Names are compared relative
+to each zone apex, so www.staging.example.com matches www.example.com, and
+aliases to records of the zone itself are compared the same way. Values,
+TTLs, alias targets and routing policies must match. The SOA and NS records
+at the apex are ignored. Exits with status 2 if the zones differ.
+
+Each zone can be read with its own credentials, see --a-profile and
+--b-profile.`,
+	ValidArgsFunction: completeArgs(completeZones, completeZones),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := commandContext()
+
+		if len(args) < 2 {
+			cmd.Usage()
+			os.Exit(1)
+		}
+		dfParams.zoneA = args[0]
+		dfParams.zoneB = args[1]
+
+		a, aRecords := zoneRecords(ctx, accountConfig(dfParams.profileA, dfParams.roleARNA), dfParams.zoneA)
+		b, bRecords := zoneRecords(ctx, accountConfig(dfParams.profileB, dfParams.roleARNB), dfParams.zoneB)
+
+		diffs := recordset.Compare(a.Name, a.ID, aRecords, b.Name, b.ID, bRecords)
+
+		switch dfParams.output {
+		case "json":
+			if diffs == nil {
+				diffs = []recordset.Difference{}
+			}
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			enc.Encode(map[string]interface{}{
+				"a":           map[string]string{"id": a.ID, "name": a.Name},
+				"b":           map[string]string{"id": b.ID, "name": b.Name},
+				"differences": diffs,
+			})
+		default:
+			printDifferences(a, b, diffs)
+		}
+
+		if len(diffs) > 0 {
+			os.Exit(2)
+		}
+	},
+}
+
+// zoneRecords reads the editable record sets of a zone.
+func zoneRecords(ctx context.Context, cfg awsclient.Config, zoneName string) (*awsclient.Zone, []*route53.ResourceRecordSet) {
+	client := newClientWithConfig(cfg)
+
+	zone, err := client.FindZoneWithContext(ctx, zoneName)
+	if err != nil {
+		logger(diffFields()).Fatalf("Error finding zone %s: %s", zoneName, err)
+	}
+
+	rrsets, err := client.RecordSetsWithContext(ctx, zone)
+	if err != nil {
+		logger(diffFields()).Fatalf("Error listing record sets of %s: %s", zone.Name, err)
+	}
+
+	return zone, recordset.Editable(zone.Name, rrsets)
+}
+
+func printDifferences(a, b *awsclient.Zone, diffs []recordset.Difference) {
+	if len(diffs) == 0 {
+		fmt.Println("The zones have the same records.")
+		return
+	}
+
+	fmt.Printf("--- %s (%s)\n", a.Name, a.ID)
+	fmt.Printf("+++ %s (%s)\n", b.Name, b.ID)
+	for _, d := range diffs {
+		id := ""
+		if d.SetIdentifier != "" {
+			id = " [" + d.SetIdentifier + "]"
+		}
+		switch {
+		case d.B == nil:
+			fmt.Printf("- %s %s%s %s\n", d.Name, d.Type, id, describeRecordSet(d.A))
+		case d.A == nil:
+			fmt.Printf("+ %s %s%s %s\n", d.Name, d.Type, id, describeRecordSet(d.B))
+		default:
+			fmt.Printf("~ %s %s%s\n", d.Name, d.Type, id)
+			fmt.Printf("    - %s\n", describeRecordSet(d.A))
+			fmt.Printf("    + %s\n", describeRecordSet(d.B))
+		}
+	}
+	fmt.Println(differenceSummary(diffs))
+}
+
+// describeRecordSet formats the TTL, values or alias target and the routing
+// policy of a record set.
+func describeRecordSet(rrs *route53.ResourceRecordSet) string {
+	var parts []string
+	if rrs.AliasTarget != nil {
+		parts = append(parts,
+			recordSetValue(rrs),
+			"zone="+aws.StringValue(rrs.AliasTarget.HostedZoneId),
+			"evaluate-target-health="+strconv.FormatBool(aws.BoolValue(rrs.AliasTarget.EvaluateTargetHealth)),
+		)
+	} else {
+		parts = append(parts, "ttl="+strconv.FormatInt(aws.Int64Value(rrs.TTL), 10), recordSetValue(rrs))
+	}
+
+	if rrs.Weight != nil {
+		parts = append(parts, "weight="+strconv.FormatInt(aws.Int64Value(rrs.Weight), 10))
+	}
+	if rrs.Region != nil {
+		parts = append(parts, "region="+aws.StringValue(rrs.Region))
+	}
+	if rrs.Failover != nil {
+		parts = append(parts, "failover="+aws.StringValue(rrs.Failover))
+	}
+	if geo := rrs.GeoLocation; geo != nil {
+		parts = append(parts, "geo="+strings.Trim(strings.Join([]string{
+			aws.StringValue(geo.ContinentCode),
+			aws.StringValue(geo.CountryCode),
+			aws.StringValue(geo.SubdivisionCode),
+		}, "/"), "/"))
+	}
+	if aws.BoolValue(rrs.MultiValueAnswer) {
+		parts = append(parts, "multivalue")
+	}
+	if rrs.HealthCheckId != nil {
+		parts = append(parts, "health-check="+aws.StringValue(rrs.HealthCheckId))
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// differenceSummary counts the differences by kind.
+func differenceSummary(diffs []recordset.Difference) string {
+	var onlyA, onlyB, changed int
+	for _, d := range diffs {
+		switch {
+		case d.B == nil:
+			onlyA++
+		case d.A == nil:
+			onlyB++
+		default:
+			changed++
+		}
+	}
+	return fmt.Sprintf("%d only in %s, %d only in %s, %d changed.", onlyA, dfParams.zoneA, onlyB, dfParams.zoneB, changed)
+}
+
+func diffFields() logrus.Fields {
+	return logrus.Fields{
+		"op":    "diff",
+		"zone":  dfParams.zoneA,
+		"other": dfParams.zoneB,
+	}
+}
+
+func init() {
+	RootCmd.AddCommand(diffCmd)
+
+	diffCmd.Flags().StringVar(&dfParams.profileA, "a-profile", "", "AWS profile for the first zone")
+	diffCmd.Flags().StringVar(&dfParams.roleARNA, "a-role-arn", "", "IAM role to assume for the first zone")
+	diffCmd.Flags().StringVar(&dfParams.profileB, "b-profile", "", "AWS profile for the second zone")
+	diffCmd.Flags().StringVar(&dfParams.roleARNB, "b-role-arn", "", "IAM role to assume for the second zone")
+	diffCmd.Flags().StringVarP(&dfParams.output, "output", "o", "text", "output format. text|json")
+}
@@ -0,0 +1,168 @@
+// Copyright © 2016 Ryan Eschinger <ryanesc@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/ryane/takethe53/awsclient"
+	"github.com/ryane/takethe53/drift"
+	"github.com/ryane/takethe53/server"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+type driftParams struct {
+	specFile string
+	webhook  string
+	output   string
+}
+
+var dParams driftParams
+
+var driftCmd = &cobra.Command{
+	Use:   "drift --file <spec>",
+	Short: "Compare Route53 records with a desired-state spec",
+	Long: `Compare Route53 records with a desired-state spec and report records that
+were added, changed or removed out-of-band, and aliases of load balancers that
+no longer exist. Exits with status 2 if there is drift.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := commandContext()
+
+		if dParams.specFile == "" {
+			cmd.Usage()
+			os.Exit(1)
+		}
+
+		spec, err := drift.LoadSpec(dParams.specFile)
+		if err != nil {
+			logger(driftFields()).Fatal("Error reading spec: ", err)
+		}
+
+		cfg := awsConfig()
+		cfg.Cache = nil
+		client := newClientWithConfig(cfg)
+		report, err := drift.Check(ctx, client, spec, regionLoadBalancers(cfg, client))
+		if err != nil {
+			logger(driftFields()).Fatal("Error checking drift: ", err)
+		}
+
+		switch dParams.output {
+		case "json":
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			enc.Encode(report)
+		default:
+			report.WriteText(os.Stdout)
+		}
+
+		if !report.Drifted() {
+			return
+		}
+		if dParams.webhook != "" {
+			if err := drift.Notify(ctx, dParams.webhook, report); err != nil {
+				logger(driftFields()).Error("Error sending webhook: ", err)
+			}
+		}
+		os.Exit(2)
+	},
+}
+
+// driftJob returns the server job that checks the spec in the drift-spec
+// setting, or nil if it is not set. The job has its own client without the
+// lookup cache, cached record sets could hide drift. It only reads, so the
+// client has none of the change handlers.
+func driftJob() *server.Job {
+	specFile := viper.GetString("drift-spec")
+	if specFile == "" {
+		return nil
+	}
+
+	cfg := awsConfig()
+	cfg.Cache = nil
+	client := awsclient.NewWithConfig(cfg)
+
+	fields := logrus.Fields{"op": "drift", "spec": specFile}
+	webhook := viper.GetString("drift-webhook")
+	var notified []byte
+
+	return &server.Job{
+		Name:     "drift",
+		Interval: viper.GetDuration("drift-interval"),
+		Run: func(ctx context.Context) error {
+			// re-read the spec so it can be updated without a restart
+			spec, err := drift.LoadSpec(specFile)
+			if err != nil {
+				return err
+			}
+
+			report, err := drift.Check(ctx, client, spec, regionLoadBalancers(cfg, client))
+			if err != nil {
+				return err
+			}
+
+			if !report.Drifted() {
+				logger(fields).Info("No drift.")
+				notified = nil
+				return nil
+			}
+
+			logger(fields).WithField("findings", len(report.Findings)).Warn("Records drifted from the spec.")
+
+			// only notify again when the findings change
+			findings, _ := json.Marshal(report.Findings)
+			if webhook == "" || bytes.Equal(findings, notified) {
+				return nil
+			}
+			if err := drift.Notify(ctx, webhook, report); err != nil {
+				return err
+			}
+			notified = findings
+			return nil
+		},
+	}
+}
+
+// regionLoadBalancers looks up load balancers with client in its own region,
+// and with a client for the region in others.
+func regionLoadBalancers(cfg awsclient.Config, client *awsclient.AWSClient) drift.LoadBalancersFunc {
+	return func(ctx context.Context, region string) ([]string, error) {
+		if region == cfg.Region {
+			return client.LoadBalancerDNSNamesWithContext(ctx)
+		}
+		regionCfg := cfg
+		regionCfg.Region = region
+		return awsclient.NewWithConfig(regionCfg).LoadBalancerDNSNamesWithContext(ctx)
+	}
+}
+
+func driftFields() logrus.Fields {
+	return logrus.Fields{
+		"op":   "drift",
+		"spec": dParams.specFile,
+	}
+}
+
+func init() {
+	RootCmd.AddCommand(driftCmd)
+
+	driftCmd.Flags().StringVar(&dParams.specFile, "file", "", "YAML spec of the desired records")
+	driftCmd.Flags().StringVar(&dParams.webhook, "webhook", "", "post the report to this URL if there is drift")
+	driftCmd.Flags().StringVarP(&dParams.output, "output", "o", "text", "report format. text|json")
+}
@@ -0,0 +1,75 @@
+// Copyright © 2016 Ryan Eschinger <ryanesc@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"net/http"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/ryane/takethe53/awsclient/fake"
+	"github.com/ryane/takethe53/emulator"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var emulateCmd = &cobra.Command{
+	Use:   "emulate",
+	Short: "Serve a local Route53 and ELB API emulator",
+	Long: `Serve a subset of the Route53 REST API (ListHostedZones, CreateHostedZone,
+ListResourceRecordSets, ChangeResourceRecordSets, GetChange) and the ELB
+DescribeLoadBalancers action from in-memory state. Point takethe53 at it with
+--route53-endpoint and --elb-endpoint.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		r53 := fake.NewRoute53()
+		r53.SyncDelay = viper.GetDuration("emulate.sync-delay")
+		e := emulator.New(r53, fake.NewELB())
+
+		if seedFile := viper.GetString("emulate.seed"); seedFile != "" {
+			seed, err := emulator.LoadSeed(seedFile)
+			if err != nil {
+				logger(emulateFields()).Fatal("Error reading seed file: ", err)
+			}
+			if err := e.Apply(seed); err != nil {
+				logger(emulateFields()).Fatal("Error applying seed file: ", err)
+			}
+		}
+
+		logger(emulateFields()).Info("Emulator started")
+		if err := http.ListenAndServe(viper.GetString("emulate.address"), e); err != nil {
+			logger(emulateFields()).Fatal("Error running emulator: ", err)
+		}
+	},
+}
+
+func emulateFields() logrus.Fields {
+	return logrus.Fields{
+		"op":      "emulate",
+		"address": viper.GetString("emulate.address"),
+		"seed":    viper.GetString("emulate.seed"),
+	}
+}
+
+func init() {
+	RootCmd.AddCommand(emulateCmd)
+
+	emulateCmd.Flags().String("address", ":9054", "the address to listen on")
+	viper.BindPFlag("emulate.address", emulateCmd.Flags().Lookup("address"))
+
+	emulateCmd.Flags().String("seed", "", "JSON file with zones, record sets and load balancers to start with")
+	viper.BindPFlag("emulate.seed", emulateCmd.Flags().Lookup("seed"))
+
+	emulateCmd.Flags().Duration("sync-delay", 0, "how long changes stay PENDING before they are INSYNC")
+	viper.BindPFlag("emulate.sync-delay", emulateCmd.Flags().Lookup("sync-delay"))
+}
@@ -0,0 +1,89 @@
+// Copyright © 2016 Ryan Eschinger <ryanesc@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"os"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/ryane/takethe53/export"
+	"github.com/spf13/cobra"
+)
+
+type exportParams struct {
+	zoneName string
+	format   string
+}
+
+var eParams exportParams
+
+var exportCmd = &cobra.Command{
+	Use:   "export <zone_name>",
+	Short: "Export the records of a zone as Terraform or CloudFormation",
+	Long: `Export the live records of a zone as Terraform aws_route53_record resources
+with import blocks, or as a CloudFormation template of AWS::Route53::RecordSet
+resources. Aliases of classic load balancers refer to the load balancer by
+name where the format allows it. The SOA and NS records at the zone apex and
+ownership TXT records are left out. The result is written to stdout.`,
+	ValidArgsFunction: completeArgs(completeZones),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := commandContext()
+
+		if len(args) < 1 {
+			cmd.Usage()
+			os.Exit(1)
+		}
+		eParams.zoneName = args[0]
+
+		client := newClient()
+
+		zone, err := client.FindZoneWithContext(ctx, eParams.zoneName)
+		if err != nil {
+			logger(exportFields()).Fatal("Error finding zone: ", err)
+		}
+
+		rrsets, err := client.RecordSetsWithContext(ctx, zone)
+		if err != nil {
+			logger(exportFields()).Fatal("Error listing record sets: ", err)
+		}
+
+		lbs, err := client.LoadBalancersWithContext(ctx)
+		if err != nil {
+			// aliases are exported with their DNS names instead
+			logger(exportFields()).Warn("Error listing load balancers: ", err)
+		}
+
+		if err := export.Write(os.Stdout, eParams.format, zone, rrsets, lbs); err != nil {
+			logger(exportFields()).Fatal(err)
+		}
+	},
+}
+
+func exportFields() logrus.Fields {
+	return logrus.Fields{
+		"op":     "export",
+		"zone":   eParams.zoneName,
+		"format": eParams.format,
+	}
+}
+
+func init() {
+	RootCmd.AddCommand(exportCmd)
+
+	exportCmd.Flags().StringVar(&eParams.format, "format", export.Terraform, "output format. terraform|cloudformation")
+	exportCmd.RegisterFlagCompletionFunc("format", func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+		return []string{export.Terraform, export.CloudFormation}, cobra.ShellCompDirectiveNoFileComp
+	})
+}
@@ -0,0 +1,93 @@
+// Copyright © 2016 Ryan Eschinger <ryanesc@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/ryane/takethe53/journal"
+	"github.com/spf13/cobra"
+)
+
+type historyParams struct {
+	zoneName string
+	limit    int
+}
+
+var hParams historyParams
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "List changes recorded in the change journal",
+	Long:  `List changes recorded in the change journal, newest last. Pass an entry ID to "undo" to revert it.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := commandContext()
+
+		store := journalStore()
+		if store == nil {
+			logger(historyFields()).Fatal("The change journal is disabled.")
+		}
+
+		entries, err := store.List(ctx)
+		if err != nil {
+			logger(historyFields()).Fatal("Error reading journal: ", err)
+		}
+
+		var shown []*journal.Entry
+		for _, e := range entries {
+			if hParams.zoneName != "" && !strings.EqualFold(strings.TrimSuffix(e.ZoneName, "."), strings.TrimSuffix(hParams.zoneName, ".")) {
+				continue
+			}
+			shown = append(shown, e)
+		}
+		if hParams.limit > 0 && len(shown) > hParams.limit {
+			shown = shown[len(shown)-hParams.limit:]
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tTIME\tUSER\tOP\tZONE\tRECORDS\tCHANGE")
+		for _, e := range shown {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+				e.ID,
+				e.Time.Local().Format("2006-01-02 15:04:05"),
+				e.User,
+				e.Op,
+				e.ZoneName,
+				strings.Join(e.Names(), ","),
+				e.ChangeID,
+			)
+		}
+		w.Flush()
+	},
+}
+
+func historyFields() logrus.Fields {
+	return logrus.Fields{
+		"op":   "history",
+		"zone": hParams.zoneName,
+	}
+}
+
+func init() {
+	RootCmd.AddCommand(historyCmd)
+
+	historyCmd.Flags().StringVar(&hParams.zoneName, "zone", "", "only show changes to this zone")
+	historyCmd.RegisterFlagCompletionFunc("zone", completeArgs(completeZones))
+	historyCmd.Flags().IntVarP(&hParams.limit, "limit", "n", 20, "number of entries to show, 0 for all")
+}
@@ -0,0 +1,96 @@
+// Copyright © 2016 Ryan Eschinger <ryanesc@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/spf13/cobra"
+)
+
+type moveParams struct {
+	from     string
+	to       string
+	zoneName string
+	yes      bool
+}
+
+var mvParams moveParams
+
+var mvCmd = &cobra.Command{
+	Use:   "mv <alias> <new_alias> <zone_name>",
+	Short: "Rename a Route53 alias",
+	Long: `Rename a Route53 alias. Every alias record of the old name, like its A and
+AAAA records, is deleted and recreated under the new name in a single change
+batch, so there is no moment in which both or neither exist. The new name
+must not have a CNAME or a record of the same type yet.`,
+	ValidArgsFunction: completeArgs(completeAliases, nil, completeZonesWithAlias),
+	Run: func(cmd *cobra.Command, args []string) {
+		client := newClient()
+		ctx := commandContext()
+
+		if len(args) < 3 {
+			cmd.Usage()
+			os.Exit(1)
+		}
+
+		mvParams.from = args[0]
+		mvParams.to = args[1]
+		mvParams.zoneName = args[2]
+
+		zone, err := client.FindZoneWithContext(ctx, mvParams.zoneName)
+		if err != nil {
+			logger(mvFields()).Fatal("Error finding zone: ", err)
+		}
+
+		rec, err := client.FindRecordWithContext(ctx, zone, mvParams.from)
+		if err != nil {
+			logger(mvFields()).Fatal("Error finding alias: ", err)
+		}
+
+		ctx, ok := confirmRecordChange(ctx, fmt.Sprintf("Move this record to %s?", zone.RecordName(mvParams.to)), zone, []*route53.ResourceRecordSet{rec.ResourceRecordSet()}, mvParams.yes)
+		if !ok {
+			fmt.Println("Canceled.")
+			os.Exit(1)
+		}
+
+		change, err := client.MoveAliasWithOptions(ctx, zone, mvParams.from, mvParams.to, aliasOptions(cmd))
+		if err != nil {
+			logger(mvFields()).Fatal("Error moving alias: ", err)
+		}
+
+		fmt.Print("Pending...  ")
+		waitForChangeSync(ctx, client, change, 60, mvFields())
+	},
+}
+
+func mvFields() logrus.Fields {
+	return logrus.Fields{
+		"op":    "mv",
+		"zone":  mvParams.zoneName,
+		"alias": mvParams.from,
+		"to":    mvParams.to,
+	}
+}
+
+func init() {
+	RootCmd.AddCommand(mvCmd)
+
+	mvCmd.Flags().Bool("force", false, "modify the record even if it is owned by someone else")
+	mvCmd.Flags().BoolVarP(&mvParams.yes, "yes", "y", false, "move without asking for confirmation")
+}
@@ -19,46 +19,58 @@ import (
 	"os"
 
 	"github.com/Sirupsen/logrus"
-	"github.com/ryane/takethe53/awsclient"
+	"github.com/aws/aws-sdk-go/service/route53"
 	"github.com/spf13/cobra"
 )
 
 type removeParams struct {
 	alias    string
 	zoneName string
+	yes      bool
 }
 
 var rParams removeParams
 
 var removeCmd = &cobra.Command{
-	Use:   "remove <alias> <zone_name>",
-	Short: "Remove a Route53 alias for an ELB",
-	Long:  `Remove a Route53 alias for an ELB`,
+	Use:               "remove <alias> <zone_name>",
+	Short:             "Remove a Route53 alias for an ELB",
+	Long:              `Remove a Route53 alias for an ELB`,
+	ValidArgsFunction: completeArgs(completeAliases, completeZonesWithAlias),
 	Run: func(cmd *cobra.Command, args []string) {
-		client := awsclient.New()
+		client := newClient()
+		ctx := commandContext()
 
 		if len(args) < 2 {
 			cmd.Usage()
 			os.Exit(1)
 		}
 
-		rParams = removeParams{
-			alias:    args[0],
-			zoneName: args[1],
-		}
+		rParams.alias = args[0]
+		rParams.zoneName = args[1]
 
-		zone, err := client.FindZone(rParams.zoneName)
+		zone, err := client.FindZoneWithContext(ctx, rParams.zoneName)
 		if err != nil {
 			logger(removeFields()).Fatal("Error finding zone: ", err)
 		}
 
-		change, err := client.RemoveAlias(zone, rParams.alias)
+		rec, err := client.FindRecordWithContext(ctx, zone, rParams.alias)
+		if err != nil {
+			logger(removeFields()).Fatal("Error finding alias: ", err)
+		}
+
+		ctx, ok := confirmRecordChange(ctx, "Delete this record?", zone, []*route53.ResourceRecordSet{rec.ResourceRecordSet()}, rParams.yes)
+		if !ok {
+			fmt.Println("Canceled.")
+			os.Exit(1)
+		}
+
+		change, err := client.RemoveAliasWithOptions(ctx, zone, rParams.alias, aliasOptions(cmd))
 		if err != nil {
 			logger(removeFields()).Fatal("Error removing alias: ", err)
 		}
 
 		fmt.Print("Pending...  ")
-		waitForChangeSync(client, change, 60, removeFields())
+		waitForChangeSync(ctx, client, change, 60, removeFields())
 	},
 }
 
@@ -72,4 +84,7 @@ func removeFields() logrus.Fields {
 
 func init() {
 	RootCmd.AddCommand(removeCmd)
+
+	removeCmd.Flags().Bool("force", false, "modify the record even if it is owned by someone else")
+	removeCmd.Flags().BoolVarP(&rParams.yes, "yes", "y", false, "delete without asking for confirmation")
 }
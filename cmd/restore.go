@@ -0,0 +1,172 @@
+// Copyright © 2016 Ryan Eschinger <ryanesc@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/ryane/takethe53/awsclient"
+	"github.com/ryane/takethe53/policy"
+	"github.com/ryane/takethe53/recordset"
+	"github.com/spf13/cobra"
+)
+
+type restoreParams struct {
+	zoneName string
+	snapshot string
+	dryRun   bool
+	yes      bool
+}
+
+var rsParams restoreParams
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore <zone_name> <snapshot>",
+	Short: "Return a zone to the state of a snapshot",
+	Long: `Return a zone to the state of a snapshot by applying the minimal set of
+changes. The snapshot is a file, the name of a snapshot in the snapshot
+directory, or "latest". The SOA and NS records at the zone apex are never
+changed.`,
+	ValidArgsFunction: completeArgs(completeZones),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := commandContext()
+
+		if len(args) < 2 {
+			cmd.Usage()
+			os.Exit(1)
+		}
+		rsParams.zoneName = args[0]
+		rsParams.snapshot = args[1]
+
+		cfg := awsConfig()
+		cfg.Cache = nil
+		client := newClientWithConfig(cfg)
+
+		zone, err := client.FindZoneWithContext(ctx, rsParams.zoneName)
+		if err != nil {
+			logger(restoreFields()).Fatal("Error finding zone: ", err)
+		}
+
+		path, err := snapshotDir().Find(zone.Name, rsParams.snapshot)
+		if err != nil {
+			logger(restoreFields()).Fatal(err)
+		}
+		snap, err := recordset.LoadSnapshot(path)
+		if err != nil {
+			logger(restoreFields()).Fatal("Error reading snapshot: ", err)
+		}
+		if !recordset.SameName(snap.ZoneName, zone.Name) {
+			logger(restoreFields()).Fatalf("Snapshot is of zone %s, not %s.", snap.ZoneName, zone.Name)
+		}
+
+		current, err := client.RecordSetsWithContext(ctx, zone)
+		if err != nil {
+			logger(restoreFields()).Fatal("Error listing record sets: ", err)
+		}
+
+		changes := recordset.Diff(
+			recordset.Editable(zone.Name, current),
+			recordset.Editable(zone.Name, snap.RecordSets),
+		)
+		if len(changes) == 0 {
+			fmt.Println("Zone already matches the snapshot.")
+			return
+		}
+
+		printChanges(changes)
+		fmt.Println(changeSummary(changes))
+		if rsParams.dryRun {
+			return
+		}
+		if !rsParams.yes {
+			if names := protectedNames(changes); len(names) > 0 {
+				fmt.Printf("Protected by policy: %s\n", strings.Join(names, ", "))
+			}
+			if !confirm(fmt.Sprintf("Restore %s to the snapshot taken %s?", zone.Name, snap.Time.Local().Format("2006-01-02 15:04:05"))) {
+				fmt.Println("Canceled.")
+				os.Exit(1)
+			}
+			ctx = policy.WithConfirmation(ctx)
+		}
+
+		batches := recordset.Batches(changes, recordset.MaxBatchSize)
+		var submitted []*awsclient.ChangeStatus
+		for i, batch := range batches {
+			change, err := client.ChangeRecordSets(ctx, zone, "restore", batch)
+			if err != nil {
+				if i > 0 {
+					logger(restoreFields()).Fatalf("Error applying batch %d of %d, the first %d were applied: %s", i+1, len(batches), i, err)
+				}
+				logger(restoreFields()).Fatalf("Error applying batch %d of %d: %s", i+1, len(batches), err)
+			}
+			submitted = append(submitted, change)
+		}
+
+		fmt.Print("Pending...  ")
+		waitForChangesSync(ctx, client, submitted, 60, restoreFields())
+	},
+}
+
+// protectedNames returns the names of the changes that the policy protects.
+func protectedNames(changes []*route53.Change) []string {
+	p := changePolicy()
+	if p == nil {
+		return nil
+	}
+
+	var names []string
+	seen := map[string]bool{}
+	for _, c := range changes {
+		name := aws.StringValue(c.ResourceRecordSet.Name)
+		if !seen[name] && p.Protected(name) {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// changeSummary counts the changes of a plan by action.
+func changeSummary(changes []*route53.Change) string {
+	counts := map[string]int{}
+	for _, c := range changes {
+		counts[aws.StringValue(c.Action)]++
+	}
+	return fmt.Sprintf("%d to delete, %d to update, %d to create.",
+		counts[route53.ChangeActionDelete],
+		counts[route53.ChangeActionUpsert],
+		counts[route53.ChangeActionCreate],
+	)
+}
+
+func restoreFields() logrus.Fields {
+	return logrus.Fields{
+		"op":       "restore",
+		"zone":     rsParams.zoneName,
+		"snapshot": rsParams.snapshot,
+	}
+}
+
+func init() {
+	RootCmd.AddCommand(restoreCmd)
+
+	restoreCmd.Flags().BoolVar(&rsParams.dryRun, "dry-run", false, "print the changes without applying them")
+	restoreCmd.Flags().BoolVarP(&rsParams.yes, "yes", "y", false, "apply the changes without asking for confirmation")
+}
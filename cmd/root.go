@@ -16,8 +16,13 @@ package cmd
 
 import (
 	"os"
+	"strings"
+	"time"
 
 	"github.com/Sirupsen/logrus"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/ryane/takethe53/awsclient"
+	"github.com/ryane/takethe53/metrics"
 	"github.com/ryane/takethe53/server"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -25,17 +30,75 @@ import (
 
 var cfgFile string
 
+// clientMetrics is set in server mode to export AWS client metrics.
+var clientMetrics awsclient.Metrics
+
 var RootCmd = &cobra.Command{
 	Use:   "takethe53",
 	Short: "Creates Route53 records.",
 	Long:  `Creates Route53 records.`,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		commandLine = strings.Join(append([]string{cmd.CommandPath()}, args...), " ")
+	},
 	Run: func(cmd *cobra.Command, args []string) {
-		server.Run(viper.GetString("address"))
+		runServer()
 	},
 }
 
+func runServer() {
+	clientMetrics = metrics.New(prometheus.DefaultRegisterer)
+
+	// the server is long running, keep its lookups in memory instead of
+	// sharing the on-disk cache of the CLI
+	clientCfg := awsConfig()
+	clientCfg.Cache = newMemoryCache()
+	client := newClientWithConfig(clientCfg)
+	tracker := server.NewTracker(client, 5*time.Second, viper.GetDuration("change-timeout"))
+	client.AddChangeHandler(tracker.Handler())
+	if n := webhookNotifier(); n != nil {
+		tracker.OnTimeout(func(tc server.TrackedChange) {
+			n.SyncTimeout(tc.ID)
+		})
+	}
+
+	cfg := server.Config{
+		Addr:            viper.GetString("address"),
+		ReadTimeout:     viper.GetDuration("read-timeout"),
+		WriteTimeout:    viper.GetDuration("write-timeout"),
+		IdleTimeout:     viper.GetDuration("idle-timeout"),
+		ShutdownTimeout: viper.GetDuration("shutdown-timeout"),
+		Ready:           client.Ping,
+		Tracker:         tracker,
+		Client:          client,
+	}
+
+	var tlsConfig server.TLSConfig
+	if err := viper.UnmarshalKey("tls", &tlsConfig); err != nil {
+		logrus.Fatal("Error reading tls config: ", err)
+	}
+	cfg.TLS = &tlsConfig
+
+	var authConfig server.AuthConfig
+	if err := viper.UnmarshalKey("auth", &authConfig); err != nil {
+		logrus.Fatal("Error reading auth config: ", err)
+	}
+	if len(authConfig.Tokens) == 0 && !authConfig.AllowAnonymous {
+		logrus.Warn("No API tokens configured, the /v1 API will reject all requests")
+	}
+	cfg.Auth = server.NewAuthenticator(authConfig)
+	if job := driftJob(); job != nil {
+		cfg.Jobs = append(cfg.Jobs, job)
+	}
+
+	if err := server.Run(cfg); err != nil {
+		logrus.Fatal(err)
+	}
+}
+
 func Execute() {
-	if err := RootCmd.Execute(); err != nil {
+	err := RootCmd.Execute()
+	flushNotifications()
+	if err != nil {
 		logrus.Error(err)
 		os.Exit(-1)
 	}
@@ -51,8 +114,92 @@ func init() {
 	RootCmd.PersistentFlags().StringP("log-format", "f", "text", "log format. text|json")
 	viper.BindPFlag("log-format", RootCmd.PersistentFlags().Lookup("log-format"))
 
+	RootCmd.PersistentFlags().String("route53-endpoint", "", "override the Route53 API endpoint (e.g. a local emulator)")
+	viper.BindPFlag("route53-endpoint", RootCmd.PersistentFlags().Lookup("route53-endpoint"))
+
+	RootCmd.PersistentFlags().String("elb-endpoint", "", "override the ELB API endpoint (e.g. a local emulator)")
+	viper.BindPFlag("elb-endpoint", RootCmd.PersistentFlags().Lookup("elb-endpoint"))
+
+	RootCmd.PersistentFlags().String("aws-region", "", "AWS region")
+	viper.BindPFlag("aws-region", RootCmd.PersistentFlags().Lookup("aws-region"))
+
+	RootCmd.PersistentFlags().Bool("insecure-skip-verify", false, "skip TLS certificate verification for AWS endpoints")
+	viper.BindPFlag("insecure-skip-verify", RootCmd.PersistentFlags().Lookup("insecure-skip-verify"))
+
+	RootCmd.PersistentFlags().String("aws-access-key-id", "", "static AWS access key id")
+	viper.BindPFlag("aws-access-key-id", RootCmd.PersistentFlags().Lookup("aws-access-key-id"))
+
+	RootCmd.PersistentFlags().String("aws-secret-access-key", "", "static AWS secret access key")
+	viper.BindPFlag("aws-secret-access-key", RootCmd.PersistentFlags().Lookup("aws-secret-access-key"))
+
+	RootCmd.PersistentFlags().String("aws-session-token", "", "static AWS session token")
+	viper.BindPFlag("aws-session-token", RootCmd.PersistentFlags().Lookup("aws-session-token"))
+
+	RootCmd.PersistentFlags().String("aws-profile", "", "profile of the shared AWS config files to use")
+	viper.BindPFlag("aws-profile", RootCmd.PersistentFlags().Lookup("aws-profile"))
+
+	RootCmd.PersistentFlags().String("aws-role-arn", "", "IAM role to assume, e.g. to reach zones in another account")
+	viper.BindPFlag("aws-role-arn", RootCmd.PersistentFlags().Lookup("aws-role-arn"))
+
+	RootCmd.PersistentFlags().Float64("route53-rate-limit", awsclient.DefaultRoute53RateLimit, "max Route53 requests per second, 0 to disable")
+	viper.BindPFlag("route53-rate-limit", RootCmd.PersistentFlags().Lookup("route53-rate-limit"))
+
+	RootCmd.PersistentFlags().Int("route53-rate-burst", 0, "Route53 request burst size (default is the rate limit)")
+	viper.BindPFlag("route53-rate-burst", RootCmd.PersistentFlags().Lookup("route53-rate-burst"))
+
+	RootCmd.PersistentFlags().Int("max-retries", awsclient.DefaultMaxRetries, "max retries of throttled Route53 requests, 0 to disable")
+	viper.BindPFlag("max-retries", RootCmd.PersistentFlags().Lookup("max-retries"))
+
+	RootCmd.PersistentFlags().Duration("cache-ttl", 0, "cache zone and load balancer lookups for this long, 0 to disable")
+	viper.BindPFlag("cache-ttl", RootCmd.PersistentFlags().Lookup("cache-ttl"))
+
+	RootCmd.PersistentFlags().String("cache-dir", "", "directory for the lookup cache of the CLI, the server caches in memory (default is $HOME/.takethe53/cache)")
+	viper.BindPFlag("cache-dir", RootCmd.PersistentFlags().Lookup("cache-dir"))
+
+	RootCmd.PersistentFlags().String("owner-id", "", "record ownership in TXT records and only modify records with this owner. Without it only unowned records are modified")
+	viper.BindPFlag("owner-id", RootCmd.PersistentFlags().Lookup("owner-id"))
+
+	RootCmd.PersistentFlags().String("journal", "", "change journal location, a file or s3://bucket/prefix, \"off\" to disable (default is $HOME/.takethe53/journal.jsonl)")
+	viper.BindPFlag("journal", RootCmd.PersistentFlags().Lookup("journal"))
+
+	RootCmd.PersistentFlags().String("snapshot-dir", "", "directory for zone snapshots (default is $HOME/.takethe53/snapshots)")
+	viper.BindPFlag("snapshot-dir", RootCmd.PersistentFlags().Lookup("snapshot-dir"))
+
+	RootCmd.PersistentFlags().Bool("confirm", false, "confirm changes to names the policy protects")
+	viper.BindPFlag("confirm", RootCmd.PersistentFlags().Lookup("confirm"))
+
+	RootCmd.PersistentFlags().String("audit-log", "", "write an audit record of every change to this file, \"stdout\", \"syslog\" or syslog://host:port")
+	viper.BindPFlag("audit-log", RootCmd.PersistentFlags().Lookup("audit-log"))
+
+	RootCmd.PersistentFlags().Duration("webhook-flush-timeout", 30*time.Second, "how long to wait for webhook deliveries before exiting")
+	viper.BindPFlag("webhook-flush-timeout", RootCmd.PersistentFlags().Lookup("webhook-flush-timeout"))
+
 	RootCmd.Flags().String("address", ":9053", "the address to listen on")
 	viper.BindPFlag("address", RootCmd.Flags().Lookup("address"))
+
+	RootCmd.Flags().Duration("read-timeout", 10*time.Second, "max duration for reading a request")
+	viper.BindPFlag("read-timeout", RootCmd.Flags().Lookup("read-timeout"))
+
+	RootCmd.Flags().Duration("write-timeout", 30*time.Second, "max duration for writing a response")
+	viper.BindPFlag("write-timeout", RootCmd.Flags().Lookup("write-timeout"))
+
+	RootCmd.Flags().Duration("idle-timeout", 2*time.Minute, "max duration a keep-alive connection stays idle")
+	viper.BindPFlag("idle-timeout", RootCmd.Flags().Lookup("idle-timeout"))
+
+	RootCmd.Flags().Duration("shutdown-timeout", 2*time.Minute, "how long to wait for requests and changes in flight on shutdown")
+	viper.BindPFlag("shutdown-timeout", RootCmd.Flags().Lookup("shutdown-timeout"))
+
+	RootCmd.Flags().Duration("change-timeout", 10*time.Minute, "how long to track a change until it is INSYNC")
+	viper.BindPFlag("change-timeout", RootCmd.Flags().Lookup("change-timeout"))
+
+	RootCmd.Flags().String("drift-spec", "", "periodically compare Route53 with this YAML spec")
+	viper.BindPFlag("drift-spec", RootCmd.Flags().Lookup("drift-spec"))
+
+	RootCmd.Flags().Duration("drift-interval", 15*time.Minute, "how often to check for drift")
+	viper.BindPFlag("drift-interval", RootCmd.Flags().Lookup("drift-interval"))
+
+	RootCmd.Flags().String("drift-webhook", "", "post drift reports to this URL")
+	viper.BindPFlag("drift-webhook", RootCmd.Flags().Lookup("drift-webhook"))
 }
 
 // initConfig reads in config file and ENV variables if set.
@@ -64,6 +211,7 @@ func initConfig() {
 	viper.SetConfigName(".takethe53") // name of config file (without extension)
 	viper.AddConfigPath("$HOME")      // adding home directory as first search path
 	viper.SetEnvPrefix("takethe53")
+	viper.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
 	viper.AutomaticEnv() // read in environment variables that match
 
 	// If a config file is found, read it in.
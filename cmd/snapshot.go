@@ -0,0 +1,108 @@
+// Copyright © 2016 Ryan Eschinger <ryanesc@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/ryane/takethe53/recordset"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+type snapshotParams struct {
+	zoneName string
+	output   string
+}
+
+var sParams snapshotParams
+
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot <zone_name>",
+	Short: "Save every record set of a zone",
+	Long: `Save every record set of a zone as a JSON document. Snapshots are kept in
+the snapshot directory, one file per snapshot, and can be restored with
+"restore".`,
+	ValidArgsFunction: completeArgs(completeZones),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := commandContext()
+
+		if len(args) < 1 {
+			cmd.Usage()
+			os.Exit(1)
+		}
+		sParams.zoneName = args[0]
+
+		cfg := awsConfig()
+		cfg.Cache = nil
+		client := newClientWithConfig(cfg)
+
+		zone, err := client.FindZoneWithContext(ctx, sParams.zoneName)
+		if err != nil {
+			logger(snapshotFields()).Fatal("Error finding zone: ", err)
+		}
+
+		rrsets, err := client.RecordSetsWithContext(ctx, zone)
+		if err != nil {
+			logger(snapshotFields()).Fatal("Error listing record sets: ", err)
+		}
+
+		snap := recordset.NewSnapshot(zone.ID, zone.Name, rrsets)
+
+		if sParams.output == "-" {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(snap); err != nil {
+				logger(snapshotFields()).Fatal("Error writing snapshot: ", err)
+			}
+			return
+		}
+
+		path := sParams.output
+		if path == "" {
+			path = snapshotDir().Path(snap)
+		}
+		if err := snap.Save(path); err != nil {
+			logger(snapshotFields()).Fatal("Error writing snapshot: ", err)
+		}
+
+		fmt.Printf("Saved %d record sets to %s\n", len(rrsets), path)
+	},
+}
+
+func snapshotDir() recordset.SnapshotDir {
+	dir := viper.GetString("snapshot-dir")
+	if dir == "" {
+		dir = filepath.Join(os.Getenv("HOME"), ".takethe53", "snapshots")
+	}
+	return recordset.SnapshotDir(dir)
+}
+
+func snapshotFields() logrus.Fields {
+	return logrus.Fields{
+		"op":   "snapshot",
+		"zone": sParams.zoneName,
+	}
+}
+
+func init() {
+	RootCmd.AddCommand(snapshotCmd)
+
+	snapshotCmd.Flags().StringVarP(&sParams.output, "output", "o", "", "write the snapshot to this file instead of the snapshot directory, - for stdout")
+}
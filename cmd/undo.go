@@ -0,0 +1,117 @@
+// Copyright © 2016 Ryan Eschinger <ryanesc@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/ryane/takethe53/awsclient"
+	"github.com/ryane/takethe53/journal"
+	"github.com/ryane/takethe53/policy"
+	"github.com/spf13/cobra"
+)
+
+type undoParams struct {
+	entryID string
+	dryRun  bool
+	yes     bool
+}
+
+var uParams undoParams
+
+var undoCmd = &cobra.Command{
+	Use:   "undo <entry>",
+	Short: "Revert a change recorded in the change journal",
+	Long: `Revert a change recorded in the change journal by submitting the inverse
+change batch. The entry can be given as any unique prefix of its ID. The undo
+fails without changing anything if the records were modified since. Entries
+whose replaced records could not be read when they were recorded can't be
+undone.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := commandContext()
+
+		if len(args) < 1 {
+			cmd.Usage()
+			os.Exit(1)
+		}
+		uParams.entryID = args[0]
+
+		store := journalStore()
+		if store == nil {
+			logger(undoFields()).Fatal("The change journal is disabled.")
+		}
+
+		entry, err := journal.Find(ctx, store, uParams.entryID)
+		if err != nil {
+			logger(undoFields()).Fatal("Error finding journal entry: ", err)
+		}
+
+		if entry.Incomplete {
+			logger(undoFields()).Fatal(journal.ErrIncomplete)
+		}
+
+		changes := entry.Inverse()
+		if len(changes) == 0 {
+			logger(undoFields()).Fatal(journal.ErrNothingToUndo)
+		}
+
+		printChanges(changes)
+		if uParams.dryRun {
+			return
+		}
+		if !uParams.yes {
+			if names := protectedNames(changes); len(names) > 0 {
+				fmt.Printf("Protected by policy: %s\n", strings.Join(names, ", "))
+			}
+			if !confirm(fmt.Sprintf("Revert %s in %s?", entry.ID, entry.ZoneName)) {
+				fmt.Println("Canceled.")
+				os.Exit(1)
+			}
+			ctx = policy.WithConfirmation(ctx)
+		}
+
+		client := newClient()
+		zone := &awsclient.Zone{ID: entry.ZoneID, Name: entry.ZoneName}
+		change, err := client.ChangeRecordSets(ctx, zone, journal.OpUndo, changes)
+		if err != nil {
+			if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == route53.ErrCodeInvalidChangeBatch {
+				logger(undoFields()).Fatal("The records were changed after this entry and can't be reverted automatically: ", err)
+			}
+			logger(undoFields()).Fatal("Error reverting change: ", err)
+		}
+
+		fmt.Print("Pending...  ")
+		waitForChangeSync(ctx, client, change, 60, undoFields())
+	},
+}
+
+func undoFields() logrus.Fields {
+	return logrus.Fields{
+		"op":    journal.OpUndo,
+		"entry": uParams.entryID,
+	}
+}
+
+func init() {
+	RootCmd.AddCommand(undoCmd)
+
+	undoCmd.Flags().BoolVar(&uParams.dryRun, "dry-run", false, "print the inverse change batch without submitting it")
+	undoCmd.Flags().BoolVarP(&uParams.yes, "yes", "y", false, "revert the change without asking for confirmation")
+}
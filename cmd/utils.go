@@ -15,46 +15,468 @@
 package cmd
 
 import (
+	"bufio"
+	"context"
 	"fmt"
+	"os"
+	"os/signal"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"text/tabwriter"
 	"time"
 
 	"github.com/Sirupsen/logrus"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/route53"
 	"github.com/briandowns/spinner"
+	"github.com/mattn/go-isatty"
+	"github.com/ryane/takethe53/auditlog"
 	"github.com/ryane/takethe53/awsclient"
+	"github.com/ryane/takethe53/journal"
+	"github.com/ryane/takethe53/notify"
+	"github.com/ryane/takethe53/policy"
+	"github.com/ryane/takethe53/server"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
-func waitForChangeSync(client *awsclient.AWSClient, change *awsclient.ChangeStatus, timeout int, fields logrus.Fields) {
-	if change.Status == awsclient.ChangeStatusInSync {
-		return
+func newClient() *awsclient.AWSClient {
+	return newClientWithConfig(awsConfig())
+}
+
+func newClientWithConfig(cfg awsclient.Config) *awsclient.AWSClient {
+	client := awsclient.NewWithConfig(cfg)
+	if store := journalStore(); store != nil {
+		client.AddChangeHandler(journal.Handler(store, currentUser(), func(err error) {
+			logrus.Warn("Error writing journal entry: ", err)
+		}))
+	}
+	if p := changePolicy(); p != nil {
+		client.AddChangeValidator(p.Validator())
+	}
+	if n := webhookNotifier(); n != nil {
+		client.AddChangeHandler(n.Handler())
 	}
+	if location := viper.GetString("audit-log"); location != "" {
+		sink, err := auditlog.Open(location)
+		if err != nil {
+			logrus.Fatal("Error opening audit log: ", err)
+		}
+		audit := auditlog.New(sink)
+		audit.Caller = auditCaller(client)
+		audit.Command = auditCommand
+		client.AddChangeHandler(audit.Handler(func(err error) {
+			logrus.Error("Error writing audit log: ", err)
+		}))
+	}
+	return client
+}
 
-	s := spinner.New(spinner.CharSets[9], 100*time.Millisecond) // Build our new spinner
-	s.Start()
+// commandLine is the command being run, without flags. It is set before the
+// command runs.
+var commandLine string
 
-	id := change.ID
-	c := make(chan string, 1)
+// auditCommand returns the API request of a change made by the server, or the
+// command line.
+func auditCommand(ctx context.Context) string {
+	if req, ok := server.RequestFromContext(ctx); ok {
+		return req
+	}
+	return commandLine
+}
 
-	go func() {
-		for {
-			time.Sleep(2 * time.Second)
-			status, err := client.GetChangeStatus(id)
+// auditCaller returns a function that identifies the caller of a change by
+// the client's AWS principal, looked up once, the local user and the server
+// API token.
+func auditCaller(client *awsclient.AWSClient) func(ctx context.Context) auditlog.Caller {
+	var (
+		once     sync.Once
+		identity *awsclient.Identity
+	)
+	return func(ctx context.Context) auditlog.Caller {
+		once.Do(func() {
+			// the change is already submitted, look up the identity even if
+			// ctx was canceled
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			var err error
+			identity, err = client.CallerIdentityWithContext(ctx)
 			if err != nil {
-				logger(fields).Fatal("Error checking status: ", err)
-			}
-			if status.Status == awsclient.ChangeStatusInSync {
-				c <- status.Status
+				logrus.Warn("Error looking up AWS caller identity: ", err)
 			}
+		})
+
+		caller := auditlog.Caller{User: currentUser()}
+		if identity != nil {
+			caller.ARN = identity.ARN
+			caller.Account = identity.Account
+		}
+		if token, ok := server.TokenFromContext(ctx); ok {
+			caller.Token = token.Name
+		}
+		return caller
+	}
+}
+
+// changePolicy returns the policy in the config file, or nil if there is
+// none.
+func changePolicy() *policy.Policy {
+	if !viper.IsSet("policy") {
+		return nil
+	}
+
+	var cfg policy.Config
+	if err := viper.UnmarshalKey("policy", &cfg); err != nil {
+		logrus.Fatal("Error reading policy config: ", err)
+	}
+	return policy.New(cfg)
+}
+
+// notifier posts to the webhooks in the config file. Use webhookNotifier.
+var notifier *notify.Notifier
+
+// webhookNotifier returns the notifier for the configured webhooks, or nil
+// if there are none.
+func webhookNotifier() *notify.Notifier {
+	if notifier != nil {
+		return notifier
+	}
+
+	var hooks []*notify.Webhook
+	if err := viper.UnmarshalKey("webhooks", &hooks); err != nil {
+		logrus.Fatal("Error reading webhooks config: ", err)
+	}
+	if len(hooks) == 0 {
+		return nil
+	}
+
+	notifier = notify.New(hooks, currentUser())
+	return notifier
+}
+
+// flushNotifications waits for webhook deliveries before the process exits.
+func flushNotifications() {
+	if notifier == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), viper.GetDuration("webhook-flush-timeout"))
+	defer cancel()
+	if err := notifier.Close(ctx); err != nil {
+		logrus.Warn("Some webhooks were not delivered: ", err)
+	}
+}
+
+func awsConfig() awsclient.Config {
+	// 0 disables the limiter and retries on the command line, the client
+	// config takes a negative value for that and uses the defaults for 0
+	rateLimit := viper.GetFloat64("route53-rate-limit")
+	if rateLimit == 0 {
+		rateLimit = -1
+	}
+	maxRetries := viper.GetInt("max-retries")
+	if maxRetries == 0 {
+		maxRetries = -1
+	}
+
+	return awsclient.Config{
+		Route53Endpoint:    viper.GetString("route53-endpoint"),
+		ELBEndpoint:        viper.GetString("elb-endpoint"),
+		Region:             viper.GetString("aws-region"),
+		InsecureSkipVerify: viper.GetBool("insecure-skip-verify"),
+		AccessKeyID:        viper.GetString("aws-access-key-id"),
+		SecretAccessKey:    viper.GetString("aws-secret-access-key"),
+		SessionToken:       viper.GetString("aws-session-token"),
+		Profile:            viper.GetString("aws-profile"),
+		RoleARN:            viper.GetString("aws-role-arn"),
+		Route53RateLimit:   rateLimit,
+		Route53RateBurst:   viper.GetInt("route53-rate-burst"),
+		MaxRetries:         maxRetries,
+		Cache:              newFileCache(),
+		OwnerID:            viper.GetString("owner-id"),
+		Metrics:            clientMetrics,
+	}
+}
+
+// accountConfig returns the client config for another account, without the
+// lookup cache. profile replaces the configured credentials and roleARN is
+// assumed with them. With neither it is the regular config.
+func accountConfig(profile, roleARN string) awsclient.Config {
+	cfg := awsConfig()
+	cfg.Cache = nil
+	if profile != "" {
+		cfg.Profile = profile
+		cfg.AccessKeyID, cfg.SecretAccessKey, cfg.SessionToken = "", "", ""
+		cfg.RoleARN = ""
+	}
+	if roleARN != "" {
+		cfg.RoleARN = roleARN
+	}
+	return cfg
+}
+
+func aliasOptions(cmd *cobra.Command) awsclient.AliasOptions {
+	opts := awsclient.AliasOptions{}
+	opts.Force, _ = cmd.Flags().GetBool("force")
+	if cmd.Flags().Lookup("if-not-exists") != nil {
+		opts.IfNotExists, _ = cmd.Flags().GetBool("if-not-exists")
+	}
+	if cmd.Flags().Lookup("expect-target") != nil {
+		opts.ExpectTarget, _ = cmd.Flags().GetString("expect-target")
+	}
+	return opts
+}
+
+// newFileCache returns the on-disk lookup cache, or nil if it is disabled.
+func newFileCache() awsclient.Cache {
+	return fileCache(viper.GetDuration("cache-ttl"))
+}
+
+// newMemoryCache returns an in-memory lookup cache, or nil if it is
+// disabled.
+func newMemoryCache() awsclient.Cache {
+	ttl := viper.GetDuration("cache-ttl")
+	if ttl <= 0 {
+		return nil
+	}
+	return awsclient.NewMemoryCache(ttl)
+}
+
+// fileCache returns the on-disk lookup cache with entries that are valid for
+// ttl, or nil if ttl is not positive.
+func fileCache(ttl time.Duration) awsclient.Cache {
+	if ttl <= 0 {
+		return nil
+	}
+
+	dir := viper.GetString("cache-dir")
+	if dir == "" {
+		dir = filepath.Join(os.Getenv("HOME"), ".takethe53", "cache")
+	}
+
+	cache, err := awsclient.NewFileCache(dir, ttl)
+	if err != nil {
+		logrus.Warn("Lookup cache disabled: ", err)
+		return nil
+	}
+
+	return cache
+}
+
+// journalStore returns the change journal, or nil if it is disabled.
+func journalStore() journal.Store {
+	location := viper.GetString("journal")
+	switch location {
+	case "off":
+		return nil
+	case "":
+		location = filepath.Join(os.Getenv("HOME"), ".takethe53", "journal.jsonl")
+	}
+
+	store, err := journal.NewStore(location)
+	if err != nil {
+		logrus.Warn("Change journal disabled: ", err)
+		return nil
+	}
+
+	return store
+}
+
+func currentUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
+
+// interactive reports whether stdin is a terminal that can answer prompts.
+func interactive() bool {
+	fd := os.Stdin.Fd()
+	return isatty.IsTerminal(fd) || isatty.IsCygwinTerminal(fd)
+}
+
+// confirm asks a yes/no question on stdin and defaults to no. Without a
+// terminal the answer is no.
+func confirm(question string) bool {
+	if !interactive() {
+		fmt.Printf("%s Not running in a terminal, use --yes to confirm.\n", question)
+		return false
+	}
+
+	fmt.Printf("%s [y/N] ", question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// confirmRecordChange shows the record sets that are about to be deleted or
+// overwritten and asks before changing them, unless yes is set. Without a
+// terminal, records the policy protects are only changed with yes. The
+// returned context confirms the change for the policy if the user agreed to
+// change a protected record.
+func confirmRecordChange(ctx context.Context, question string, zone *awsclient.Zone, rrsets []*route53.ResourceRecordSet, yes bool) (context.Context, bool) {
+	name := aws.StringValue(rrsets[0].Name)
+	protected := false
+	if p := changePolicy(); p != nil {
+		protected = p.Protected(name)
+	}
+
+	if yes {
+		return ctx, true
+	}
+	if !interactive() {
+		if protected {
+			fmt.Printf("%s is protected by policy. Not running in a terminal, use --yes to confirm.\n", name)
+			return ctx, false
+		}
+		return ctx, true
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Zone ID:\t%s\n", zone.ID)
+	fmt.Fprintf(w, "Name:\t%s\n", name)
+	for _, rrs := range rrsets {
+		fmt.Fprintf(w, "Type:\t%s\n", aws.StringValue(rrs.Type))
+		if rrs.AliasTarget != nil {
+			fmt.Fprintf(w, "Target:\tALIAS %s (hosted zone %s)\n", aws.StringValue(rrs.AliasTarget.DNSName), aws.StringValue(rrs.AliasTarget.HostedZoneId))
+		} else {
+			fmt.Fprintf(w, "Values:\t%s (TTL %d)\n", recordSetValue(rrs), aws.Int64Value(rrs.TTL))
 		}
+	}
+	w.Flush()
+	if protected {
+		fmt.Println("This record is protected by policy.")
+	}
+
+	if !confirm(question) {
+		return ctx, false
+	}
+	if protected {
+		ctx = policy.WithConfirmation(ctx)
+	}
+	return ctx, true
+}
+
+// printChanges prints a change batch, one record set per line.
+func printChanges(changes []*route53.Change) {
+	for _, change := range changes {
+		rrs := change.ResourceRecordSet
+		fmt.Printf("%-6s %s %s %s\n", aws.StringValue(change.Action), aws.StringValue(rrs.Name), aws.StringValue(rrs.Type), recordSetValue(rrs))
+	}
+}
+
+// recordSetValue formats the alias target or the values of a record set.
+func recordSetValue(rrs *route53.ResourceRecordSet) string {
+	if rrs.AliasTarget != nil {
+		return "ALIAS " + aws.StringValue(rrs.AliasTarget.DNSName)
+	}
+
+	var values []string
+	for _, rr := range rrs.ResourceRecords {
+		values = append(values, aws.StringValue(rr.Value))
+	}
+	return strings.Join(values, " ")
+}
+
+// commandContext returns a context that is canceled when the process receives
+// SIGINT or SIGTERM.
+func commandContext() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigs
+		signal.Stop(sigs)
+		cancel()
 	}()
 
+	if viper.GetBool("confirm") {
+		return policy.WithConfirmation(ctx)
+	}
+	return ctx
+}
+
+func waitForChangeSync(ctx context.Context, client *awsclient.AWSClient, change *awsclient.ChangeStatus, timeout int, fields logrus.Fields) {
+	waitForChangesSync(ctx, client, []*awsclient.ChangeStatus{change}, timeout, fields)
+}
+
+// waitForChangesSync waits until every change is INSYNC, at most timeout
+// seconds in total.
+func waitForChangesSync(ctx context.Context, client *awsclient.AWSClient, changes []*awsclient.ChangeStatus, timeout int, fields logrus.Fields) {
+	var pending []string
+	for _, change := range changes {
+		if change.Status != awsclient.ChangeStatusInSync {
+			pending = append(pending, change.ID)
+		}
+	}
+	if len(pending) == 0 {
+		return
+	}
+
+	s := spinner.New(spinner.CharSets[9], 100*time.Millisecond) // Build our new spinner
+	s.Start()
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second*time.Duration(timeout))
+	defer cancel()
+
+	errs := make([]error, len(pending))
+	var wg sync.WaitGroup
+	for i, id := range pending {
+		wg.Add(1)
+		go func(i int, id string) {
+			defer wg.Done()
+			_, errs[i] = client.WaitUntilInSync(ctx, id, 2*time.Second)
+		}(i, id)
+	}
+	wg.Wait()
+	s.Stop()
+
+	// unsynced are the changes still pending when waiting stopped, err the
+	// reason, preferring errors other than the deadline or a cancelation
+	var unsynced []string
+	var err error
+	for i, e := range errs {
+		if e == nil {
+			continue
+		}
+		unsynced = append(unsynced, pending[i])
+		if err == nil || (e != context.DeadlineExceeded && e != context.Canceled) {
+			err = e
+		}
+	}
+
 	var message string
-	select {
-	case <-c:
+	switch err {
+	case nil:
 		message = "Done."
-	case <-time.After(time.Second * time.Duration(timeout)):
-		message = fmt.Sprintf("It is taking longer than expected to synchronize the change to all Route53 DNS servers. You can check the status with the AWS CLI.\n\naws route53 get-change --id %s\n", id)
+	case context.DeadlineExceeded:
+		if n := webhookNotifier(); n != nil {
+			for _, id := range unsynced {
+				n.SyncTimeout(id)
+			}
+		}
+		message = fmt.Sprintf("It is taking longer than expected to synchronize the change to all Route53 DNS servers. You can check the status with the AWS CLI.\n\n%s", getChangeCommands(unsynced))
+	case context.Canceled:
+		message = fmt.Sprintf("Canceled. The change was submitted and will still be synchronized. You can check the status with the AWS CLI.\n\n%s", getChangeCommands(unsynced))
+	default:
+		fmt.Println()
+		logger(fields).Fatal("Error checking status: ", err)
 	}
 
-	s.Stop()
 	fmt.Printf(" %s\n", message)
 }
+
+// getChangeCommands returns the AWS CLI commands that show the status of
+// changes, one per line.
+func getChangeCommands(ids []string) string {
+	var commands string
+	for _, id := range ids {
+		commands += fmt.Sprintf("aws route53 get-change --id %s\n", id)
+	}
+	return commands
+}
@@ -0,0 +1,228 @@
+// Package dangling finds alias and CNAME records whose targets no longer
+// exist. Anyone who creates a resource with the same name, like an S3 bucket,
+// could take over such a record.
+package dangling
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/ryane/takethe53/awsclient"
+)
+
+// Target kinds.
+const (
+	LoadBalancer = "load-balancer"
+	Distribution = "cloudfront"
+	Bucket       = "s3"
+)
+
+// Finding is a record whose target does not exist.
+type Finding struct {
+	ZoneID    string                     `json:"zone_id"`
+	ZoneName  string                     `json:"zone_name"`
+	Name      string                     `json:"name"`
+	Type      string                     `json:"type"`
+	Target    string                     `json:"target"`
+	Kind      string                     `json:"kind"`
+	RecordSet *route53.ResourceRecordSet `json:"record_set"`
+}
+
+// InventoryFunc returns the inventory of a region. Region is "" for global
+// resources like CloudFront distributions and S3 buckets.
+type InventoryFunc func(ctx context.Context, region string) (*awsclient.Inventory, error)
+
+var (
+	// s3-website-us-east-1.amazonaws.com, s3-website.eu-central-1.amazonaws.com
+	s3WebsitePattern = regexp.MustCompile(`^s3-website[.-][a-z0-9-]+\.amazonaws\.com(?:\.cn)?$`)
+	// bucket.s3.amazonaws.com, bucket.s3-website-us-east-1.amazonaws.com, ...
+	s3BucketPattern = regexp.MustCompile(`^(.+)\.s3[.-]?[a-z0-9.-]*\.amazonaws\.com(?:\.cn)?$`)
+)
+
+// Audit checks every alias and CNAME record in every zone.
+func Audit(ctx context.Context, client *awsclient.AWSClient, inventory InventoryFunc) ([]*Finding, error) {
+	zones, err := client.ZonesWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &auditor{inventory: inventory, inventories: map[string]*index{}}
+	findings := []*Finding{}
+	for _, zone := range zones {
+		rrsets, err := client.RecordSetsWithContext(ctx, zone)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", zone.Name, err)
+		}
+
+		for _, rrs := range rrsets {
+			f, err := a.check(ctx, rrs)
+			if err != nil {
+				return nil, err
+			}
+			if f != nil {
+				f.ZoneID = zone.ID
+				f.ZoneName = zone.Name
+				findings = append(findings, f)
+			}
+		}
+	}
+
+	return findings, nil
+}
+
+// RemovePlan returns the change batches, by zone ID, that delete the records
+// of the findings.
+func RemovePlan(findings []*Finding) map[string]*route53.ChangeBatch {
+	plan := map[string]*route53.ChangeBatch{}
+	for _, f := range findings {
+		batch, ok := plan[f.ZoneID]
+		if !ok {
+			batch = &route53.ChangeBatch{
+				Comment: aws.String("Remove dangling records from " + f.ZoneName),
+			}
+			plan[f.ZoneID] = batch
+		}
+		batch.Changes = append(batch.Changes, &route53.Change{
+			Action:            aws.String(route53.ChangeActionDelete),
+			ResourceRecordSet: f.RecordSet,
+		})
+	}
+	return plan
+}
+
+// MarshalPlan returns a change batch of RemovePlan as JSON for
+// "aws route53 change-resource-record-sets --change-batch", which rejects the
+// null values the SDK types marshal for fields that are not set.
+func MarshalPlan(batch *route53.ChangeBatch) ([]byte, error) {
+	data, err := json.Marshal(batch)
+	if err != nil {
+		return nil, err
+	}
+
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(dropNulls(v), "", "  ")
+}
+
+// dropNulls removes null object fields from decoded JSON.
+func dropNulls(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, field := range v {
+			if field == nil {
+				delete(v, k)
+				continue
+			}
+			v[k] = dropNulls(field)
+		}
+	case []interface{}:
+		for i, elem := range v {
+			v[i] = dropNulls(elem)
+		}
+	}
+	return v
+}
+
+type index struct {
+	loadBalancers map[string]bool
+	distributions map[string]bool
+	buckets       map[string]bool
+}
+
+type auditor struct {
+	inventory   InventoryFunc
+	inventories map[string]*index
+}
+
+func (a *auditor) check(ctx context.Context, rrs *route53.ResourceRecordSet) (*Finding, error) {
+	var target string
+	switch {
+	case rrs.AliasTarget != nil:
+		target = aws.StringValue(rrs.AliasTarget.DNSName)
+	case aws.StringValue(rrs.Type) == route53.RRTypeCname && len(rrs.ResourceRecords) > 0:
+		target = aws.StringValue(rrs.ResourceRecords[0].Value)
+	default:
+		return nil, nil
+	}
+	target = normalize(target)
+
+	var kind, region, key string
+	lbRegion, isLoadBalancer := awsclient.LoadBalancerRegion(target)
+	switch {
+	case isLoadBalancer:
+		kind, region, key = LoadBalancer, lbRegion, strings.TrimPrefix(target, "dualstack.")
+	case strings.HasSuffix(target, ".cloudfront.net"):
+		kind, key = Distribution, target
+	case rrs.AliasTarget != nil && s3WebsitePattern.MatchString(target):
+		// S3 website aliases only work for a bucket named like the record
+		kind, key = Bucket, normalize(aws.StringValue(rrs.Name))
+	case s3BucketPattern.MatchString(target):
+		kind, key = Bucket, s3BucketPattern.FindStringSubmatch(target)[1]
+	default:
+		return nil, nil
+	}
+
+	idx, err := a.index(ctx, region)
+	if err != nil {
+		return nil, err
+	}
+
+	var exists bool
+	switch kind {
+	case LoadBalancer:
+		exists = idx.loadBalancers[key]
+	case Distribution:
+		exists = idx.distributions[key]
+	case Bucket:
+		exists = idx.buckets[key]
+	}
+	if exists {
+		return nil, nil
+	}
+
+	return &Finding{
+		Name:      aws.StringValue(rrs.Name),
+		Type:      aws.StringValue(rrs.Type),
+		Target:    target,
+		Kind:      kind,
+		RecordSet: rrs,
+	}, nil
+}
+
+func (a *auditor) index(ctx context.Context, region string) (*index, error) {
+	if idx, ok := a.inventories[region]; ok {
+		return idx, nil
+	}
+
+	inv, err := a.inventory(ctx, region)
+	if err != nil {
+		return nil, fmt.Errorf("inventory %s: %s", region, err)
+	}
+
+	idx := &index{
+		loadBalancers: set(inv.LoadBalancers),
+		distributions: set(inv.Distributions),
+		buckets:       set(inv.Buckets),
+	}
+	a.inventories[region] = idx
+	return idx, nil
+}
+
+func set(names []string) map[string]bool {
+	s := map[string]bool{}
+	for _, name := range names {
+		s[normalize(name)] = true
+	}
+	return s
+}
+
+func normalize(name string) string {
+	return strings.ToLower(strings.TrimSuffix(name, "."))
+}
@@ -0,0 +1,117 @@
+package dangling
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/ryane/takethe53/awsclient"
+	"github.com/ryane/takethe53/awsclient/fake"
+	"github.com/stretchr/testify/assert"
+)
+
+func alias(name, target string) *route53.ResourceRecordSet {
+	return &route53.ResourceRecordSet{
+		Name: aws.String(name),
+		Type: aws.String(route53.RRTypeA),
+		AliasTarget: &route53.AliasTarget{
+			DNSName:              aws.String(target),
+			HostedZoneId:         aws.String("Z35SXDOTRQ7X7K"),
+			EvaluateTargetHealth: aws.Bool(false),
+		},
+	}
+}
+
+func cname(name, target string) *route53.ResourceRecordSet {
+	return &route53.ResourceRecordSet{
+		Name:            aws.String(name),
+		Type:            aws.String(route53.RRTypeCname),
+		TTL:             aws.Int64(300),
+		ResourceRecords: []*route53.ResourceRecord{{Value: aws.String(target)}},
+	}
+}
+
+func TestAudit(t *testing.T) {
+	r53 := fake.NewRoute53()
+	zoneID, _ := r53.AddZone("example.com")
+	otherID, _ := r53.AddZone("example.org")
+
+	records := []*route53.ResourceRecordSet{
+		alias("web.example.com.", "dualstack.web-1.us-east-1.elb.amazonaws.com."),
+		alias("gone.example.com.", "gone-1.us-east-1.elb.amazonaws.com."),
+		alias("nlb.example.com.", "nlb-1.elb.eu-west-1.amazonaws.com."),
+		alias("cdn.example.com.", "d111111abcdef8.cloudfront.net."),
+		cname("oldcdn.example.com.", "d222222abcdef8.cloudfront.net"),
+		alias("www.example.com.", "s3-website-us-east-1.amazonaws.com."),
+		alias("assets.example.com.", "s3-website-us-east-1.amazonaws.com."),
+		cname("files.example.com.", "files.example.com.s3.amazonaws.com"),
+		cname("blog.example.com.", "example.github.io"),
+	}
+	for _, rrs := range records {
+		assert.Nil(t, r53.AddRecordSet(zoneID, rrs))
+	}
+	r53.AddRecordSet(otherID, cname("app.example.org.", "app-1.eu-west-1.elb.amazonaws.com"))
+
+	var regions []string
+	inventory := func(ctx context.Context, region string) (*awsclient.Inventory, error) {
+		regions = append(regions, region)
+		switch region {
+		case "us-east-1":
+			return &awsclient.Inventory{LoadBalancers: []string{"web-1.us-east-1.elb.amazonaws.com"}}, nil
+		case "eu-west-1":
+			return &awsclient.Inventory{LoadBalancers: []string{"nlb-1.elb.eu-west-1.amazonaws.com"}}, nil
+		default:
+			return &awsclient.Inventory{
+				Distributions: []string{"d111111abcdef8.cloudfront.net"},
+				Buckets:       []string{"www.example.com"},
+			}, nil
+		}
+	}
+
+	findings, err := Audit(context.Background(), awsclient.NewWithServices(r53, fake.NewELB()), inventory)
+	assert.Nil(t, err)
+
+	dangling := map[string]string{}
+	for _, f := range findings {
+		dangling[f.Name] = f.Kind
+	}
+	assert.Equal(t, map[string]string{
+		"gone.example.com.":   LoadBalancer,
+		"oldcdn.example.com.": Distribution,
+		"assets.example.com.": Bucket,
+		"files.example.com.":  Bucket,
+		"app.example.org.":    LoadBalancer,
+	}, dangling)
+	assert.Equal(t, 3, len(regions), "every inventory is loaded once")
+
+	plan := RemovePlan(findings)
+	assert.Equal(t, 2, len(plan))
+	assert.Equal(t, 4, len(plan[zoneID].Changes))
+	assert.Equal(t, route53.ChangeActionDelete, aws.StringValue(plan[otherID].Changes[0].Action))
+
+	// the plan applies cleanly
+	for id, batch := range plan {
+		_, err := r53.ChangeResourceRecordSets(&route53.ChangeResourceRecordSetsInput{HostedZoneId: aws.String(id), ChangeBatch: batch})
+		assert.Nil(t, err)
+	}
+	findings, err = Audit(context.Background(), awsclient.NewWithServices(r53, fake.NewELB()), inventory)
+	assert.Nil(t, err)
+	assert.Empty(t, findings)
+}
+
+func TestMarshalPlan(t *testing.T) {
+	plan := RemovePlan([]*Finding{
+		{ZoneID: "Z1", ZoneName: "example.com.", RecordSet: alias("gone.example.com.", "gone-1.us-east-1.elb.amazonaws.com")},
+		{ZoneID: "Z1", ZoneName: "example.com.", RecordSet: cname("cdn.example.com.", "d111111abcdef8.cloudfront.net")},
+	})
+
+	data, err := MarshalPlan(plan["Z1"])
+	assert.Nil(t, err)
+	assert.NotContains(t, string(data), "null")
+
+	var batch route53.ChangeBatch
+	assert.Nil(t, json.Unmarshal(data, &batch))
+	assert.Equal(t, plan["Z1"], &batch)
+}
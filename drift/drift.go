@@ -0,0 +1,240 @@
+// Package drift compares live Route53 records with a desired-state spec and
+// reports records that were changed out-of-band.
+package drift
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/ryane/takethe53/awsclient"
+	"github.com/ryane/takethe53/recordset"
+)
+
+type Kind string
+
+const (
+	// Added records exist in Route53 but not in the spec.
+	Added Kind = "added"
+	// Removed records are in the spec but not in Route53.
+	Removed Kind = "removed"
+	// Changed records have different values in Route53.
+	Changed Kind = "changed"
+	// DanglingAlias records are aliases of load balancers that no longer
+	// exist.
+	DanglingAlias Kind = "dangling-alias"
+)
+
+type Finding struct {
+	Kind          Kind                       `json:"kind"`
+	Zone          string                     `json:"zone"`
+	Name          string                     `json:"name"`
+	Type          string                     `json:"type"`
+	SetIdentifier string                     `json:"set_identifier,omitempty"`
+	Expected      *route53.ResourceRecordSet `json:"expected,omitempty"`
+	Actual        *route53.ResourceRecordSet `json:"actual,omitempty"`
+}
+
+type Report struct {
+	Time     time.Time  `json:"time"`
+	Findings []*Finding `json:"findings"`
+}
+
+func (r *Report) Drifted() bool {
+	return len(r.Findings) > 0
+}
+
+// LoadBalancersFunc returns the DNS names of the load balancers in a region.
+type LoadBalancersFunc func(ctx context.Context, region string) ([]string, error)
+
+// Check compares every zone of the spec with Route53. Load balancer aliases
+// are checked against the load balancers in the region of their DNS name.
+func Check(ctx context.Context, client *awsclient.AWSClient, spec *Spec, loadBalancers LoadBalancersFunc) (*Report, error) {
+	lbNames := map[string]bool{}
+	regions := map[string]bool{}
+
+	report := &Report{Time: time.Now().UTC(), Findings: []*Finding{}}
+	for _, zs := range spec.Zones {
+		zone, err := client.FindZoneWithContext(ctx, zs.Name)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", zs.Name, err)
+		}
+
+		live, err := client.RecordSetsWithContext(ctx, zone)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", zs.Name, err)
+		}
+
+		for _, rrs := range live {
+			if rrs.AliasTarget == nil {
+				continue
+			}
+			region, ok := awsclient.LoadBalancerRegion(aws.StringValue(rrs.AliasTarget.DNSName))
+			if !ok || regions[region] {
+				continue
+			}
+			names, err := loadBalancers(ctx, region)
+			if err != nil {
+				return nil, fmt.Errorf("load balancers in %s: %s", region, err)
+			}
+			for _, name := range names {
+				lbNames[normalizeTarget(name)] = true
+			}
+			regions[region] = true
+		}
+
+		report.Findings = append(report.Findings, compareZone(zone.Name, zs, live, lbNames)...)
+	}
+
+	return report, nil
+}
+
+func compareZone(zoneName string, zs *ZoneSpec, live []*route53.ResourceRecordSet, lbNames map[string]bool) []*Finding {
+	var findings []*Finding
+
+	var actual []*route53.ResourceRecordSet
+	for _, rrs := range recordset.Editable(zoneName, live) {
+		if awsclient.IsOwnershipRecord(rrs) || zs.Ignored(aws.StringValue(rrs.Name)) {
+			continue
+		}
+		actual = append(actual, rrs)
+	}
+
+	actualByKey := map[string]*route53.ResourceRecordSet{}
+	for _, rrs := range actual {
+		actualByKey[recordset.Key(rrs)] = rrs
+	}
+	expectedByKey := map[string]*route53.ResourceRecordSet{}
+	for _, rrs := range zs.RecordSets() {
+		expectedByKey[recordset.Key(rrs)] = rrs
+
+		existing, ok := actualByKey[recordset.Key(rrs)]
+		switch {
+		case !ok:
+			findings = append(findings, newFinding(Removed, zoneName, rrs, nil))
+		case !recordset.Equal(existing, rrs):
+			findings = append(findings, newFinding(Changed, zoneName, rrs, existing))
+		}
+	}
+
+	for _, rrs := range actual {
+		if _, ok := expectedByKey[recordset.Key(rrs)]; !ok {
+			findings = append(findings, newFinding(Added, zoneName, nil, rrs))
+		}
+		if isDanglingAlias(rrs, lbNames) {
+			findings = append(findings, newFinding(DanglingAlias, zoneName, nil, rrs))
+		}
+	}
+
+	return findings
+}
+
+func newFinding(kind Kind, zoneName string, expected, actual *route53.ResourceRecordSet) *Finding {
+	rrs := expected
+	if rrs == nil {
+		rrs = actual
+	}
+	return &Finding{
+		Kind:          kind,
+		Zone:          zoneName,
+		Name:          aws.StringValue(rrs.Name),
+		Type:          aws.StringValue(rrs.Type),
+		SetIdentifier: aws.StringValue(rrs.SetIdentifier),
+		Expected:      expected,
+		Actual:        actual,
+	}
+}
+
+// isDanglingAlias reports whether rrs is an alias of a load balancer that is
+// not in lbNames.
+func isDanglingAlias(rrs *route53.ResourceRecordSet, lbNames map[string]bool) bool {
+	if rrs.AliasTarget == nil {
+		return false
+	}
+	target := normalizeTarget(aws.StringValue(rrs.AliasTarget.DNSName))
+	_, ok := awsclient.LoadBalancerRegion(target)
+	return ok && !lbNames[target]
+}
+
+// normalizeTarget strips the parts Route53 adds to load balancer alias
+// targets.
+func normalizeTarget(name string) string {
+	name = strings.ToLower(strings.TrimSuffix(name, "."))
+	return strings.TrimPrefix(name, "dualstack.")
+}
+
+// WriteText writes a human readable report.
+func (r *Report) WriteText(w io.Writer) {
+	if !r.Drifted() {
+		fmt.Fprintln(w, "No drift.")
+		return
+	}
+
+	for _, f := range r.Findings {
+		fmt.Fprintf(w, "%-14s %s %s", f.Kind, f.Name, f.Type)
+		if f.SetIdentifier != "" {
+			fmt.Fprintf(w, " (%s)", f.SetIdentifier)
+		}
+		switch f.Kind {
+		case Changed:
+			fmt.Fprintf(w, ": %s, expected %s", value(f.Actual), value(f.Expected))
+		case Added, DanglingAlias:
+			fmt.Fprintf(w, ": %s", value(f.Actual))
+		case Removed:
+			fmt.Fprintf(w, ": expected %s", value(f.Expected))
+		}
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintf(w, "%d records drifted.\n", len(r.Findings))
+}
+
+// Notify posts the report as JSON to a webhook. The text field makes the
+// payload readable in Slack compatible incoming webhooks.
+func Notify(ctx context.Context, url string, r *Report) error {
+	var text bytes.Buffer
+	r.WriteText(&text)
+
+	body, err := json.Marshal(struct {
+		Text string `json:"text"`
+		*Report
+	}{text.String(), r})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("Webhook returned %s.", resp.Status)
+	}
+	return nil
+}
+
+func value(rrs *route53.ResourceRecordSet) string {
+	if rrs.AliasTarget != nil {
+		return "ALIAS " + aws.StringValue(rrs.AliasTarget.DNSName)
+	}
+
+	var values []string
+	for _, rr := range rrs.ResourceRecords {
+		values = append(values, aws.StringValue(rr.Value))
+	}
+	return fmt.Sprintf("%s (ttl %d)", strings.Join(values, " "), aws.Int64Value(rrs.TTL))
+}
@@ -0,0 +1,206 @@
+package drift
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/ryane/takethe53/awsclient"
+	"github.com/ryane/takethe53/awsclient/fake"
+	"github.com/stretchr/testify/assert"
+)
+
+const testSpec = `
+zones:
+- name: example.com
+  ignore: ["_acme-challenge.*"]
+  records:
+  - name: www
+    type: A
+    alias:
+      target: web-1.us-east-1.elb.amazonaws.com
+      hosted_zone_id: Z35SXDOTRQ7X7K
+      evaluate_target_health: true
+  - name: old
+    type: A
+    alias:
+      target: old-1.us-east-1.elb.amazonaws.com
+      hosted_zone_id: Z35SXDOTRQ7X7K
+      evaluate_target_health: true
+  - name: mail.example.com.
+    type: mx
+    ttl: 300
+    values: ["10 mx2.example.com", "5 mx1.example.com"]
+  - name: api
+    type: CNAME
+    ttl: 60
+    values: ["www.example.com"]
+`
+
+func loadTestSpec(t *testing.T) *Spec {
+	f, err := ioutil.TempFile("", "spec")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString(testSpec)
+	f.Close()
+
+	spec, err := LoadSpec(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return spec
+}
+
+func findingsByName(report *Report) map[string]Kind {
+	kinds := map[string]Kind{}
+	for _, f := range report.Findings {
+		kinds[f.Name+" "+string(f.Kind)] = f.Kind
+	}
+	return kinds
+}
+
+func TestCheck(t *testing.T) {
+	ctx := context.Background()
+	r53 := fake.NewRoute53()
+	zoneID, _ := r53.AddZone("example.com")
+	zone := &awsclient.Zone{ID: zoneID, Name: "example.com."}
+
+	lbs := fake.NewELB()
+	lbs.AddLoadBalancer("web", "web-1.us-east-1.elb.amazonaws.com", "Z35SXDOTRQ7X7K")
+
+	client := awsclient.NewWithServices(r53, lbs)
+	client.SetAlias(zone, "Z35SXDOTRQ7X7K", "web-1.us-east-1.elb.amazonaws.com", "www")
+	client.SetAlias(zone, "Z35SXDOTRQ7X7K", "old-1.us-east-1.elb.amazonaws.com", "old")
+	r53.AddRecordSet(zoneID, &route53.ResourceRecordSet{
+		Name: aws.String("mail.example.com."),
+		Type: aws.String("MX"),
+		TTL:  aws.Int64(300),
+		ResourceRecords: []*route53.ResourceRecord{
+			{Value: aws.String("5 mx1.example.com")},
+			{Value: aws.String("10 mx2.example.com")},
+		},
+	})
+	r53.AddRecordSet(zoneID, &route53.ResourceRecordSet{
+		Name:            aws.String("api.example.com."),
+		Type:            aws.String("CNAME"),
+		TTL:             aws.Int64(60),
+		ResourceRecords: []*route53.ResourceRecord{{Value: aws.String("elsewhere.example.net")}},
+	})
+	r53.AddRecordSet(zoneID, &route53.ResourceRecordSet{
+		Name:            aws.String("_acme-challenge.www.example.com."),
+		Type:            aws.String("TXT"),
+		TTL:             aws.Int64(60),
+		ResourceRecords: []*route53.ResourceRecord{{Value: aws.String(`"token"`)}},
+	})
+	r53.AddRecordSet(zoneID, &route53.ResourceRecordSet{
+		Name:            aws.String("rogue.example.com."),
+		Type:            aws.String("A"),
+		TTL:             aws.Int64(60),
+		ResourceRecords: []*route53.ResourceRecord{{Value: aws.String("10.0.0.1")}},
+	})
+
+	report, err := Check(ctx, client, loadTestSpec(t), clientLoadBalancers(client))
+	assert.Nil(t, err)
+	assert.True(t, report.Drifted())
+	assert.Equal(t, map[string]Kind{
+		"api.example.com. changed":        Changed,
+		"rogue.example.com. added":        Added,
+		"old.example.com. dangling-alias": DanglingAlias,
+	}, findingsByName(report))
+
+	client.RemoveAlias(zone, "www")
+	report, err = Check(ctx, client, loadTestSpec(t), clientLoadBalancers(client))
+	assert.Nil(t, err)
+	assert.Equal(t, Removed, findingsByName(report)["www.example.com. removed"])
+}
+
+// clientLoadBalancers looks up the load balancers of every region with client.
+func clientLoadBalancers(client *awsclient.AWSClient) LoadBalancersFunc {
+	return func(ctx context.Context, region string) ([]string, error) {
+		return client.LoadBalancerDNSNamesWithContext(ctx)
+	}
+}
+
+func TestCheckLoadBalancerRegions(t *testing.T) {
+	r53 := fake.NewRoute53()
+	zoneID, _ := r53.AddZone("example.com")
+	zone := &awsclient.Zone{ID: zoneID, Name: "example.com."}
+
+	client := awsclient.NewWithServices(r53, fake.NewELB())
+	client.SetAlias(zone, "Z35SXDOTRQ7X7K", "dualstack.app-1.us-east-1.elb.amazonaws.com", "app")
+	client.SetAlias(zone, "Z32O12XQLNTSW2", "web-1.eu-west-1.elb.amazonaws.com", "eu")
+	client.SetAlias(zone, "Z2IFOLAFXWLO4F", "net-1.elb.us-east-2.amazonaws.com", "net")
+	client.SetAlias(zone, "Z35SXDOTRQ7X7K", "gone-1.us-east-1.elb.amazonaws.com", "gone")
+
+	var loaded []string
+	loadBalancers := func(ctx context.Context, region string) ([]string, error) {
+		loaded = append(loaded, region)
+		return map[string][]string{
+			"us-east-1": {"app-1.us-east-1.elb.amazonaws.com"},
+			"us-east-2": {"net-1.elb.us-east-2.amazonaws.com"},
+			"eu-west-1": {"web-1.eu-west-1.elb.amazonaws.com"},
+		}[region], nil
+	}
+
+	spec := &Spec{Zones: []*ZoneSpec{{Name: "example.com"}}}
+	report, err := Check(context.Background(), client, spec, loadBalancers)
+	assert.Nil(t, err)
+	kinds := findingsByName(report)
+	assert.NotContains(t, kinds, "app.example.com. dangling-alias")
+	assert.NotContains(t, kinds, "eu.example.com. dangling-alias")
+	assert.NotContains(t, kinds, "net.example.com. dangling-alias")
+	assert.Contains(t, kinds, "gone.example.com. dangling-alias")
+	assert.ElementsMatch(t, []string{"us-east-1", "us-east-2", "eu-west-1"}, loaded, "every region is loaded once")
+}
+
+func TestCheckNoDrift(t *testing.T) {
+	r53 := fake.NewRoute53()
+	r53.AddZone("example.com")
+
+	spec := &Spec{Zones: []*ZoneSpec{{Name: "example.com"}}}
+	client := awsclient.NewWithServices(r53, fake.NewELB())
+	report, err := Check(context.Background(), client, spec, clientLoadBalancers(client))
+	assert.Nil(t, err)
+	assert.False(t, report.Drifted())
+
+	var text bytes.Buffer
+	report.WriteText(&text)
+	assert.Equal(t, "No drift.\n", text.String())
+}
+
+func TestCheckMissingZone(t *testing.T) {
+	spec := &Spec{Zones: []*ZoneSpec{{Name: "example.com"}}}
+	client := awsclient.NewWithServices(fake.NewRoute53(), fake.NewELB())
+	_, err := Check(context.Background(), client, spec, clientLoadBalancers(client))
+	assert.NotNil(t, err)
+}
+
+func TestNotify(t *testing.T) {
+	var payload map[string]interface{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		json.NewDecoder(r.Body).Decode(&payload)
+	}))
+	defer ts.Close()
+
+	report := &Report{Findings: []*Finding{{
+		Kind:   Added,
+		Zone:   "example.com.",
+		Name:   "rogue.example.com.",
+		Type:   "A",
+		Actual: &route53.ResourceRecordSet{TTL: aws.Int64(60), ResourceRecords: []*route53.ResourceRecord{{Value: aws.String("10.0.0.1")}}},
+	}}}
+
+	assert.Nil(t, Notify(context.Background(), ts.URL, report))
+	assert.Contains(t, payload["text"], "rogue.example.com. A: 10.0.0.1 (ttl 60)")
+	assert.Equal(t, 1, len(payload["findings"].([]interface{})))
+}
@@ -0,0 +1,123 @@
+package drift
+
+import (
+	"io/ioutil"
+	"path"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"gopkg.in/yaml.v2"
+)
+
+// Spec is the desired state of one or more hosted zones:
+//
+//	zones:
+//	- name: example.com
+//	  ignore: ["_acme-challenge.*"]
+//	  records:
+//	  - name: www
+//	    type: A
+//	    alias:
+//	      target: my-elb-1234.us-east-1.elb.amazonaws.com
+//	      hosted_zone_id: Z35SXDOTRQ7X7K
+//	  - name: mail
+//	    type: MX
+//	    ttl: 300
+//	    values: ["10 mx.example.com"]
+type Spec struct {
+	Zones []*ZoneSpec `yaml:"zones"`
+}
+
+type ZoneSpec struct {
+	Name string `yaml:"name"`
+	// Ignore lists name patterns, relative to the zone or fully qualified,
+	// of records that are managed elsewhere.
+	Ignore  []string      `yaml:"ignore"`
+	Records []*RecordSpec `yaml:"records"`
+}
+
+type RecordSpec struct {
+	// Name is relative to the zone, fully qualified with a trailing dot, or
+	// "@" for the zone apex.
+	Name          string     `yaml:"name"`
+	Type          string     `yaml:"type"`
+	SetIdentifier string     `yaml:"set_identifier"`
+	TTL           int64      `yaml:"ttl"`
+	Values        []string   `yaml:"values"`
+	Alias         *AliasSpec `yaml:"alias"`
+}
+
+type AliasSpec struct {
+	Target               string `yaml:"target"`
+	HostedZoneID         string `yaml:"hosted_zone_id"`
+	EvaluateTargetHealth bool   `yaml:"evaluate_target_health"`
+}
+
+// LoadSpec reads a YAML spec.
+func LoadSpec(path string) (*Spec, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	spec := &Spec{}
+	if err := yaml.Unmarshal(data, spec); err != nil {
+		return nil, err
+	}
+	return spec, nil
+}
+
+// RecordSets returns the record sets the zone should have.
+func (z *ZoneSpec) RecordSets() []*route53.ResourceRecordSet {
+	var rrsets []*route53.ResourceRecordSet
+	for _, r := range z.Records {
+		rrs := &route53.ResourceRecordSet{
+			Name: aws.String(qualify(r.Name, z.Name)),
+			Type: aws.String(strings.ToUpper(r.Type)),
+		}
+		if r.SetIdentifier != "" {
+			rrs.SetIdentifier = aws.String(r.SetIdentifier)
+		}
+
+		if r.Alias != nil {
+			rrs.AliasTarget = &route53.AliasTarget{
+				DNSName:              aws.String(r.Alias.Target),
+				HostedZoneId:         aws.String(r.Alias.HostedZoneID),
+				EvaluateTargetHealth: aws.Bool(r.Alias.EvaluateTargetHealth),
+			}
+		} else {
+			rrs.TTL = aws.Int64(r.TTL)
+			for _, v := range r.Values {
+				rrs.ResourceRecords = append(rrs.ResourceRecords, &route53.ResourceRecord{Value: aws.String(v)})
+			}
+		}
+
+		rrsets = append(rrsets, rrs)
+	}
+	return rrsets
+}
+
+// Ignored reports whether a record name matches one of the ignore patterns.
+func (z *ZoneSpec) Ignored(name string) bool {
+	name = strings.ToLower(name)
+	for _, pattern := range z.Ignore {
+		if ok, _ := path.Match(strings.ToLower(qualify(pattern, z.Name)), name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// qualify turns a name relative to zone into a fully qualified name.
+func qualify(name, zone string) string {
+	zone = strings.TrimSuffix(zone, ".") + "."
+	switch {
+	case name == "@" || name == "":
+		return zone
+	case strings.HasSuffix(name, "."):
+		return name
+	default:
+		return name + "." + zone
+	}
+}
@@ -0,0 +1,81 @@
+package emulator
+
+import (
+	"encoding/xml"
+	"net/http"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/elb"
+)
+
+const elbNamespace = "http://elasticloadbalancing.amazonaws.com/doc/2012-06-01/"
+
+type xmlLoadBalancerDescription struct {
+	LoadBalancerName          string `xml:"LoadBalancerName"`
+	DNSName                   string `xml:"DNSName"`
+	CanonicalHostedZoneName   string `xml:"CanonicalHostedZoneName"`
+	CanonicalHostedZoneNameID string `xml:"CanonicalHostedZoneNameID"`
+	Scheme                    string `xml:"Scheme"`
+}
+
+type xmlDescribeLoadBalancersResponse struct {
+	XMLName                  xml.Name                     `xml:"DescribeLoadBalancersResponse"`
+	Xmlns                    string                       `xml:"xmlns,attr"`
+	LoadBalancerDescriptions []xmlLoadBalancerDescription `xml:"DescribeLoadBalancersResult>LoadBalancerDescriptions>member"`
+	NextMarker               string                       `xml:"DescribeLoadBalancersResult>NextMarker,omitempty"`
+	RequestID                string                       `xml:"ResponseMetadata>RequestId"`
+}
+
+func (e *Emulator) serveELB(w http.ResponseWriter, r *http.Request, reqID string) {
+	if err := r.ParseForm(); err != nil {
+		writeError(w, reqID, http.StatusBadRequest, "Sender", "MalformedQueryString", err.Error())
+		return
+	}
+
+	switch action := r.PostForm.Get("Action"); action {
+	case "DescribeLoadBalancers":
+		e.describeLoadBalancers(w, r, reqID)
+	default:
+		writeError(w, reqID, http.StatusBadRequest, "Sender", "InvalidAction", "Unsupported ELB action: "+action)
+	}
+}
+
+func (e *Emulator) describeLoadBalancers(w http.ResponseWriter, r *http.Request, reqID string) {
+	input := &elb.DescribeLoadBalancersInput{
+		Marker: optionalString(r.PostForm.Get("Marker")),
+	}
+	if pageSize, err := strconv.ParseInt(r.PostForm.Get("PageSize"), 10, 64); err == nil {
+		input.PageSize = aws.Int64(pageSize)
+	}
+	for i := 1; ; i++ {
+		name := r.PostForm.Get("LoadBalancerNames.member." + strconv.Itoa(i))
+		if name == "" {
+			break
+		}
+		input.LoadBalancerNames = append(input.LoadBalancerNames, aws.String(name))
+	}
+
+	out, err := e.ELB.DescribeLoadBalancers(input)
+	if err != nil {
+		writeAWSError(w, reqID, err)
+		return
+	}
+
+	resp := &xmlDescribeLoadBalancersResponse{
+		Xmlns:      elbNamespace,
+		NextMarker: aws.StringValue(out.NextMarker),
+		RequestID:  reqID,
+	}
+	for _, lbd := range out.LoadBalancerDescriptions {
+		resp.LoadBalancerDescriptions = append(resp.LoadBalancerDescriptions, xmlLoadBalancerDescription{
+			LoadBalancerName:          aws.StringValue(lbd.LoadBalancerName),
+			DNSName:                   aws.StringValue(lbd.DNSName),
+			CanonicalHostedZoneName:   aws.StringValue(lbd.CanonicalHostedZoneName),
+			CanonicalHostedZoneNameID: aws.StringValue(lbd.CanonicalHostedZoneNameID),
+			Scheme:                    aws.StringValue(lbd.Scheme),
+		})
+	}
+
+	writeXML(w, http.StatusOK, resp)
+}
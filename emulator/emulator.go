@@ -0,0 +1,105 @@
+// Package emulator serves a subset of the Route53 REST/XML API and the classic
+// ELB Query API from the in-memory backends in awsclient/fake. Point
+// awsclient at it with the Route53/ELB endpoint overrides.
+package emulator
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/ryane/takethe53/awsclient/fake"
+)
+
+const route53Prefix = "/2013-04-01/"
+
+type Emulator struct {
+	Route53 *fake.Route53
+	ELB     *fake.ELB
+
+	requestID uint64
+}
+
+func New(r53 *fake.Route53, elber *fake.ELB) *Emulator {
+	return &Emulator{Route53: r53, ELB: elber}
+}
+
+func (e *Emulator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	logrus.WithFields(logrus.Fields{
+		"method": r.Method,
+		"path":   r.URL.Path,
+		"query":  r.URL.RawQuery,
+		"type":   "emulator",
+	}).Debug("request.emulator")
+
+	reqID := fmt.Sprintf("%08d-emulator", atomic.AddUint64(&e.requestID, 1))
+	w.Header().Set("X-Amzn-RequestId", reqID)
+
+	if strings.HasPrefix(r.URL.Path, route53Prefix) {
+		e.serveRoute53(w, r, reqID)
+		return
+	}
+
+	if r.URL.Path == "/" && r.Method == http.MethodPost {
+		e.serveELB(w, r, reqID)
+		return
+	}
+
+	writeError(w, reqID, http.StatusNotFound, "Sender", "UnknownOperationException", "Unsupported operation: "+r.Method+" "+r.URL.Path)
+}
+
+type xmlErrorResponse struct {
+	XMLName   xml.Name `xml:"ErrorResponse"`
+	Type      string   `xml:"Error>Type"`
+	Code      string   `xml:"Error>Code"`
+	Message   string   `xml:"Error>Message"`
+	RequestID string   `xml:"RequestId"`
+}
+
+func writeError(w http.ResponseWriter, reqID string, status int, errType, code, message string) {
+	writeXML(w, status, &xmlErrorResponse{
+		Type:      errType,
+		Code:      code,
+		Message:   message,
+		RequestID: reqID,
+	})
+}
+
+// writeAWSError translates an error from the fake backends into an AWS style
+// error response.
+func writeAWSError(w http.ResponseWriter, reqID string, err error) {
+	status := http.StatusBadRequest
+	code := "InternalFailure"
+	if aerr, ok := err.(awserr.Error); ok {
+		code = aerr.Code()
+	}
+	if rf, ok := err.(awserr.RequestFailure); ok && rf.StatusCode() > 0 {
+		status = rf.StatusCode()
+	}
+
+	message := err.Error()
+	if aerr, ok := err.(awserr.Error); ok {
+		message = aerr.Message()
+	}
+
+	errType := "Sender"
+	if status >= 500 {
+		errType = "Receiver"
+	}
+
+	writeError(w, reqID, status, errType, code, message)
+}
+
+func writeXML(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "text/xml")
+	w.WriteHeader(status)
+
+	w.Write([]byte(xml.Header))
+	if err := xml.NewEncoder(w).Encode(v); err != nil {
+		logrus.WithField("type", "emulator").Error("Error encoding response: ", err)
+	}
+}
@@ -0,0 +1,179 @@
+package emulator
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/ryane/takethe53/awsclient"
+	"github.com/ryane/takethe53/awsclient/fake"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestServer() (*Emulator, *httptest.Server, *awsclient.AWSClient) {
+	e := New(fake.NewRoute53(), fake.NewELB())
+	e.Route53.PageSize = 2
+	e.ELB.PageSize = 1
+	ts := httptest.NewServer(e)
+
+	c := awsclient.NewWithConfig(awsclient.Config{
+		Route53Endpoint:  ts.URL,
+		ELBEndpoint:      ts.URL,
+		AccessKeyID:      "test",
+		SecretAccessKey:  "test",
+		Route53RateLimit: -1,
+	})
+
+	return e, ts, c
+}
+
+func TestAliasFlow(t *testing.T) {
+	e, ts, c := newTestServer()
+	defer ts.Close()
+
+	e.Apply(&Seed{
+		Zones: []SeedZone{{Name: "example.com"}, {Name: "example.org"}},
+		LoadBalancers: []SeedLoadBalancer{
+			{Name: "web", DNSName: "web-1.us-east-1.elb.amazonaws.com", HostedZoneID: "Z35SXDOTRQ7X7K"},
+			{Name: "api", DNSName: "api-2.us-east-1.elb.amazonaws.com", HostedZoneID: "Z35SXDOTRQ7X7K"},
+		},
+	})
+
+	zone, err := c.FindZone("example.org")
+	assert.Nil(t, err)
+
+	lb, err := c.FindLoadBalancer("api-2.us-east-1.elb.amazonaws.com")
+	assert.Nil(t, err)
+
+	for _, alias := range []string{"a", "b", "www"} {
+		_, err = c.SetAlias(zone, lb.HostedZoneID, lb.Name, alias)
+		assert.Nil(t, err)
+	}
+
+	rec, err := c.FindRecord(zone, "www")
+	assert.Nil(t, err)
+	assert.Equal(t, "api-2.us-east-1.elb.amazonaws.com.", rec.DNSName)
+
+	change, err := c.RemoveAlias(zone, "www")
+	assert.Nil(t, err)
+
+	status, err := c.GetChangeStatus(change.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, awsclient.ChangeStatusInSync, status.Status)
+
+	_, err = c.GetChangeStatus("C0000000000FF")
+	assert.Equal(t, awsclient.ErrChangeNotFound, err)
+}
+
+type apiCallMetrics struct {
+	awsclient.Metrics
+	calls map[string]int
+}
+
+func (m *apiCallMetrics) APICall(service, op, code string, d time.Duration) {
+	m.calls[service+" "+op+" "+code]++
+}
+
+func TestAPICallMetrics(t *testing.T) {
+	e, ts, c := newTestServer()
+	defer ts.Close()
+
+	m := &apiCallMetrics{calls: map[string]int{}}
+	c.SetMetrics(m)
+	e.Apply(&Seed{Zones: []SeedZone{{Name: "example.com"}, {Name: "example.org"}, {Name: "example.net"}}})
+
+	_, err := c.FindZone("example.net")
+	assert.Nil(t, err)
+	_, err = c.GetChangeStatus("C0000000000FF")
+	assert.Equal(t, awsclient.ErrChangeNotFound, err)
+
+	assert.Equal(t, map[string]int{
+		"route53 ListHostedZones ":       2,
+		"route53 GetChange NoSuchChange": 1,
+	}, m.calls, "one call per page")
+}
+
+func TestCreateHostedZoneAndInvalidChangeBatch(t *testing.T) {
+	_, ts, _ := newTestServer()
+	defer ts.Close()
+
+	r53 := route53.New(session.New(), &aws.Config{
+		Endpoint:    aws.String(ts.URL),
+		Region:      aws.String("us-east-1"),
+		Credentials: credentials.NewStaticCredentials("test", "test", ""),
+	})
+
+	out, err := r53.CreateHostedZone(&route53.CreateHostedZoneInput{
+		Name:            aws.String("example.net"),
+		CallerReference: aws.String("test"),
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "example.net.", aws.StringValue(out.HostedZone.Name))
+
+	_, err = r53.ChangeResourceRecordSets(&route53.ChangeResourceRecordSetsInput{
+		HostedZoneId: out.HostedZone.Id,
+		ChangeBatch: &route53.ChangeBatch{
+			Changes: []*route53.Change{
+				{
+					Action: aws.String(route53.ChangeActionDelete),
+					ResourceRecordSet: &route53.ResourceRecordSet{
+						Name:            aws.String("missing.example.net"),
+						Type:            aws.String(route53.RRTypeCname),
+						TTL:             aws.Int64(60),
+						ResourceRecords: []*route53.ResourceRecord{{Value: aws.String("x.example.net")}},
+					},
+				},
+			},
+		},
+	})
+	assert.NotNil(t, err)
+	assert.Equal(t, route53.ErrCodeInvalidChangeBatch, err.(awserr.Error).Code())
+}
+
+func TestRecordSetValues(t *testing.T) {
+	e, ts, c := newTestServer()
+	defer ts.Close()
+
+	e.Apply(&Seed{Zones: []SeedZone{{Name: "example.com"}}})
+	zone, err := c.FindZone("example.com")
+	assert.Nil(t, err)
+
+	_, err = c.SetAlias(zone, "Z35SXDOTRQ7X7K", "web-1.us-east-1.elb.amazonaws.com", "www")
+	assert.Nil(t, err)
+
+	r53 := route53.New(session.New(), &aws.Config{
+		Endpoint:    aws.String(ts.URL),
+		Region:      aws.String("us-east-1"),
+		Credentials: credentials.NewStaticCredentials("test", "test", ""),
+	})
+	var rrsets []*route53.ResourceRecordSet
+	err = r53.ListResourceRecordSetsPages(&route53.ListResourceRecordSetsInput{HostedZoneId: aws.String(zone.ID)}, func(out *route53.ListResourceRecordSetsOutput, last bool) bool {
+		rrsets = append(rrsets, out.ResourceRecordSets...)
+		return true
+	})
+	assert.Nil(t, err)
+
+	var ns, alias *route53.ResourceRecordSet
+	for _, rrs := range rrsets {
+		switch aws.StringValue(rrs.Type) {
+		case route53.RRTypeNs:
+			ns = rrs
+		case route53.RRTypeA:
+			alias = rrs
+		}
+	}
+	assert.Equal(t, 4, len(ns.ResourceRecords), "one ResourceRecord per value")
+	assert.Empty(t, alias.ResourceRecords, "aliases have no values")
+}
+
+func TestApplySeedInvalidZone(t *testing.T) {
+	e := New(fake.NewRoute53(), fake.NewELB())
+	err := e.Apply(&Seed{Zones: []SeedZone{{Name: "example.com"}, {Name: "."}}})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "InvalidDomainName")
+}
@@ -0,0 +1,347 @@
+package emulator
+
+import (
+	"encoding/xml"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/route53"
+)
+
+const route53Namespace = "https://route53.amazonaws.com/doc/2013-04-01/"
+
+type xmlHostedZone struct {
+	ID                     string `xml:"Id"`
+	Name                   string `xml:"Name"`
+	CallerReference        string `xml:"CallerReference"`
+	Comment                string `xml:"Config>Comment,omitempty"`
+	PrivateZone            bool   `xml:"Config>PrivateZone"`
+	ResourceRecordSetCount int64  `xml:"ResourceRecordSetCount"`
+}
+
+type xmlChangeInfo struct {
+	ID          string `xml:"Id"`
+	Status      string `xml:"Status"`
+	SubmittedAt string `xml:"SubmittedAt"`
+	Comment     string `xml:"Comment,omitempty"`
+}
+
+type xmlAliasTarget struct {
+	HostedZoneID         string `xml:"HostedZoneId"`
+	DNSName              string `xml:"DNSName"`
+	EvaluateTargetHealth bool   `xml:"EvaluateTargetHealth"`
+}
+
+type xmlResourceRecord struct {
+	Value string `xml:"Value"`
+}
+
+type xmlResourceRecordSet struct {
+	Name            string              `xml:"Name"`
+	Type            string              `xml:"Type"`
+	SetIdentifier   string              `xml:"SetIdentifier,omitempty"`
+	Weight          *int64              `xml:"Weight,omitempty"`
+	Region          string              `xml:"Region,omitempty"`
+	Failover        string              `xml:"Failover,omitempty"`
+	TTL             *int64              `xml:"TTL,omitempty"`
+	ResourceRecords []xmlResourceRecord `xml:"ResourceRecords>ResourceRecord,omitempty"`
+	AliasTarget     *xmlAliasTarget     `xml:"AliasTarget,omitempty"`
+	HealthCheckID   string              `xml:"HealthCheckId,omitempty"`
+}
+
+type xmlListHostedZonesResponse struct {
+	XMLName     xml.Name        `xml:"ListHostedZonesResponse"`
+	Xmlns       string          `xml:"xmlns,attr"`
+	HostedZones []xmlHostedZone `xml:"HostedZones>HostedZone"`
+	Marker      string          `xml:"Marker"`
+	IsTruncated bool            `xml:"IsTruncated"`
+	NextMarker  string          `xml:"NextMarker,omitempty"`
+	MaxItems    string          `xml:"MaxItems"`
+}
+
+type xmlCreateHostedZoneRequest struct {
+	Name            string `xml:"Name"`
+	CallerReference string `xml:"CallerReference"`
+	Comment         string `xml:"HostedZoneConfig>Comment"`
+	PrivateZone     bool   `xml:"HostedZoneConfig>PrivateZone"`
+}
+
+type xmlCreateHostedZoneResponse struct {
+	XMLName     xml.Name      `xml:"CreateHostedZoneResponse"`
+	Xmlns       string        `xml:"xmlns,attr"`
+	HostedZone  xmlHostedZone `xml:"HostedZone"`
+	ChangeInfo  xmlChangeInfo `xml:"ChangeInfo"`
+	NameServers []string      `xml:"DelegationSet>NameServers>NameServer"`
+}
+
+type xmlListResourceRecordSetsResponse struct {
+	XMLName              xml.Name               `xml:"ListResourceRecordSetsResponse"`
+	Xmlns                string                 `xml:"xmlns,attr"`
+	ResourceRecordSets   []xmlResourceRecordSet `xml:"ResourceRecordSets>ResourceRecordSet"`
+	IsTruncated          bool                   `xml:"IsTruncated"`
+	NextRecordName       string                 `xml:"NextRecordName,omitempty"`
+	NextRecordType       string                 `xml:"NextRecordType,omitempty"`
+	NextRecordIdentifier string                 `xml:"NextRecordIdentifier,omitempty"`
+	MaxItems             string                 `xml:"MaxItems"`
+}
+
+type xmlChangeResourceRecordSetsRequest struct {
+	Comment string `xml:"ChangeBatch>Comment"`
+	Changes []struct {
+		Action            string               `xml:"Action"`
+		ResourceRecordSet xmlResourceRecordSet `xml:"ResourceRecordSet"`
+	} `xml:"ChangeBatch>Changes>Change"`
+}
+
+type xmlChangeResourceRecordSetsResponse struct {
+	XMLName    xml.Name      `xml:"ChangeResourceRecordSetsResponse"`
+	Xmlns      string        `xml:"xmlns,attr"`
+	ChangeInfo xmlChangeInfo `xml:"ChangeInfo"`
+}
+
+type xmlGetChangeResponse struct {
+	XMLName    xml.Name      `xml:"GetChangeResponse"`
+	Xmlns      string        `xml:"xmlns,attr"`
+	ChangeInfo xmlChangeInfo `xml:"ChangeInfo"`
+}
+
+type xmlInvalidChangeBatch struct {
+	XMLName   xml.Name `xml:"InvalidChangeBatch"`
+	Xmlns     string   `xml:"xmlns,attr"`
+	Messages  []string `xml:"Messages>Message"`
+	RequestID string   `xml:"RequestId"`
+}
+
+func (e *Emulator) serveRoute53(w http.ResponseWriter, r *http.Request, reqID string) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, route53Prefix), "/"), "/")
+
+	switch {
+	case len(parts) == 1 && parts[0] == "hostedzone" && r.Method == http.MethodGet:
+		e.listHostedZones(w, r, reqID)
+	case len(parts) == 1 && parts[0] == "hostedzone" && r.Method == http.MethodPost:
+		e.createHostedZone(w, r, reqID)
+	case len(parts) == 3 && parts[0] == "hostedzone" && parts[2] == "rrset" && r.Method == http.MethodGet:
+		e.listResourceRecordSets(w, r, reqID, parts[1])
+	case len(parts) == 3 && parts[0] == "hostedzone" && parts[2] == "rrset" && r.Method == http.MethodPost:
+		e.changeResourceRecordSets(w, r, reqID, parts[1])
+	case len(parts) == 2 && parts[0] == "change" && r.Method == http.MethodGet:
+		e.getChange(w, r, reqID, parts[1])
+	default:
+		writeError(w, reqID, http.StatusNotFound, "Sender", "UnknownOperationException", "Unsupported Route53 operation: "+r.Method+" "+r.URL.Path)
+	}
+}
+
+func (e *Emulator) listHostedZones(w http.ResponseWriter, r *http.Request, reqID string) {
+	q := r.URL.Query()
+	out, err := e.Route53.ListHostedZones(&route53.ListHostedZonesInput{
+		Marker:   optionalString(q.Get("marker")),
+		MaxItems: optionalString(q.Get("maxitems")),
+	})
+	if err != nil {
+		writeAWSError(w, reqID, err)
+		return
+	}
+
+	resp := &xmlListHostedZonesResponse{
+		Xmlns:       route53Namespace,
+		Marker:      aws.StringValue(out.Marker),
+		IsTruncated: aws.BoolValue(out.IsTruncated),
+		NextMarker:  aws.StringValue(out.NextMarker),
+		MaxItems:    aws.StringValue(out.MaxItems),
+	}
+	for _, hz := range out.HostedZones {
+		resp.HostedZones = append(resp.HostedZones, toXMLHostedZone(hz))
+	}
+
+	writeXML(w, http.StatusOK, resp)
+}
+
+func (e *Emulator) createHostedZone(w http.ResponseWriter, r *http.Request, reqID string) {
+	var req xmlCreateHostedZoneRequest
+	if err := xml.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, reqID, http.StatusBadRequest, "Sender", "InvalidInput", err.Error())
+		return
+	}
+
+	out, err := e.Route53.CreateHostedZone(&route53.CreateHostedZoneInput{
+		Name:            aws.String(req.Name),
+		CallerReference: aws.String(req.CallerReference),
+		HostedZoneConfig: &route53.HostedZoneConfig{
+			Comment:     optionalString(req.Comment),
+			PrivateZone: aws.Bool(req.PrivateZone),
+		},
+	})
+	if err != nil {
+		writeAWSError(w, reqID, err)
+		return
+	}
+
+	w.Header().Set("Location", aws.StringValue(out.Location))
+	writeXML(w, http.StatusCreated, &xmlCreateHostedZoneResponse{
+		Xmlns:       route53Namespace,
+		HostedZone:  toXMLHostedZone(out.HostedZone),
+		ChangeInfo:  toXMLChangeInfo(out.ChangeInfo),
+		NameServers: aws.StringValueSlice(out.DelegationSet.NameServers),
+	})
+}
+
+func (e *Emulator) listResourceRecordSets(w http.ResponseWriter, r *http.Request, reqID, zoneID string) {
+	q := r.URL.Query()
+	out, err := e.Route53.ListResourceRecordSets(&route53.ListResourceRecordSetsInput{
+		HostedZoneId:          aws.String(zoneID),
+		StartRecordName:       optionalString(q.Get("name")),
+		StartRecordType:       optionalString(q.Get("type")),
+		StartRecordIdentifier: optionalString(q.Get("identifier")),
+		MaxItems:              optionalString(q.Get("maxitems")),
+	})
+	if err != nil {
+		writeAWSError(w, reqID, err)
+		return
+	}
+
+	resp := &xmlListResourceRecordSetsResponse{
+		Xmlns:                route53Namespace,
+		IsTruncated:          aws.BoolValue(out.IsTruncated),
+		NextRecordName:       aws.StringValue(out.NextRecordName),
+		NextRecordType:       aws.StringValue(out.NextRecordType),
+		NextRecordIdentifier: aws.StringValue(out.NextRecordIdentifier),
+		MaxItems:             aws.StringValue(out.MaxItems),
+	}
+	for _, rrs := range out.ResourceRecordSets {
+		resp.ResourceRecordSets = append(resp.ResourceRecordSets, toXMLResourceRecordSet(rrs))
+	}
+
+	writeXML(w, http.StatusOK, resp)
+}
+
+func (e *Emulator) changeResourceRecordSets(w http.ResponseWriter, r *http.Request, reqID, zoneID string) {
+	var req xmlChangeResourceRecordSetsRequest
+	if err := xml.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, reqID, http.StatusBadRequest, "Sender", "InvalidInput", err.Error())
+		return
+	}
+
+	batch := &route53.ChangeBatch{Comment: optionalString(req.Comment)}
+	for _, c := range req.Changes {
+		batch.Changes = append(batch.Changes, &route53.Change{
+			Action:            aws.String(c.Action),
+			ResourceRecordSet: fromXMLResourceRecordSet(c.ResourceRecordSet),
+		})
+	}
+
+	out, err := e.Route53.ChangeResourceRecordSets(&route53.ChangeResourceRecordSetsInput{
+		HostedZoneId: aws.String(zoneID),
+		ChangeBatch:  batch,
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == route53.ErrCodeInvalidChangeBatch {
+			writeXML(w, http.StatusBadRequest, &xmlInvalidChangeBatch{
+				Xmlns:     route53Namespace,
+				Messages:  []string{aerr.Message()},
+				RequestID: reqID,
+			})
+			return
+		}
+		writeAWSError(w, reqID, err)
+		return
+	}
+
+	writeXML(w, http.StatusOK, &xmlChangeResourceRecordSetsResponse{
+		Xmlns:      route53Namespace,
+		ChangeInfo: toXMLChangeInfo(out.ChangeInfo),
+	})
+}
+
+func (e *Emulator) getChange(w http.ResponseWriter, r *http.Request, reqID, id string) {
+	out, err := e.Route53.GetChange(&route53.GetChangeInput{Id: aws.String(id)})
+	if err != nil {
+		writeAWSError(w, reqID, err)
+		return
+	}
+
+	writeXML(w, http.StatusOK, &xmlGetChangeResponse{
+		Xmlns:      route53Namespace,
+		ChangeInfo: toXMLChangeInfo(out.ChangeInfo),
+	})
+}
+
+func toXMLHostedZone(hz *route53.HostedZone) xmlHostedZone {
+	out := xmlHostedZone{
+		ID:                     aws.StringValue(hz.Id),
+		Name:                   aws.StringValue(hz.Name),
+		CallerReference:        aws.StringValue(hz.CallerReference),
+		ResourceRecordSetCount: aws.Int64Value(hz.ResourceRecordSetCount),
+	}
+	if hz.Config != nil {
+		out.Comment = aws.StringValue(hz.Config.Comment)
+		out.PrivateZone = aws.BoolValue(hz.Config.PrivateZone)
+	}
+	return out
+}
+
+func toXMLChangeInfo(ci *route53.ChangeInfo) xmlChangeInfo {
+	return xmlChangeInfo{
+		ID:          aws.StringValue(ci.Id),
+		Status:      aws.StringValue(ci.Status),
+		SubmittedAt: aws.TimeValue(ci.SubmittedAt).UTC().Format(time.RFC3339),
+		Comment:     aws.StringValue(ci.Comment),
+	}
+}
+
+func toXMLResourceRecordSet(rrs *route53.ResourceRecordSet) xmlResourceRecordSet {
+	out := xmlResourceRecordSet{
+		Name:          aws.StringValue(rrs.Name),
+		Type:          aws.StringValue(rrs.Type),
+		SetIdentifier: aws.StringValue(rrs.SetIdentifier),
+		Weight:        rrs.Weight,
+		Region:        aws.StringValue(rrs.Region),
+		Failover:      aws.StringValue(rrs.Failover),
+		TTL:           rrs.TTL,
+		HealthCheckID: aws.StringValue(rrs.HealthCheckId),
+	}
+	for _, rr := range rrs.ResourceRecords {
+		out.ResourceRecords = append(out.ResourceRecords, xmlResourceRecord{Value: aws.StringValue(rr.Value)})
+	}
+	if rrs.AliasTarget != nil {
+		out.AliasTarget = &xmlAliasTarget{
+			HostedZoneID:         aws.StringValue(rrs.AliasTarget.HostedZoneId),
+			DNSName:              aws.StringValue(rrs.AliasTarget.DNSName),
+			EvaluateTargetHealth: aws.BoolValue(rrs.AliasTarget.EvaluateTargetHealth),
+		}
+	}
+	return out
+}
+
+func fromXMLResourceRecordSet(rrs xmlResourceRecordSet) *route53.ResourceRecordSet {
+	out := &route53.ResourceRecordSet{
+		Name:          aws.String(rrs.Name),
+		Type:          aws.String(rrs.Type),
+		SetIdentifier: optionalString(rrs.SetIdentifier),
+		Weight:        rrs.Weight,
+		Region:        optionalString(rrs.Region),
+		Failover:      optionalString(rrs.Failover),
+		TTL:           rrs.TTL,
+		HealthCheckId: optionalString(rrs.HealthCheckID),
+	}
+	for _, rr := range rrs.ResourceRecords {
+		out.ResourceRecords = append(out.ResourceRecords, &route53.ResourceRecord{Value: aws.String(rr.Value)})
+	}
+	if rrs.AliasTarget != nil {
+		out.AliasTarget = &route53.AliasTarget{
+			HostedZoneId:         aws.String(rrs.AliasTarget.HostedZoneID),
+			DNSName:              aws.String(rrs.AliasTarget.DNSName),
+			EvaluateTargetHealth: aws.Bool(rrs.AliasTarget.EvaluateTargetHealth),
+		}
+	}
+	return out
+}
+
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return aws.String(s)
+}
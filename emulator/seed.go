@@ -0,0 +1,63 @@
+package emulator
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/aws/aws-sdk-go/service/route53"
+)
+
+// Seed describes the initial state of the emulator. Record sets use the same
+// JSON layout as the AWS CLI.
+type Seed struct {
+	Zones         []SeedZone         `json:"zones"`
+	LoadBalancers []SeedLoadBalancer `json:"load_balancers"`
+}
+
+type SeedZone struct {
+	Name       string                       `json:"name"`
+	RecordSets []*route53.ResourceRecordSet `json:"record_sets"`
+}
+
+type SeedLoadBalancer struct {
+	Name         string `json:"name"`
+	DNSName      string `json:"dns_name"`
+	HostedZoneID string `json:"hosted_zone_id"`
+}
+
+func LoadSeed(path string) (*Seed, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var seed Seed
+	if err := json.NewDecoder(f).Decode(&seed); err != nil {
+		return nil, err
+	}
+
+	return &seed, nil
+}
+
+// Apply loads the seed into the emulator backends.
+func (e *Emulator) Apply(seed *Seed) error {
+	for _, z := range seed.Zones {
+		id, err := e.Route53.AddZone(z.Name)
+		if err != nil {
+			return fmt.Errorf("%s: %s", z.Name, err)
+		}
+		for _, rrs := range z.RecordSets {
+			if err := e.Route53.AddRecordSet(id, rrs); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, lb := range seed.LoadBalancers {
+		e.ELB.AddLoadBalancer(lb.Name, lb.DNSName, lb.HostedZoneID)
+	}
+
+	return nil
+}
@@ -0,0 +1,111 @@
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// cloudFormation writes a template with an AWS::Route53::RecordSet resource
+// per record set. The resources have DeletionPolicy Retain, which
+// CloudFormation requires to import existing resources into a stack. Load
+// balancers outside the stack can't be referenced, aliases of them name the
+// load balancer in a comment.
+func (e *exporter) cloudFormation(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "# Route53 records of %s (%s), exported by takethe53.\n", e.zone.Name, zoneID(e.zone.ID))
+	fmt.Fprintln(bw, `AWSTemplateFormatVersion: "2010-09-09"`)
+	fmt.Fprintf(bw, "Description: %s\n", yamlString("Route53 records of "+e.zone.Name))
+	if len(e.records) == 0 {
+		fmt.Fprintln(bw, "Resources: {}")
+		return bw.Flush()
+	}
+	fmt.Fprintln(bw, "Resources:")
+
+	used := map[string]bool{}
+	for _, rrs := range e.records {
+		id := unique(logicalID(e.words(rrs)), "", used)
+		fmt.Fprintf(bw, "  %s:\n", id)
+		fmt.Fprintln(bw, "    Type: AWS::Route53::RecordSet")
+		fmt.Fprintln(bw, "    DeletionPolicy: Retain")
+		fmt.Fprintln(bw, "    Properties:")
+
+		p := func(key, value string) {
+			fmt.Fprintf(bw, "      %s: %s\n", key, value)
+		}
+		p("HostedZoneId", yamlString(zoneID(e.zone.ID)))
+		p("Name", yamlString(recordName(rrs)))
+		p("Type", yamlString(aws.StringValue(rrs.Type)))
+
+		if rrs.AliasTarget != nil {
+			fmt.Fprintln(bw, "      AliasTarget:")
+			if lb, _ := e.loadBalancer(rrs); lb != nil {
+				fmt.Fprintf(bw, "        # load balancer %s\n", lb.LoadBalancerName)
+			}
+			fmt.Fprintf(bw, "        DNSName: %s\n", yamlString(aws.StringValue(rrs.AliasTarget.DNSName)))
+			fmt.Fprintf(bw, "        HostedZoneId: %s\n", yamlString(aws.StringValue(rrs.AliasTarget.HostedZoneId)))
+			fmt.Fprintf(bw, "        EvaluateTargetHealth: %t\n", aws.BoolValue(rrs.AliasTarget.EvaluateTargetHealth))
+		} else {
+			p("TTL", yamlString(strconv.FormatInt(aws.Int64Value(rrs.TTL), 10)))
+			fmt.Fprintln(bw, "      ResourceRecords:")
+			for _, rr := range rrs.ResourceRecords {
+				fmt.Fprintf(bw, "        - %s\n", yamlString(aws.StringValue(rr.Value)))
+			}
+		}
+
+		if rrs.SetIdentifier != nil {
+			p("SetIdentifier", yamlString(aws.StringValue(rrs.SetIdentifier)))
+		}
+		if rrs.Weight != nil {
+			p("Weight", strconv.FormatInt(aws.Int64Value(rrs.Weight), 10))
+		}
+		if rrs.Region != nil {
+			p("Region", yamlString(aws.StringValue(rrs.Region)))
+		}
+		if rrs.Failover != nil {
+			p("Failover", yamlString(aws.StringValue(rrs.Failover)))
+		}
+		if geo := rrs.GeoLocation; geo != nil {
+			fmt.Fprintln(bw, "      GeoLocation:")
+			if geo.ContinentCode != nil {
+				fmt.Fprintf(bw, "        ContinentCode: %s\n", yamlString(aws.StringValue(geo.ContinentCode)))
+			}
+			if geo.CountryCode != nil {
+				fmt.Fprintf(bw, "        CountryCode: %s\n", yamlString(aws.StringValue(geo.CountryCode)))
+			}
+			if geo.SubdivisionCode != nil {
+				fmt.Fprintf(bw, "        SubdivisionCode: %s\n", yamlString(aws.StringValue(geo.SubdivisionCode)))
+			}
+		}
+		if rrs.MultiValueAnswer != nil {
+			p("MultiValueAnswer", strconv.FormatBool(aws.BoolValue(rrs.MultiValueAnswer)))
+		}
+		if rrs.HealthCheckId != nil {
+			p("HealthCheckId", yamlString(aws.StringValue(rrs.HealthCheckId)))
+		}
+	}
+
+	return bw.Flush()
+}
+
+// logicalID joins words into an alphanumeric CloudFormation logical ID, e.g.
+// "ApiV2A".
+func logicalID(words []string) string {
+	var id string
+	for _, w := range words {
+		id += strings.ToUpper(w[:1]) + strings.ToLower(w[1:])
+	}
+	if id == "" || (id[0] >= '0' && id[0] <= '9') {
+		id = "Record" + id
+	}
+	return id
+}
+
+// yamlString quotes s as a double-quoted YAML scalar.
+func yamlString(s string) string {
+	return strconv.Quote(s)
+}
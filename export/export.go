@@ -0,0 +1,119 @@
+// Package export writes the records of a zone as Terraform or CloudFormation
+// code, for teams that move their records to infrastructure-as-code.
+package export
+
+import (
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/ryane/takethe53/awsclient"
+	"github.com/ryane/takethe53/recordset"
+)
+
+// Formats.
+const (
+	Terraform      = "terraform"
+	CloudFormation = "cloudformation"
+)
+
+var ErrUnknownFormat = errors.New("Unknown format. Use terraform or cloudformation.")
+
+// Write writes the record sets of zone in format. Aliases of the classic load
+// balancers in lbs refer to the load balancer by name where the format
+// allows it. The SOA and NS records at the apex and ownership TXT records are
+// left out.
+func Write(w io.Writer, format string, zone *awsclient.Zone, rrsets []*route53.ResourceRecordSet, lbs []*awsclient.LoadBalancer) error {
+	e := newExporter(zone, rrsets, lbs)
+	switch format {
+	case Terraform:
+		return e.terraform(w)
+	case CloudFormation:
+		return e.cloudFormation(w)
+	}
+	return ErrUnknownFormat
+}
+
+type exporter struct {
+	zone    *awsclient.Zone
+	records []*route53.ResourceRecordSet
+	// lbs are the load balancers by DNS name.
+	lbs map[string]*awsclient.LoadBalancer
+}
+
+func newExporter(zone *awsclient.Zone, rrsets []*route53.ResourceRecordSet, lbs []*awsclient.LoadBalancer) *exporter {
+	e := &exporter{zone: zone, lbs: map[string]*awsclient.LoadBalancer{}}
+	for _, rrs := range recordset.Editable(zone.Name, rrsets) {
+		if !awsclient.IsOwnershipRecord(rrs) {
+			e.records = append(e.records, rrs)
+		}
+	}
+	for _, lb := range lbs {
+		if lb.LoadBalancerName != "" {
+			e.lbs[normalizeTarget(lb.Name)] = lb
+		}
+	}
+	return e
+}
+
+// loadBalancer returns the load balancer an alias points at, and whether it
+// is the dualstack name of it.
+func (e *exporter) loadBalancer(rrs *route53.ResourceRecordSet) (*awsclient.LoadBalancer, bool) {
+	if rrs.AliasTarget == nil {
+		return nil, false
+	}
+	target := strings.ToLower(strings.TrimSuffix(aws.StringValue(rrs.AliasTarget.DNSName), "."))
+	lb := e.lbs[normalizeTarget(target)]
+	return lb, lb != nil && strings.HasPrefix(target, "dualstack.")
+}
+
+// words splits the name, type and set identifier of a record set into the
+// words of a resource name, e.g. "api", "v2", "A" for api-v2.example.com.
+func (e *exporter) words(rrs *route53.ResourceRecordSet) []string {
+	name := recordset.RelativeName(recordName(rrs), e.zone.Name)
+	if name == "@" {
+		name = "apex"
+	}
+	name = strings.Replace(name, "*", "wildcard", -1)
+
+	words := splitWords(name)
+	words = append(words, aws.StringValue(rrs.Type))
+	return append(words, splitWords(aws.StringValue(rrs.SetIdentifier))...)
+}
+
+// recordName returns the name of a record set with the escaped wildcard that
+// Route53 returns replaced by *.
+func recordName(rrs *route53.ResourceRecordSet) string {
+	return strings.Replace(aws.StringValue(rrs.Name), `\052`, "*", -1)
+}
+
+func splitWords(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return r > unicode.MaxASCII || !(unicode.IsLetter(r) || unicode.IsDigit(r))
+	})
+}
+
+// unique returns name, or name with a number if it is already used.
+func unique(name, sep string, used map[string]bool) string {
+	candidate := name
+	for i := 2; used[candidate]; i++ {
+		candidate = name + sep + strconv.Itoa(i)
+	}
+	used[candidate] = true
+	return candidate
+}
+
+// normalizeTarget strips the parts Route53 adds to load balancer alias
+// targets.
+func normalizeTarget(name string) string {
+	name = strings.ToLower(strings.TrimSuffix(name, "."))
+	return strings.TrimPrefix(name, "dualstack.")
+}
+
+func zoneID(id string) string {
+	return strings.TrimPrefix(id, "/hostedzone/")
+}
@@ -0,0 +1,131 @@
+package export
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/ryane/takethe53/awsclient"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+)
+
+var testZone = &awsclient.Zone{ID: "/hostedzone/Z1", Name: "example.com."}
+
+var testLBs = []*awsclient.LoadBalancer{
+	{Name: "web-1.us-east-1.elb.amazonaws.com", HostedZoneID: "Z35SXDOTRQ7X7K", LoadBalancerName: "web-1"},
+}
+
+func testRecordSets() []*route53.ResourceRecordSet {
+	return []*route53.ResourceRecordSet{
+		{Name: aws.String("example.com."), Type: aws.String(route53.RRTypeSoa), TTL: aws.Int64(900), ResourceRecords: []*route53.ResourceRecord{{Value: aws.String("ns-1. hostmaster. 1 7200 900 1209600 86400")}}},
+		{Name: aws.String("example.com."), Type: aws.String(route53.RRTypeNs), TTL: aws.Int64(172800), ResourceRecords: []*route53.ResourceRecord{{Value: aws.String("ns-1.")}}},
+		{
+			Name: aws.String("www.example.com."),
+			Type: aws.String(route53.RRTypeA),
+			AliasTarget: &route53.AliasTarget{
+				HostedZoneId:         aws.String("Z35SXDOTRQ7X7K"),
+				DNSName:              aws.String("dualstack.web-1.us-east-1.elb.amazonaws.com."),
+				EvaluateTargetHealth: aws.Bool(true),
+			},
+		},
+		{
+			Name:            aws.String("www.example.com."),
+			Type:            aws.String(route53.RRTypeTxt),
+			TTL:             aws.Int64(300),
+			ResourceRecords: []*route53.ResourceRecord{{Value: aws.String(`"heritage=takethe53,takethe53/owner=team-a"`)}},
+		},
+		{
+			Name:            aws.String("example.com."),
+			Type:            aws.String(route53.RRTypeTxt),
+			TTL:             aws.Int64(300),
+			ResourceRecords: []*route53.ResourceRecord{{Value: aws.String(`"v=spf1 include:${domain} -all"`)}},
+		},
+		{
+			Name:            aws.String(`\052.api.example.com.`),
+			Type:            aws.String(route53.RRTypeCname),
+			TTL:             aws.Int64(60),
+			SetIdentifier:   aws.String("blue"),
+			Weight:          aws.Int64(10),
+			ResourceRecords: []*route53.ResourceRecord{{Value: aws.String("blue.example.net")}},
+		},
+	}
+}
+
+func TestTerraform(t *testing.T) {
+	var buf bytes.Buffer
+	err := Write(&buf, Terraform, testZone, testRecordSets(), testLBs)
+	assert.Nil(t, err)
+	out := buf.String()
+
+	assert.Contains(t, out, `data "aws_elb" "web_1" {
+  name = "web-1"
+}`)
+	assert.Contains(t, out, `resource "aws_route53_record" "www_a" {
+  zone_id = "Z1"
+  name    = "www.example.com"
+  type    = "A"
+
+  alias {
+    name                   = "dualstack.${data.aws_elb.web_1.dns_name}"
+    zone_id                = data.aws_elb.web_1.zone_id
+    evaluate_target_health = true
+  }
+}
+
+import {
+  to = aws_route53_record.www_a
+  id = "Z1_www.example.com_A"
+}`)
+	assert.Contains(t, out, `records = ["v=spf1 include:$${domain} -all"]`, "TXT quotes are added by the provider")
+	assert.Contains(t, out, `resource "aws_route53_record" "wildcard_api_cname_blue" {
+  zone_id        = "Z1"
+  name           = "*.api.example.com"
+  type           = "CNAME"
+  ttl            = 60
+  records        = ["blue.example.net"]
+  set_identifier = "blue"
+
+  weighted_routing_policy {
+    weight = 10
+  }
+}`)
+	assert.Contains(t, out, `id = "Z1_*.api.example.com_CNAME_blue"`)
+	assert.NotContains(t, out, "SOA")
+	assert.NotContains(t, out, "heritage=takethe53", "ownership records are left out")
+}
+
+func TestCloudFormation(t *testing.T) {
+	var buf bytes.Buffer
+	err := Write(&buf, CloudFormation, testZone, testRecordSets(), testLBs)
+	assert.Nil(t, err)
+	assert.Contains(t, buf.String(), "# load balancer web-1")
+
+	var template struct {
+		Resources map[string]struct {
+			Type           string                 `yaml:"Type"`
+			DeletionPolicy string                 `yaml:"DeletionPolicy"`
+			Properties     map[string]interface{} `yaml:"Properties"`
+		} `yaml:"Resources"`
+	}
+	assert.Nil(t, yaml.Unmarshal(buf.Bytes(), &template))
+	assert.Equal(t, 3, len(template.Resources))
+
+	www := template.Resources["WwwA"]
+	assert.Equal(t, "AWS::Route53::RecordSet", www.Type)
+	assert.Equal(t, "Retain", www.DeletionPolicy)
+	assert.Equal(t, "Z1", www.Properties["HostedZoneId"])
+	assert.Equal(t, "dualstack.web-1.us-east-1.elb.amazonaws.com.", www.Properties["AliasTarget"].(map[interface{}]interface{})["DNSName"])
+
+	spf := template.Resources["ApexTxt"]
+	assert.Equal(t, []interface{}{`"v=spf1 include:${domain} -all"`}, spf.Properties["ResourceRecords"])
+
+	wildcard := template.Resources["WildcardApiCnameBlue"]
+	assert.Equal(t, "*.api.example.com.", wildcard.Properties["Name"])
+	assert.Equal(t, 10, wildcard.Properties["Weight"])
+}
+
+func TestUnknownFormat(t *testing.T) {
+	assert.Equal(t, ErrUnknownFormat, Write(&bytes.Buffer{}, "pulumi", testZone, nil, nil))
+}
@@ -0,0 +1,209 @@
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/ryane/takethe53/awsclient"
+)
+
+// terraform writes an aws_route53_record resource and an import block per
+// record set, and an aws_elb data source per load balancer that aliases
+// point at. Import blocks need Terraform 1.5 or later.
+func (e *exporter) terraform(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "# Route53 records of %s (%s), exported by takethe53.\n", e.zone.Name, zoneID(e.zone.ID))
+
+	used := map[string]bool{}
+	dataSources := map[string]string{}
+	var data, resources []*hclBlock
+
+	for _, rrs := range e.records {
+		label := unique(terraformLabel(e.words(rrs)), "_", used)
+		resource := &hclBlock{header: fmt.Sprintf("resource \"aws_route53_record\" %q", label)}
+		resource.attr("zone_id", hclString(zoneID(e.zone.ID)))
+		resource.attr("name", hclString(strings.TrimSuffix(recordName(rrs), ".")))
+		resource.attr("type", hclString(aws.StringValue(rrs.Type)))
+
+		if rrs.AliasTarget != nil {
+			alias, lb := e.terraformAlias(rrs, dataSources, used)
+			resource.blocks = append(resource.blocks, alias)
+			if lb != nil {
+				data = append(data, lb)
+			}
+		} else {
+			resource.attr("ttl", strconv.FormatInt(aws.Int64Value(rrs.TTL), 10))
+			var values []string
+			for _, rr := range rrs.ResourceRecords {
+				values = append(values, hclString(terraformValue(aws.StringValue(rrs.Type), aws.StringValue(rr.Value))))
+			}
+			resource.attr("records", "["+strings.Join(values, ", ")+"]")
+		}
+		terraformRouting(resource, rrs)
+
+		resources = append(resources, resource)
+		resources = append(resources, &hclBlock{
+			header: "import",
+			attrs: [][2]string{
+				{"to", "aws_route53_record." + label},
+				{"id", hclString(terraformImportID(e.zone, rrs))},
+			},
+		})
+	}
+
+	for _, b := range append(data, resources...) {
+		bw.WriteString("\n")
+		b.write(bw, "")
+	}
+	return bw.Flush()
+}
+
+// terraformAlias returns the alias block of a record set. Load balancers are
+// referred to by an aws_elb data source, which is returned too the first time
+// it is used.
+func (e *exporter) terraformAlias(rrs *route53.ResourceRecordSet, dataSources map[string]string, used map[string]bool) (*hclBlock, *hclBlock) {
+	alias := &hclBlock{header: "alias"}
+	evaluate := strconv.FormatBool(aws.BoolValue(rrs.AliasTarget.EvaluateTargetHealth))
+
+	lb, dualstack := e.loadBalancer(rrs)
+	if lb == nil {
+		alias.attr("name", hclString(strings.TrimSuffix(aws.StringValue(rrs.AliasTarget.DNSName), ".")))
+		alias.attr("zone_id", hclString(aws.StringValue(rrs.AliasTarget.HostedZoneId)))
+		alias.attr("evaluate_target_health", evaluate)
+		return alias, nil
+	}
+
+	var data *hclBlock
+	label, ok := dataSources[lb.LoadBalancerName]
+	if !ok {
+		label = unique(terraformLabel(splitWords(lb.LoadBalancerName)), "_", used)
+		dataSources[lb.LoadBalancerName] = label
+		data = &hclBlock{header: fmt.Sprintf("data \"aws_elb\" %q", label)}
+		data.attr("name", hclString(lb.LoadBalancerName))
+	}
+
+	ref := "data.aws_elb." + label
+	name := ref + ".dns_name"
+	if dualstack {
+		name = `"dualstack.${` + name + `}"`
+	}
+	alias.attr("name", name)
+	alias.attr("zone_id", ref+".zone_id")
+	alias.attr("evaluate_target_health", evaluate)
+	return alias, data
+}
+
+func terraformRouting(resource *hclBlock, rrs *route53.ResourceRecordSet) {
+	if rrs.SetIdentifier != nil {
+		resource.attr("set_identifier", hclString(aws.StringValue(rrs.SetIdentifier)))
+	}
+	if rrs.HealthCheckId != nil {
+		resource.attr("health_check_id", hclString(aws.StringValue(rrs.HealthCheckId)))
+	}
+	if rrs.MultiValueAnswer != nil {
+		resource.attr("multivalue_answer_routing_policy", strconv.FormatBool(aws.BoolValue(rrs.MultiValueAnswer)))
+	}
+
+	if rrs.Weight != nil {
+		b := &hclBlock{header: "weighted_routing_policy"}
+		b.attr("weight", strconv.FormatInt(aws.Int64Value(rrs.Weight), 10))
+		resource.blocks = append(resource.blocks, b)
+	}
+	if rrs.Region != nil {
+		b := &hclBlock{header: "latency_routing_policy"}
+		b.attr("region", hclString(aws.StringValue(rrs.Region)))
+		resource.blocks = append(resource.blocks, b)
+	}
+	if rrs.Failover != nil {
+		b := &hclBlock{header: "failover_routing_policy"}
+		b.attr("type", hclString(aws.StringValue(rrs.Failover)))
+		resource.blocks = append(resource.blocks, b)
+	}
+	if geo := rrs.GeoLocation; geo != nil {
+		b := &hclBlock{header: "geolocation_routing_policy"}
+		if geo.ContinentCode != nil {
+			b.attr("continent", hclString(aws.StringValue(geo.ContinentCode)))
+		}
+		if geo.CountryCode != nil {
+			b.attr("country", hclString(aws.StringValue(geo.CountryCode)))
+		}
+		if geo.SubdivisionCode != nil {
+			b.attr("subdivision", hclString(aws.StringValue(geo.SubdivisionCode)))
+		}
+		resource.blocks = append(resource.blocks, b)
+	}
+}
+
+// terraformImportID returns the ID aws_route53_record is imported by,
+// <zone ID>_<name>_<type>[_<set identifier>].
+func terraformImportID(zone *awsclient.Zone, rrs *route53.ResourceRecordSet) string {
+	parts := []string{zoneID(zone.ID), strings.TrimSuffix(recordName(rrs), "."), aws.StringValue(rrs.Type)}
+	if rrs.SetIdentifier != nil {
+		parts = append(parts, aws.StringValue(rrs.SetIdentifier))
+	}
+	return strings.Join(parts, "_")
+}
+
+// terraformValue returns a record value the way aws_route53_record expects
+// it. The provider adds the quotes around TXT and SPF values itself.
+func terraformValue(rrType, value string) string {
+	if (rrType == route53.RRTypeTxt || rrType == route53.RRTypeSpf) && len(value) >= 2 && strings.HasPrefix(value, `"`) && strings.HasSuffix(value, `"`) {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+// terraformLabel joins words into a resource label, which must start with a
+// letter or underscore.
+func terraformLabel(words []string) string {
+	label := strings.ToLower(strings.Join(words, "_"))
+	if label == "" || (label[0] >= '0' && label[0] <= '9') {
+		label = "r_" + label
+	}
+	return label
+}
+
+// hclString quotes s as an HCL string, escaping template sequences.
+func hclString(s string) string {
+	q := strconv.Quote(s)
+	q = strings.Replace(q, "${", "$${", -1)
+	return strings.Replace(q, "%{", "%%{", -1)
+}
+
+// hclBlock is a block of HCL. Attributes are aligned on the = like
+// terraform fmt does and nested blocks follow the attributes.
+type hclBlock struct {
+	header string
+	attrs  [][2]string
+	blocks []*hclBlock
+}
+
+func (b *hclBlock) attr(name, value string) {
+	b.attrs = append(b.attrs, [2]string{name, value})
+}
+
+func (b *hclBlock) write(w io.Writer, indent string) {
+	fmt.Fprintf(w, "%s%s {\n", indent, b.header)
+
+	width := 0
+	for _, a := range b.attrs {
+		if len(a[0]) > width {
+			width = len(a[0])
+		}
+	}
+	for _, a := range b.attrs {
+		fmt.Fprintf(w, "%s  %-*s = %s\n", indent, width, a[0], a[1])
+	}
+
+	for _, nested := range b.blocks {
+		io.WriteString(w, "\n")
+		nested.write(w, indent+"  ")
+	}
+
+	fmt.Fprintf(w, "%s}\n", indent)
+}
@@ -0,0 +1,172 @@
+// Package journal records every change takethe53 makes to Route53 so that it
+// can be reviewed and undone later.
+package journal
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/ryane/takethe53/awsclient"
+)
+
+// OpUndo is the operation name of change batches that revert an entry.
+const OpUndo = "undo"
+
+var (
+	ErrEntryNotFound  = errors.New("Journal entry does not exist.")
+	ErrAmbiguousEntry = errors.New("Journal entry ID matches more than one entry.")
+	ErrNothingToUndo  = errors.New("Journal entry has no changes to undo.")
+	ErrIncomplete     = errors.New("Journal entry is missing the records it replaced and can't be undone.")
+)
+
+// Entry is a single change batch and the state of the record sets it touched.
+type Entry struct {
+	ID       string                       `json:"id"`
+	Time     time.Time                    `json:"time"`
+	User     string                       `json:"user"`
+	Op       string                       `json:"op"`
+	ZoneID   string                       `json:"zone_id"`
+	ZoneName string                       `json:"zone_name"`
+	Before   []*route53.ResourceRecordSet `json:"before"`
+	After    []*route53.ResourceRecordSet `json:"after"`
+	ChangeID string                       `json:"change_id"`
+	// Incomplete is set when the replaced record sets could not be read, so
+	// Before is missing some of them.
+	Incomplete bool `json:"incomplete,omitempty"`
+}
+
+// Store persists journal entries.
+type Store interface {
+	Append(ctx context.Context, e *Entry) error
+	// List returns all entries, oldest first.
+	List(ctx context.Context) ([]*Entry, error)
+}
+
+// NewStore returns the Store for location, either an S3 URL
+// (s3://bucket/prefix) or a local file path.
+func NewStore(location string) (Store, error) {
+	if strings.HasPrefix(location, "s3://") {
+		bucket, prefix := parseS3URL(location)
+		if bucket == "" {
+			return nil, errors.New("Invalid S3 journal location: " + location)
+		}
+		return NewS3Store(bucket, prefix), nil
+	}
+	return NewFileStore(location)
+}
+
+// NewEntry builds the journal entry for a successful change batch.
+func NewEntry(ev *awsclient.ChangeEvent, user string) *Entry {
+	e := &Entry{
+		ID:         newID(ev.Time),
+		Time:       ev.Time.UTC(),
+		User:       user,
+		Op:         ev.Op,
+		ZoneID:     ev.Zone.ID,
+		ZoneName:   ev.Zone.Name,
+		Before:     ev.Previous,
+		Incomplete: ev.PreviousErr != nil,
+	}
+	if ev.Status != nil {
+		e.ChangeID = ev.Status.ID
+	}
+
+	for _, change := range ev.Changes {
+		switch aws.StringValue(change.Action) {
+		case route53.ChangeActionDelete:
+			e.Before = append(e.Before, change.ResourceRecordSet)
+		default:
+			e.After = append(e.After, change.ResourceRecordSet)
+		}
+	}
+
+	return e
+}
+
+// Inverse returns the change batch that restores the record sets to their
+// state before the entry. Record sets that still exist in their "after" state
+// are deleted and the "before" record sets are created again.
+func (e *Entry) Inverse() []*route53.Change {
+	var changes []*route53.Change
+	for _, rrs := range e.After {
+		changes = append(changes, &route53.Change{
+			Action:            aws.String(route53.ChangeActionDelete),
+			ResourceRecordSet: rrs,
+		})
+	}
+	for _, rrs := range e.Before {
+		changes = append(changes, &route53.Change{
+			Action:            aws.String(route53.ChangeActionCreate),
+			ResourceRecordSet: rrs,
+		})
+	}
+	return changes
+}
+
+// Names returns the distinct record names the entry touched.
+func (e *Entry) Names() []string {
+	var names []string
+	seen := map[string]bool{}
+	for _, rrs := range append(append([]*route53.ResourceRecordSet{}, e.Before...), e.After...) {
+		name := aws.StringValue(rrs.Name)
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Handler returns a change handler that appends every successful change to
+// store. Errors are passed to onError so a broken journal never fails a
+// change that Route53 already accepted.
+func Handler(store Store, user string, onError func(error)) awsclient.ChangeHandler {
+	return func(ctx context.Context, ev *awsclient.ChangeEvent) {
+		if ev.Err != nil {
+			return
+		}
+		// the change is already submitted, record it even if ctx was canceled
+		if err := store.Append(context.Background(), NewEntry(ev, user)); err != nil && onError != nil {
+			onError(err)
+		}
+	}
+}
+
+// Find returns the entry whose ID starts with id.
+func Find(ctx context.Context, store Store, id string) (*Entry, error) {
+	entries, err := store.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var found *Entry
+	for _, e := range entries {
+		if e.ID == id {
+			return e, nil
+		}
+		if strings.HasPrefix(e.ID, id) {
+			if found != nil {
+				return nil, ErrAmbiguousEntry
+			}
+			found = e
+		}
+	}
+
+	if found == nil {
+		return nil, ErrEntryNotFound
+	}
+	return found, nil
+}
+
+// newID returns a sortable, unique entry ID.
+func newID(t time.Time) string {
+	b := make([]byte, 3)
+	rand.Read(b)
+	return t.UTC().Format("20060102T150405Z") + "-" + hex.EncodeToString(b)
+}
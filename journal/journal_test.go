@@ -0,0 +1,204 @@
+package journal
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/ryane/takethe53/awsclient"
+	"github.com/ryane/takethe53/awsclient/fake"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testELBZoneID = "Z3DZX7HGU9N41H"
+	testELBOld    = "old-77828384.us-east-1.elb.amazonaws.com"
+	testELBNew    = "new-12345678.us-east-1.elb.amazonaws.com"
+)
+
+func newTestStore(t *testing.T) (*FileStore, func()) {
+	dir, err := ioutil.TempDir("", "journal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := NewFileStore(filepath.Join(dir, "journal.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store, func() { os.RemoveAll(dir) }
+}
+
+func aliasTarget(r53 *fake.Route53, zoneID string) string {
+	for _, rrs := range r53.RecordSets(zoneID) {
+		if aws.StringValue(rrs.Type) == route53.RRTypeA {
+			return aws.StringValue(rrs.AliasTarget.DNSName)
+		}
+	}
+	return ""
+}
+
+func TestJournalUndo(t *testing.T) {
+	ctx := context.Background()
+	store, cleanup := newTestStore(t)
+	defer cleanup()
+
+	r53 := fake.NewRoute53()
+	zoneID, _ := r53.AddZone("example.com")
+	zone := &awsclient.Zone{ID: zoneID, Name: "example.com."}
+
+	client := awsclient.NewWithServices(r53, fake.NewELB())
+	client.AddChangeHandler(Handler(store, "alice", func(err error) { t.Error(err) }))
+
+	_, err := client.SetAlias(zone, testELBZoneID, testELBOld, "www")
+	assert.Nil(t, err)
+	_, err = client.SetAlias(zone, testELBZoneID, testELBNew, "www")
+	assert.Nil(t, err)
+	assert.Equal(t, testELBNew+".", aliasTarget(r53, zoneID))
+
+	entries, err := store.List(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(entries))
+
+	last := entries[1]
+	assert.Equal(t, "alice", last.User)
+	assert.Equal(t, awsclient.OpSetAlias, last.Op)
+	assert.Equal(t, zoneID, last.ZoneID)
+	assert.NotEmpty(t, last.ChangeID)
+	assert.Equal(t, 1, len(last.Before), "upsert records the replaced alias")
+	assert.Equal(t, 1, len(last.After))
+	assert.Equal(t, []string{"www.example.com."}, last.Names())
+
+	found, err := Find(ctx, store, last.ID[:len(last.ID)-2])
+	assert.Nil(t, err)
+	assert.Equal(t, last.ID, found.ID)
+
+	_, err = client.ChangeRecordSets(ctx, zone, OpUndo, last.Inverse())
+	assert.Nil(t, err)
+	assert.Equal(t, testELBOld+".", aliasTarget(r53, zoneID))
+
+	// undoing the first entry removes the alias again
+	_, err = client.ChangeRecordSets(ctx, zone, OpUndo, entries[0].Inverse())
+	assert.Nil(t, err)
+	assert.Equal(t, "", aliasTarget(r53, zoneID))
+
+	entries, _ = store.List(ctx)
+	assert.Equal(t, 4, len(entries), "undos are journaled too")
+}
+
+func TestJournalUndoConflict(t *testing.T) {
+	ctx := context.Background()
+	store, cleanup := newTestStore(t)
+	defer cleanup()
+
+	r53 := fake.NewRoute53()
+	zoneID, _ := r53.AddZone("example.com")
+	zone := &awsclient.Zone{ID: zoneID, Name: "example.com."}
+
+	client := awsclient.NewWithServices(r53, fake.NewELB())
+	client.AddChangeHandler(Handler(store, "alice", nil))
+
+	client.SetAlias(zone, testELBZoneID, testELBOld, "www")
+	entries, _ := store.List(ctx)
+
+	// changed by someone without a journal
+	awsclient.NewWithServices(r53, fake.NewELB()).SetAlias(zone, testELBZoneID, testELBNew, "www")
+
+	_, err := client.ChangeRecordSets(ctx, zone, OpUndo, entries[0].Inverse())
+	assert.NotNil(t, err)
+	assert.Equal(t, testELBNew+".", aliasTarget(r53, zoneID))
+}
+
+func TestJournalSkipsFailedChanges(t *testing.T) {
+	ctx := context.Background()
+	store, cleanup := newTestStore(t)
+	defer cleanup()
+
+	r53 := fake.NewRoute53()
+	zoneID, _ := r53.AddZone("example.com")
+	zone := &awsclient.Zone{ID: zoneID, Name: "example.com."}
+
+	client := awsclient.NewWithServices(r53, fake.NewELB())
+	client.AddChangeHandler(Handler(store, "alice", nil))
+
+	_, err := client.RemoveAlias(zone, "www")
+	assert.Equal(t, awsclient.ErrRecordNotFound, err)
+
+	_, err = client.SetAliasWithOptions(ctx, zone, testELBZoneID, testELBOld, "www", awsclient.AliasOptions{ExpectTarget: testELBNew})
+	assert.Equal(t, awsclient.ErrConflict, err)
+
+	entries, _ := store.List(ctx)
+	assert.Equal(t, 0, len(entries))
+}
+
+func TestFindEntry(t *testing.T) {
+	ctx := context.Background()
+	store, cleanup := newTestStore(t)
+	defer cleanup()
+
+	store.Append(ctx, &Entry{ID: "20170101T000000Z-aaaaaa"})
+	store.Append(ctx, &Entry{ID: "20170101T000000Z-aabbbb"})
+
+	_, err := Find(ctx, store, "20170101T000000Z-aa")
+	assert.Equal(t, ErrAmbiguousEntry, err)
+
+	_, err = Find(ctx, store, "2018")
+	assert.Equal(t, ErrEntryNotFound, err)
+
+	e, err := Find(ctx, store, "20170101T000000Z-aab")
+	assert.Nil(t, err)
+	assert.Equal(t, "20170101T000000Z-aabbbb", e.ID)
+}
+
+func TestParseS3URL(t *testing.T) {
+	bucket, prefix := parseS3URL("s3://my-bucket/takethe53/journal/")
+	assert.Equal(t, "my-bucket", bucket)
+	assert.Equal(t, "takethe53/journal", prefix)
+
+	bucket, prefix = parseS3URL("s3://my-bucket")
+	assert.Equal(t, "my-bucket", bucket)
+	assert.Equal(t, "", prefix)
+}
+
+func TestJournalMarksIncompleteEntries(t *testing.T) {
+	ctx := context.Background()
+	store, cleanup := newTestStore(t)
+	defer cleanup()
+
+	r53 := fake.NewRoute53()
+	zoneID, _ := r53.AddZone("example.com")
+	zone := &awsclient.Zone{ID: zoneID, Name: "example.com."}
+
+	client := awsclient.NewWithServices(r53, fake.NewELB())
+	client.AddChangeHandler(Handler(store, "alice", func(err error) { t.Error(err) }))
+
+	_, err := client.SetAlias(zone, testELBZoneID, testELBOld, "www")
+	assert.Nil(t, err)
+
+	r53.InjectError("ListResourceRecordSets", awserr.New("AccessDenied", "denied", nil))
+	_, err = client.ChangeRecordSets(ctx, zone, awsclient.OpSetAlias, []*route53.Change{{
+		Action: aws.String(route53.ChangeActionUpsert),
+		ResourceRecordSet: &route53.ResourceRecordSet{
+			Name: aws.String("www.example.com."),
+			Type: aws.String(route53.RRTypeA),
+			AliasTarget: &route53.AliasTarget{
+				HostedZoneId:         aws.String(testELBZoneID),
+				DNSName:              aws.String(testELBNew + "."),
+				EvaluateTargetHealth: aws.Bool(false),
+			},
+		},
+	}})
+	assert.Nil(t, err)
+	assert.Equal(t, testELBNew+".", aliasTarget(r53, zoneID))
+
+	entries, err := store.List(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(entries))
+	assert.False(t, entries[0].Incomplete)
+	assert.True(t, entries[1].Incomplete, "the replaced alias could not be read")
+	assert.Equal(t, 0, len(entries[1].Before))
+}
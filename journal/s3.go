@@ -0,0 +1,108 @@
+package journal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"path"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+type S3er interface {
+	PutObjectWithContext(aws.Context, *s3.PutObjectInput, ...request.Option) (*s3.PutObjectOutput, error)
+	GetObjectWithContext(aws.Context, *s3.GetObjectInput, ...request.Option) (*s3.GetObjectOutput, error)
+	ListObjectsV2PagesWithContext(aws.Context, *s3.ListObjectsV2Input, func(*s3.ListObjectsV2Output, bool) bool, ...request.Option) error
+}
+
+// S3Store keeps every journal entry in its own object under prefix, so
+// concurrent writers never overwrite each other. Entry IDs sort by time,
+// which keeps the object listing in order.
+type S3Store struct {
+	s3     S3er
+	bucket string
+	prefix string
+}
+
+func NewS3Store(bucket, prefix string) *S3Store {
+	return NewS3StoreWithService(s3.New(session.New()), bucket, prefix)
+}
+
+func NewS3StoreWithService(svc S3er, bucket, prefix string) *S3Store {
+	return &S3Store{s3: svc, bucket: bucket, prefix: prefix}
+}
+
+func (s *S3Store) Append(ctx context.Context, e *Entry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.s3.PutObjectWithContext(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(path.Join(s.prefix, e.ID+".json")),
+		Body:        bytes.NewReader(data),
+		ContentType: aws.String("application/json"),
+	})
+	return err
+}
+
+func (s *S3Store) List(ctx context.Context) ([]*Entry, error) {
+	params := &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+	}
+	if s.prefix != "" {
+		params.Prefix = aws.String(strings.TrimSuffix(s.prefix, "/") + "/")
+	}
+
+	var keys []string
+	err := s.s3.ListObjectsV2PagesWithContext(ctx, params, func(o *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, obj := range o.Contents {
+			if strings.HasSuffix(aws.StringValue(obj.Key), ".json") {
+				keys = append(keys, aws.StringValue(obj.Key))
+			}
+		}
+		return !lastPage
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []*Entry
+	for _, key := range keys {
+		out, err := s.s3.GetObjectWithContext(ctx, &s3.GetObjectInput{
+			Bucket: aws.String(s.bucket),
+			Key:    aws.String(key),
+		})
+		if err != nil {
+			return nil, err
+		}
+		data, err := ioutil.ReadAll(out.Body)
+		out.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		e := &Entry{}
+		if err := json.Unmarshal(data, e); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+
+	return entries, nil
+}
+
+// parseS3URL splits s3://bucket/prefix into its parts.
+func parseS3URL(url string) (string, string) {
+	parts := strings.SplitN(strings.TrimPrefix(url, "s3://"), "/", 2)
+	if len(parts) == 1 {
+		return parts[0], ""
+	}
+	return parts[0], strings.Trim(parts[1], "/")
+}
@@ -0,0 +1,93 @@
+// Package metrics exports awsclient measurements to Prometheus.
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const namespace = "takethe53"
+
+// Prometheus implements awsclient.Metrics.
+type Prometheus struct {
+	apiCalls       *prometheus.CounterVec
+	apiDuration    *prometheus.HistogramVec
+	retries        *prometheus.CounterVec
+	cacheLookups   *prometheus.CounterVec
+	changes        *prometheus.CounterVec
+	inSyncDuration prometheus.Histogram
+}
+
+// New creates the collectors and registers them with reg.
+func New(reg prometheus.Registerer) *Prometheus {
+	p := &Prometheus{
+		apiCalls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "aws_api_calls_total",
+			Help:      "AWS API calls by service, operation and error code.",
+		}, []string{"service", "operation", "code"}),
+		apiDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "aws_api_call_duration_seconds",
+			Help:      "Duration of AWS API calls, including SDK retries.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"service", "operation"}),
+		retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "route53_throttle_retries_total",
+			Help:      "Retries of throttled Route53 requests by operation and error code.",
+		}, []string{"operation", "code"}),
+		cacheLookups: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "cache_lookups_total",
+			Help:      "Lookup cache hits and misses.",
+		}, []string{"result"}),
+		changes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "changes_submitted_total",
+			Help:      "Route53 change batches submitted by operation and result.",
+		}, []string{"operation", "result"}),
+		inSyncDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "change_insync_duration_seconds",
+			Help:      "Time from submitting a change until Route53 reports it INSYNC.",
+			Buckets:   []float64{5, 10, 20, 30, 45, 60, 90, 120, 180, 300, 600},
+		}),
+	}
+
+	reg.MustRegister(p.apiCalls, p.apiDuration, p.retries, p.cacheLookups, p.changes, p.inSyncDuration)
+	return p
+}
+
+func (p *Prometheus) APICall(service, op, code string, d time.Duration) {
+	if code == "" {
+		code = "OK"
+	}
+	p.apiCalls.WithLabelValues(service, op, code).Inc()
+	p.apiDuration.WithLabelValues(service, op).Observe(d.Seconds())
+}
+
+func (p *Prometheus) Retry(op, code string) {
+	p.retries.WithLabelValues(op, code).Inc()
+}
+
+func (p *Prometheus) CacheLookup(hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	p.cacheLookups.WithLabelValues(result).Inc()
+}
+
+func (p *Prometheus) ChangeSubmitted(op string, err error) {
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	p.changes.WithLabelValues(op, result).Inc()
+}
+
+func (p *Prometheus) ChangeInSync(d time.Duration) {
+	p.inSyncDuration.Observe(d.Seconds())
+}
@@ -0,0 +1,43 @@
+package metrics
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/ryane/takethe53/awsclient"
+	"github.com/stretchr/testify/assert"
+)
+
+var _ awsclient.Metrics = &Prometheus{}
+
+func TestPrometheus(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	p := New(reg)
+
+	p.APICall("route53", "ListHostedZones", "", 100*time.Millisecond)
+	p.APICall("route53", "ListHostedZones", "Throttling", 10*time.Millisecond)
+	p.Retry("ListHostedZones", "Throttling")
+	p.CacheLookup(true)
+	p.CacheLookup(false)
+	p.ChangeSubmitted(awsclient.OpSetAlias, nil)
+	p.ChangeSubmitted(awsclient.OpSetAlias, errors.New("boom"))
+	p.ChangeInSync(40 * time.Second)
+
+	families, err := reg.Gather()
+	assert.Nil(t, err)
+
+	series := map[string]int{}
+	for _, f := range families {
+		series[f.GetName()] = len(f.GetMetric())
+	}
+	assert.Equal(t, map[string]int{
+		"takethe53_aws_api_calls_total":            2,
+		"takethe53_aws_api_call_duration_seconds":  1,
+		"takethe53_route53_throttle_retries_total": 1,
+		"takethe53_cache_lookups_total":            2,
+		"takethe53_changes_submitted_total":        2,
+		"takethe53_change_insync_duration_seconds": 1,
+	}, series)
+}
@@ -0,0 +1,360 @@
+// Package notify posts DNS change events to webhooks.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/ryane/takethe53/awsclient"
+)
+
+// Event types.
+const (
+	// EventChange is sent for every change Route53 accepted.
+	EventChange = "change"
+	// EventSyncTimeout is sent when a change is not INSYNC in time.
+	EventSyncTimeout = "sync_timeout"
+)
+
+// Webhook formats.
+const (
+	FormatJSON  = "json"
+	FormatSlack = "slack"
+)
+
+// Signed deliveries carry the hex HMAC-SHA256 of "<timestamp>.<body>" in
+// SignatureHeader, and the Unix timestamp in TimestampHeader. Receivers
+// should reject old timestamps to prevent replays.
+const (
+	SignatureHeader = "X-Takethe53-Signature"
+	TimestampHeader = "X-Takethe53-Timestamp"
+	EventHeader     = "X-Takethe53-Event"
+	DeliveryHeader  = "X-Takethe53-Delivery"
+)
+
+const (
+	DefaultMaxAttempts = 5
+	DefaultBackoff     = time.Second
+	DefaultTimeout     = 10 * time.Second
+)
+
+// submittedRetention is how long submitted changes are remembered for
+// SyncTimeout.
+const submittedRetention = time.Hour
+
+// Event is the JSON payload of a webhook.
+type Event struct {
+	ID       string    `json:"id"`
+	Type     string    `json:"type"`
+	Time     time.Time `json:"time"`
+	User     string    `json:"user,omitempty"`
+	Op       string    `json:"op,omitempty"`
+	Zone     string    `json:"zone"`
+	ChangeID string    `json:"change_id"`
+	Status   string    `json:"status,omitempty"`
+	Changes  []Change  `json:"changes,omitempty"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// Change is a single record set change of an event.
+type Change struct {
+	Action string   `json:"action"`
+	Name   string   `json:"name"`
+	Type   string   `json:"type"`
+	Values []string `json:"values"`
+}
+
+// Webhook is an entry of the "webhooks" list in the config file.
+type Webhook struct {
+	URL string `mapstructure:"url"`
+	// Secret signs the payloads, unsigned if empty.
+	Secret string `mapstructure:"secret"`
+	// Format is "json" (the default) or "slack" for Slack incoming
+	// webhooks.
+	Format string `mapstructure:"format"`
+	// Events to send, all if empty.
+	Events []string `mapstructure:"events"`
+}
+
+func (w *Webhook) wants(eventType string) bool {
+	if len(w.Events) == 0 {
+		return true
+	}
+	for _, e := range w.Events {
+		if e == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Notifier delivers events to webhooks in the background.
+type Notifier struct {
+	hooks []*Webhook
+	user  string
+
+	// MaxAttempts is how often a delivery is tried.
+	MaxAttempts int
+	// Backoff is the delay before the first retry, doubled for every
+	// further retry.
+	Backoff time.Duration
+	Client  *http.Client
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu        sync.Mutex
+	submitted map[string]*Event
+}
+
+func New(hooks []*Webhook, user string) *Notifier {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Notifier{
+		hooks:       hooks,
+		user:        user,
+		MaxAttempts: DefaultMaxAttempts,
+		Backoff:     DefaultBackoff,
+		Client:      &http.Client{Timeout: DefaultTimeout},
+		ctx:         ctx,
+		cancel:      cancel,
+		submitted:   map[string]*Event{},
+	}
+}
+
+// Handler returns a change handler that sends an EventChange for every
+// successful change.
+func (n *Notifier) Handler() awsclient.ChangeHandler {
+	return func(ctx context.Context, ev *awsclient.ChangeEvent) {
+		if ev.Err != nil {
+			return
+		}
+		e := NewChangeEvent(ev, n.user)
+		n.remember(e)
+		n.Send(e)
+	}
+}
+
+func (n *Notifier) remember(e *Event) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	for id, old := range n.submitted {
+		if time.Since(old.Time) > submittedRetention {
+			delete(n.submitted, id)
+		}
+	}
+	n.submitted[e.ChangeID] = e
+}
+
+// SyncTimeout sends an EventSyncTimeout for a change that is not INSYNC in
+// time. The change's zone, op and records are filled in if it was submitted
+// through Handler.
+func (n *Notifier) SyncTimeout(changeID string) {
+	e := &Event{
+		ID:       newID(),
+		Type:     EventSyncTimeout,
+		Time:     time.Now().UTC(),
+		User:     n.user,
+		ChangeID: changeID,
+		Status:   awsclient.ChangeStatusPending,
+		Error:    "Change was not INSYNC in time.",
+	}
+
+	n.mu.Lock()
+	if submitted, ok := n.submitted[changeID]; ok {
+		e.Op = submitted.Op
+		e.Zone = submitted.Zone
+		e.Changes = submitted.Changes
+		delete(n.submitted, changeID)
+	}
+	n.mu.Unlock()
+
+	n.Send(e)
+}
+
+// Send delivers ev to every webhook that wants it, without waiting for the
+// deliveries.
+func (n *Notifier) Send(ev *Event) {
+	for _, hook := range n.hooks {
+		if !hook.wants(ev.Type) {
+			continue
+		}
+		n.wg.Add(1)
+		go func(hook *Webhook) {
+			defer n.wg.Done()
+			if err := n.deliver(n.ctx, hook, ev); err != nil {
+				logrus.WithFields(logrus.Fields{"type": "webhook", "url": hook.URL, "event": ev.ID}).Warn("Error delivering webhook: ", err)
+			}
+		}(hook)
+	}
+}
+
+// Close waits for pending deliveries. When ctx is done first, the remaining
+// deliveries are canceled.
+func (n *Notifier) Close(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		n.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		n.cancel()
+		<-done
+		return ctx.Err()
+	}
+}
+
+func (n *Notifier) deliver(ctx context.Context, hook *Webhook, ev *Event) error {
+	body, err := Payload(hook.Format, ev)
+	if err != nil {
+		return err
+	}
+
+	backoff := n.Backoff
+	for attempt := 1; ; attempt++ {
+		retry, err := n.post(ctx, hook, ev, body)
+		if err == nil || !retry || attempt >= n.MaxAttempts {
+			return err
+		}
+
+		logrus.WithFields(logrus.Fields{"type": "webhook", "url": hook.URL, "event": ev.ID, "attempt": attempt}).Debug("Retrying webhook: ", err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// post sends a single delivery and reports whether a failure is worth
+// retrying.
+func (n *Notifier) post(ctx context.Context, hook *Webhook, ev *Event, body []byte) (bool, error) {
+	req, err := http.NewRequest("POST", hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, ev.Type)
+	req.Header.Set(DeliveryHeader, ev.ID)
+	if hook.Secret != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(TimestampHeader, timestamp)
+		req.Header.Set(SignatureHeader, "sha256="+Sign(hook.Secret, timestamp, body))
+	}
+
+	resp, err := n.Client.Do(req.WithContext(ctx))
+	if err != nil {
+		return ctx.Err() == nil, err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		retry := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+		return retry, fmt.Errorf("Webhook returned %s.", resp.Status)
+	}
+	return false, nil
+}
+
+// Sign returns the hex HMAC-SHA256 signature of a delivery.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Payload returns the body posted to a webhook with the given format.
+func Payload(format string, ev *Event) ([]byte, error) {
+	switch format {
+	case "", FormatJSON:
+		return json.Marshal(ev)
+	case FormatSlack:
+		return json.Marshal(map[string]string{"text": SlackText(ev)})
+	}
+	return nil, fmt.Errorf("Unknown webhook format %q.", format)
+}
+
+// SlackText formats an event as a Slack message.
+func SlackText(ev *Event) string {
+	var b bytes.Buffer
+	switch ev.Type {
+	case EventSyncTimeout:
+		fmt.Fprintf(&b, ":warning: Change `%s` in *%s* is not INSYNC yet", ev.ChangeID, ev.Zone)
+	default:
+		fmt.Fprintf(&b, "*%s* in *%s*", ev.Op, ev.Zone)
+	}
+	if ev.User != "" {
+		fmt.Fprintf(&b, " by %s", ev.User)
+	}
+	for _, c := range ev.Changes {
+		fmt.Fprintf(&b, "\n• %s `%s` %s %s", c.Action, c.Name, c.Type, strings.Join(c.Values, " "))
+	}
+	return b.String()
+}
+
+// NewChangeEvent converts a change of the client.
+func NewChangeEvent(ev *awsclient.ChangeEvent, user string) *Event {
+	e := &Event{
+		ID:   newID(),
+		Type: EventChange,
+		Time: ev.Time.UTC(),
+		User: user,
+		Op:   ev.Op,
+		Zone: ev.Zone.Name,
+	}
+	if ev.Status != nil {
+		e.ChangeID = ev.Status.ID
+		e.Status = ev.Status.Status
+	}
+
+	for _, c := range ev.Changes {
+		rrs := c.ResourceRecordSet
+		if awsclient.IsOwnershipRecord(rrs) {
+			continue
+		}
+		e.Changes = append(e.Changes, Change{
+			Action: aws.StringValue(c.Action),
+			Name:   aws.StringValue(rrs.Name),
+			Type:   aws.StringValue(rrs.Type),
+			Values: values(rrs),
+		})
+	}
+	return e
+}
+
+func values(rrs *route53.ResourceRecordSet) []string {
+	if rrs.AliasTarget != nil {
+		return []string{"ALIAS " + aws.StringValue(rrs.AliasTarget.DNSName)}
+	}
+	values := []string{}
+	for _, rr := range rrs.ResourceRecords {
+		values = append(values, aws.StringValue(rr.Value))
+	}
+	return values
+}
+
+func newID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
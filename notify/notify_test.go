@@ -0,0 +1,157 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/ryane/takethe53/awsclient"
+	"github.com/stretchr/testify/assert"
+)
+
+type delivery struct {
+	header http.Header
+	body   []byte
+}
+
+// receiver is a webhook endpoint that fails the first failures requests.
+type receiver struct {
+	mu         sync.Mutex
+	failures   int
+	attempts   int
+	deliveries []delivery
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	rc.attempts++
+	if rc.attempts <= rc.failures {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	body, _ := ioutil.ReadAll(r.Body)
+	rc.deliveries = append(rc.deliveries, delivery{r.Header, body})
+}
+
+func changeEvent() *awsclient.ChangeEvent {
+	return &awsclient.ChangeEvent{
+		Op:   awsclient.OpSetAlias,
+		Zone: &awsclient.Zone{ID: "Z1", Name: "example.com."},
+		Changes: []*route53.Change{{
+			Action: aws.String("UPSERT"),
+			ResourceRecordSet: &route53.ResourceRecordSet{
+				Name: aws.String("www.example.com."),
+				Type: aws.String("A"),
+				AliasTarget: &route53.AliasTarget{
+					DNSName:      aws.String("web-1.us-east-1.elb.amazonaws.com"),
+					HostedZoneId: aws.String("Z35SXDOTRQ7X7K"),
+				},
+			},
+		}},
+		Status: &awsclient.ChangeStatus{ID: "/change/C1", Status: awsclient.ChangeStatusPending},
+		Time:   time.Now(),
+	}
+}
+
+func TestNotifier(t *testing.T) {
+	rc := &receiver{failures: 2}
+	srv := httptest.NewServer(rc)
+	defer srv.Close()
+
+	slack := &receiver{}
+	slackSrv := httptest.NewServer(slack)
+	defer slackSrv.Close()
+
+	n := New([]*Webhook{
+		{URL: srv.URL, Secret: "s3cr3t"},
+		{URL: slackSrv.URL, Format: FormatSlack, Events: []string{EventSyncTimeout}},
+	}, "ryan")
+	n.Backoff = time.Millisecond
+
+	handler := n.Handler()
+	handler(context.Background(), changeEvent())
+	failed := changeEvent()
+	failed.Err = awsclient.ErrConflict
+	handler(context.Background(), failed)
+	n.SyncTimeout("/change/C1")
+
+	assert.Nil(t, n.Close(context.Background()))
+
+	// the failed change is not sent, the first delivery was retried
+	assert.Len(t, rc.deliveries, 2)
+	assert.Equal(t, 4, rc.attempts)
+
+	d := rc.deliveries[0]
+	var ev Event
+	assert.Nil(t, json.Unmarshal(d.body, &ev))
+	assert.Equal(t, "application/json", d.header.Get("Content-Type"))
+	assert.Equal(t, "sha256="+Sign("s3cr3t", d.header.Get(TimestampHeader), d.body), d.header.Get(SignatureHeader))
+	assert.Equal(t, ev.Type, d.header.Get(EventHeader))
+	assert.Equal(t, ev.ID, d.header.Get(DeliveryHeader))
+
+	var types []string
+	for _, d := range rc.deliveries {
+		var ev Event
+		json.Unmarshal(d.body, &ev)
+		types = append(types, ev.Type)
+		assert.Equal(t, "example.com.", ev.Zone)
+		assert.Equal(t, "/change/C1", ev.ChangeID)
+		assert.Equal(t, []Change{{Action: "UPSERT", Name: "www.example.com.", Type: "A", Values: []string{"ALIAS web-1.us-east-1.elb.amazonaws.com"}}}, ev.Changes)
+	}
+	sort.Strings(types)
+	assert.Equal(t, []string{EventChange, EventSyncTimeout}, types)
+
+	// only the sync timeout goes to slack
+	assert.Len(t, slack.deliveries, 1)
+	var msg map[string]string
+	assert.Nil(t, json.Unmarshal(slack.deliveries[0].body, &msg))
+	assert.Contains(t, msg["text"], "not INSYNC")
+	assert.Contains(t, msg["text"], "`www.example.com.`")
+	assert.Empty(t, slack.deliveries[0].header.Get(SignatureHeader))
+}
+
+func TestNotifierGivesUp(t *testing.T) {
+	rc := &receiver{failures: 100}
+	srv := httptest.NewServer(rc)
+	defer srv.Close()
+
+	n := New([]*Webhook{{URL: srv.URL}}, "ryan")
+	n.Backoff = time.Millisecond
+	n.MaxAttempts = 3
+
+	n.Send(&Event{ID: "1", Type: EventChange})
+	assert.Nil(t, n.Close(context.Background()))
+	assert.Equal(t, 3, rc.attempts)
+	assert.Len(t, rc.deliveries, 0)
+
+	// client errors are not retried
+	bad := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rc.mu.Lock()
+		rc.attempts++
+		rc.mu.Unlock()
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer bad.Close()
+
+	rc.attempts = 0
+	n = New([]*Webhook{{URL: bad.URL}}, "ryan")
+	n.Backoff = time.Millisecond
+	n.Send(&Event{ID: "2", Type: EventChange})
+	assert.Nil(t, n.Close(context.Background()))
+	assert.Equal(t, 1, rc.attempts)
+}
+
+func TestPayload(t *testing.T) {
+	_, err := Payload("xml", &Event{})
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,187 @@
+// Package policy checks change batches against rules from the config file
+// before they are submitted to Route53.
+package policy
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/ryane/takethe53/awsclient"
+	"github.com/ryane/takethe53/recordset"
+)
+
+// Rule names.
+const (
+	RuleProtectApex        = "protect-apex"
+	RuleConfirm            = "confirm"
+	RuleAliasHostedZoneIDs = "alias-hosted-zone-ids"
+	RuleMaxDeletions       = "max-deletions"
+)
+
+// Config is the "policy" section of the config file. The zero value allows
+// everything.
+type Config struct {
+	// ProtectApex rejects deleting records at the zone apex.
+	ProtectApex bool `mapstructure:"protect-apex"`
+	// Confirm are patterns of names, like "*.prod.example.com", that can
+	// only be changed with a confirmation.
+	Confirm []string `mapstructure:"confirm"`
+	// AliasHostedZoneIDs, if set, are the only hosted zones alias records
+	// may point at, besides the record's own zone.
+	AliasHostedZoneIDs []string `mapstructure:"alias-hosted-zone-ids"`
+	// MaxDeletions, if set, is the most records a single batch may delete.
+	MaxDeletions int `mapstructure:"max-deletions"`
+}
+
+// Violation is the error for a change batch that breaks a rule.
+type Violation struct {
+	Rule    string
+	Name    string
+	Message string
+}
+
+func (v *Violation) Error() string {
+	return fmt.Sprintf("Policy %s: %s", v.Rule, v.Message)
+}
+
+// IsViolation reports whether err is a policy violation.
+func IsViolation(err error) bool {
+	_, ok := err.(*Violation)
+	return ok
+}
+
+type confirmedKey struct{}
+
+// WithConfirmation returns a context whose changes to protected names are
+// confirmed.
+func WithConfirmation(ctx context.Context) context.Context {
+	return context.WithValue(ctx, confirmedKey{}, true)
+}
+
+// Confirmed reports whether changes in ctx are confirmed.
+func Confirmed(ctx context.Context) bool {
+	confirmed, _ := ctx.Value(confirmedKey{}).(bool)
+	return confirmed
+}
+
+type Policy struct {
+	cfg Config
+}
+
+func New(cfg Config) *Policy {
+	return &Policy{cfg: cfg}
+}
+
+// Validator returns a change validator that enforces the policy.
+func (p *Policy) Validator() awsclient.ChangeValidator {
+	return func(ctx context.Context, ev *awsclient.ChangeEvent) error {
+		return p.Check(ctx, ev.Zone, ev.Changes)
+	}
+}
+
+// Protected reports whether changes to name need a confirmation.
+func (p *Policy) Protected(name string) bool {
+	for _, pattern := range p.cfg.Confirm {
+		if ok, _ := path.Match(normalizeName(pattern), normalizeName(name)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Check returns a *Violation for the first rule the batch breaks.
+func (p *Policy) Check(ctx context.Context, zone *awsclient.Zone, changes []*route53.Change) error {
+	deletions := 0
+	for _, c := range deleted(changes) {
+		rrs := c.ResourceRecordSet
+		name := aws.StringValue(rrs.Name)
+
+		if p.cfg.ProtectApex && normalizeName(name) == normalizeName(zone.Name) {
+			return &Violation{
+				Rule:    RuleProtectApex,
+				Name:    name,
+				Message: fmt.Sprintf("Deleting the apex record %s %s is not allowed.", name, aws.StringValue(rrs.Type)),
+			}
+		}
+		deletions++
+	}
+
+	if p.cfg.MaxDeletions > 0 && deletions > p.cfg.MaxDeletions {
+		return &Violation{
+			Rule:    RuleMaxDeletions,
+			Message: fmt.Sprintf("The batch deletes %d records, more than the maximum of %d.", deletions, p.cfg.MaxDeletions),
+		}
+	}
+
+	for _, c := range changes {
+		rrs := c.ResourceRecordSet
+		name := aws.StringValue(rrs.Name)
+
+		if p.Protected(name) && !Confirmed(ctx) {
+			return &Violation{
+				Rule:    RuleConfirm,
+				Name:    name,
+				Message: fmt.Sprintf("%s is protected. Confirm the change with --confirm.", name),
+			}
+		}
+
+		if aws.StringValue(c.Action) != route53.ChangeActionDelete && rrs.AliasTarget != nil && !p.allowedAliasZone(zone, aws.StringValue(rrs.AliasTarget.HostedZoneId)) {
+			return &Violation{
+				Rule:    RuleAliasHostedZoneIDs,
+				Name:    name,
+				Message: fmt.Sprintf("Alias targets in hosted zone %s are not allowed for %s.", aws.StringValue(rrs.AliasTarget.HostedZoneId), name),
+			}
+		}
+	}
+
+	return nil
+}
+
+// allowedAliasZone compares IDs without the "/hostedzone/" prefix, which
+// zone IDs have and alias targets don't.
+func (p *Policy) allowedAliasZone(zone *awsclient.Zone, hostedZoneID string) bool {
+	hostedZoneID = trimZoneID(hostedZoneID)
+	if len(p.cfg.AliasHostedZoneIDs) == 0 || hostedZoneID == trimZoneID(zone.ID) {
+		return true
+	}
+	for _, id := range p.cfg.AliasHostedZoneIDs {
+		if trimZoneID(id) == hostedZoneID {
+			return true
+		}
+	}
+	return false
+}
+
+// deleted returns the DELETE changes that remove a record. Deletes of
+// ownership records, and deletes that are replaced by a CREATE in the same
+// batch, don't count.
+func deleted(changes []*route53.Change) []*route53.Change {
+	created := map[string]bool{}
+	for _, c := range changes {
+		if aws.StringValue(c.Action) == route53.ChangeActionCreate {
+			created[recordset.Key(c.ResourceRecordSet)] = true
+		}
+	}
+
+	var deletes []*route53.Change
+	for _, c := range changes {
+		rrs := c.ResourceRecordSet
+		if aws.StringValue(c.Action) != route53.ChangeActionDelete || created[recordset.Key(rrs)] || awsclient.IsOwnershipRecord(rrs) {
+			continue
+		}
+		deletes = append(deletes, c)
+	}
+	return deletes
+}
+
+func normalizeName(name string) string {
+	return strings.ToLower(strings.TrimSuffix(name, ".")) + "."
+}
+
+func trimZoneID(id string) string {
+	return strings.TrimPrefix(id, "/hostedzone/")
+}
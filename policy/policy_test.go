@@ -0,0 +1,143 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/ryane/takethe53/awsclient"
+	"github.com/ryane/takethe53/awsclient/fake"
+	"github.com/stretchr/testify/assert"
+)
+
+const elbZoneID = "Z35SXDOTRQ7X7K"
+
+func newClient(cfg Config) (*awsclient.AWSClient, *fake.Route53, *awsclient.Zone) {
+	r53 := fake.NewRoute53()
+	zoneID, _ := r53.AddZone("example.com")
+	client := awsclient.NewWithServices(r53, fake.NewELB())
+	client.AddChangeValidator(New(cfg).Validator())
+	return client, r53, &awsclient.Zone{ID: zoneID, Name: "example.com."}
+}
+
+func assertViolation(t *testing.T, rule string, err error) {
+	if assert.True(t, IsViolation(err), "expected a violation, got %v", err) {
+		assert.Equal(t, rule, err.(*Violation).Rule)
+	}
+}
+
+func cname(name string) *route53.ResourceRecordSet {
+	return &route53.ResourceRecordSet{
+		Name:            aws.String(name),
+		Type:            aws.String("CNAME"),
+		TTL:             aws.Int64(300),
+		ResourceRecords: []*route53.ResourceRecord{{Value: aws.String("example.org")}},
+	}
+}
+
+func TestProtectApex(t *testing.T) {
+	client, _, zone := newClient(Config{ProtectApex: true})
+
+	_, err := client.SetAlias(zone, elbZoneID, "web-1.us-east-1.elb.amazonaws.com", "example.com.")
+	assert.Nil(t, err)
+
+	// replacing the apex alias is fine
+	_, err = client.SetAliasWithOptions(context.Background(), zone, elbZoneID, "web-2.us-east-1.elb.amazonaws.com", "example.com.", awsclient.AliasOptions{ExpectTarget: "web-1.us-east-1.elb.amazonaws.com"})
+	assert.Nil(t, err)
+
+	_, err = client.RemoveAlias(zone, "example.com.")
+	assertViolation(t, RuleProtectApex, err)
+	assert.Contains(t, err.Error(), "apex record example.com. A")
+
+	_, err = client.SetAlias(zone, elbZoneID, "web-1.us-east-1.elb.amazonaws.com", "www")
+	assert.Nil(t, err)
+	_, err = client.RemoveAlias(zone, "www")
+	assert.Nil(t, err)
+}
+
+func TestConfirm(t *testing.T) {
+	client, _, zone := newClient(Config{Confirm: []string{"*.prod.example.com"}})
+
+	var rejected []error
+	client.AddChangeHandler(func(ctx context.Context, ev *awsclient.ChangeEvent) {
+		if ev.Err != nil {
+			rejected = append(rejected, ev.Err)
+		}
+	})
+
+	_, err := client.SetAlias(zone, elbZoneID, "web-1.us-east-1.elb.amazonaws.com", "api.prod")
+	assertViolation(t, RuleConfirm, err)
+	assert.Contains(t, err.Error(), "--confirm")
+	// change handlers see rejected batches
+	assert.Equal(t, []error{err}, rejected)
+
+	_, err = client.SetAliasWithContext(WithConfirmation(context.Background()), zone, elbZoneID, "web-1.us-east-1.elb.amazonaws.com", "api.prod")
+	assert.Nil(t, err)
+
+	_, err = client.RemoveAlias(zone, "API.prod.example.com")
+	assertViolation(t, RuleConfirm, err)
+
+	_, err = client.SetAlias(zone, elbZoneID, "web-1.us-east-1.elb.amazonaws.com", "api.staging")
+	assert.Nil(t, err)
+}
+
+func TestAliasHostedZoneIDs(t *testing.T) {
+	client, _, zone := newClient(Config{AliasHostedZoneIDs: []string{elbZoneID}})
+
+	_, err := client.SetAlias(zone, elbZoneID, "web-1.us-east-1.elb.amazonaws.com", "www")
+	assert.Nil(t, err)
+
+	_, err = client.SetAlias(zone, "Z2FDTNDATAQYW2", "d111111abcdef8.cloudfront.net", "cdn")
+	assertViolation(t, RuleAliasHostedZoneIDs, err)
+	assert.Contains(t, err.Error(), "Z2FDTNDATAQYW2")
+
+	// aliases to records in the same zone, whose targets have bare IDs
+	_, err = client.SetAlias(zone, zone.ID, "www.example.com.", "www2")
+	assert.Nil(t, err)
+	_, err = client.SetAlias(zone, strings.TrimPrefix(zone.ID, "/hostedzone/"), "www.example.com.", "www3")
+	assert.Nil(t, err)
+
+	// configured IDs may have the prefix
+	client, _, zone = newClient(Config{AliasHostedZoneIDs: []string{"/hostedzone/" + elbZoneID}})
+	_, err = client.SetAlias(zone, elbZoneID, "web-1.us-east-1.elb.amazonaws.com", "www")
+	assert.Nil(t, err)
+}
+
+func TestMaxDeletions(t *testing.T) {
+	client, r53, zone := newClient(Config{MaxDeletions: 2})
+
+	var deletes []*route53.Change
+	for i := 0; i < 3; i++ {
+		rrs := cname(fmt.Sprintf("old%d.example.com.", i))
+		assert.Nil(t, r53.AddRecordSet(zone.ID, rrs))
+		deletes = append(deletes, &route53.Change{Action: aws.String(route53.ChangeActionDelete), ResourceRecordSet: rrs})
+	}
+
+	_, err := client.ChangeRecordSets(context.Background(), zone, "restore", deletes)
+	assertViolation(t, RuleMaxDeletions, err)
+	assert.Contains(t, err.Error(), "deletes 3 records, more than the maximum of 2")
+
+	_, err = client.ChangeRecordSets(context.Background(), zone, "restore", deletes[:2])
+	assert.Nil(t, err)
+
+	// a delete replaced by a create in the same batch is not a deletion
+	replaced := cname("old2.example.com.")
+	replaced.ResourceRecords[0].Value = aws.String("example.net")
+	_, err = client.ChangeRecordSets(context.Background(), zone, "restore", []*route53.Change{
+		deletes[2],
+		{Action: aws.String(route53.ChangeActionCreate), ResourceRecordSet: replaced},
+	})
+	assert.Nil(t, err)
+}
+
+func TestZeroConfigAllowsEverything(t *testing.T) {
+	p := New(Config{})
+	zone := &awsclient.Zone{ID: "Z1", Name: "example.com."}
+	assert.Nil(t, p.Check(context.Background(), zone, []*route53.Change{
+		{Action: aws.String(route53.ChangeActionDelete), ResourceRecordSet: cname("example.com.")},
+	}))
+	assert.False(t, p.Protected("www.example.com."))
+}
@@ -0,0 +1,92 @@
+package recordset
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/route53"
+)
+
+// Difference is a record set that differs between two zones.
+type Difference struct {
+	// Name is relative to the zone apex, "@" for the apex itself.
+	Name          string `json:"name"`
+	Type          string `json:"type"`
+	SetIdentifier string `json:"set_identifier,omitempty"`
+	// A and B are the record sets in each zone, nil if the zone doesn't have
+	// it.
+	A *route53.ResourceRecordSet `json:"a,omitempty"`
+	B *route53.ResourceRecordSet `json:"b,omitempty"`
+}
+
+// Compare returns the record sets that differ between zone A and zone B.
+// Names are compared relative to each zone apex, and aliases to records of
+// the zone itself are compared by the relative name they point at. Values,
+// TTLs, alias targets and routing policies must all match.
+func Compare(aName, aID string, a []*route53.ResourceRecordSet, bName, bID string, b []*route53.ResourceRecordSet) []Difference {
+	// move B into A's namespace, keeping the originals for the report
+	renamed := Rename(b, bName, bID, aName, aID)
+	bByKey := map[string]int{}
+	for i, rrs := range renamed {
+		bByKey[Key(rrs)] = i
+	}
+
+	var diffs []Difference
+	seen := map[string]bool{}
+	for _, rrs := range a {
+		key := Key(rrs)
+		seen[key] = true
+		i, ok := bByKey[key]
+		switch {
+		case !ok:
+			diffs = append(diffs, difference(RelativeName(aws.StringValue(rrs.Name), aName), rrs, nil))
+		case !Equal(rrs, renamed[i]):
+			diffs = append(diffs, difference(RelativeName(aws.StringValue(rrs.Name), aName), rrs, b[i]))
+		}
+	}
+	for i, rrs := range renamed {
+		if !seen[Key(rrs)] {
+			diffs = append(diffs, difference(RelativeName(aws.StringValue(rrs.Name), aName), nil, b[i]))
+		}
+	}
+
+	sort.SliceStable(diffs, func(i, j int) bool {
+		if diffs[i].Name != diffs[j].Name {
+			return diffs[i].Name < diffs[j].Name
+		}
+		if diffs[i].Type != diffs[j].Type {
+			return diffs[i].Type < diffs[j].Type
+		}
+		return diffs[i].SetIdentifier < diffs[j].SetIdentifier
+	})
+	return diffs
+}
+
+// RelativeName returns name relative to the zone apex, "@" for the apex.
+// Names outside the zone are returned fully qualified.
+func RelativeName(name, zoneName string) string {
+	name = normalizeName(name)
+	zone := normalizeName(zoneName)
+	switch {
+	case name == zone:
+		return "@"
+	case strings.HasSuffix(name, "."+zone):
+		return strings.TrimSuffix(name, "."+zone)
+	}
+	return name
+}
+
+func difference(name string, a, b *route53.ResourceRecordSet) Difference {
+	rrs := a
+	if rrs == nil {
+		rrs = b
+	}
+	return Difference{
+		Name:          name,
+		Type:          aws.StringValue(rrs.Type),
+		SetIdentifier: aws.StringValue(rrs.SetIdentifier),
+		A:             a,
+		B:             b,
+	}
+}
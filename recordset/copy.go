@@ -0,0 +1,106 @@
+package recordset
+
+import (
+	"path"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awsutil"
+	"github.com/aws/aws-sdk-go/service/route53"
+)
+
+// Match returns the record sets whose names match one of patterns. Patterns
+// are relative to the zone unless they end with a dot, "@" is the apex and *
+// matches any sequence of characters, including dots. Without patterns every
+// record set matches.
+func Match(zoneName string, patterns []string, rrsets []*route53.ResourceRecordSet) []*route53.ResourceRecordSet {
+	if len(patterns) == 0 {
+		return rrsets
+	}
+
+	var matched []*route53.ResourceRecordSet
+	for _, rrs := range rrsets {
+		name := normalizeName(aws.StringValue(rrs.Name))
+		for _, p := range patterns {
+			if ok, _ := path.Match(qualify(p, zoneName), name); ok {
+				matched = append(matched, rrs)
+				break
+			}
+		}
+	}
+	return matched
+}
+
+// Rename copies record sets of one zone for another. Names in the from zone
+// get the suffix of the to zone, and aliases to records in the from zone
+// point at the same names in the to zone, by the to zone ID without the
+// "/hostedzone/" prefix like Route53 returns alias targets. rrsets is not
+// modified.
+func Rename(rrsets []*route53.ResourceRecordSet, fromName, fromID, toName, toID string) []*route53.ResourceRecordSet {
+	var renamed []*route53.ResourceRecordSet
+	for _, rrs := range rrsets {
+		r := awsutil.CopyOf(rrs).(*route53.ResourceRecordSet)
+		r.Name = aws.String(rename(aws.StringValue(r.Name), fromName, toName))
+		if r.AliasTarget != nil && trimZoneID(aws.StringValue(r.AliasTarget.HostedZoneId)) == trimZoneID(fromID) {
+			r.AliasTarget.HostedZoneId = aws.String(trimZoneID(toID))
+			r.AliasTarget.DNSName = aws.String(rename(aws.StringValue(r.AliasTarget.DNSName), fromName, toName))
+		}
+		renamed = append(renamed, r)
+	}
+	return renamed
+}
+
+// Merge returns the changes that add desired to current without deleting
+// anything: missing record sets are created and, with overwrite, record sets
+// with different values are upserted. Record sets that differ and are not
+// overwritten are returned as skipped.
+func Merge(current, desired []*route53.ResourceRecordSet, overwrite bool) (changes []*route53.Change, skipped []*route53.ResourceRecordSet) {
+	desiredKeys := map[string]bool{}
+	for _, rrs := range desired {
+		desiredKeys[Key(rrs)] = true
+	}
+
+	// leave out the record sets we don't touch so Diff doesn't delete them
+	var existing []*route53.ResourceRecordSet
+	for _, rrs := range current {
+		if desiredKeys[Key(rrs)] {
+			existing = append(existing, rrs)
+		}
+	}
+
+	for _, c := range Diff(existing, desired) {
+		if aws.StringValue(c.Action) == route53.ChangeActionUpsert && !overwrite {
+			skipped = append(skipped, c.ResourceRecordSet)
+			continue
+		}
+		changes = append(changes, c)
+	}
+	return changes, skipped
+}
+
+func rename(name, fromName, toName string) string {
+	name = normalizeName(name)
+	from := normalizeName(fromName)
+	switch {
+	case name == from:
+		return normalizeName(toName)
+	case strings.HasSuffix(name, "."+from):
+		return strings.TrimSuffix(name, from) + normalizeName(toName)
+	}
+	return name
+}
+
+func qualify(name, zoneName string) string {
+	zone := normalizeName(zoneName)
+	switch {
+	case name == "@" || name == "":
+		return zone
+	case strings.HasSuffix(name, "."):
+		return strings.ToLower(name)
+	}
+	return strings.ToLower(name) + "." + zone
+}
+
+func trimZoneID(id string) string {
+	return strings.TrimPrefix(id, "/hostedzone/")
+}
@@ -0,0 +1,164 @@
+// Package recordset compares Route53 record sets and builds the change
+// batches that turn one set of records into another.
+package recordset
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awsutil"
+	"github.com/aws/aws-sdk-go/service/route53"
+)
+
+// MaxBatchSize is the number of record values sent in a single change batch.
+// Route53 accepts 1000, counting UPSERTs twice; staying well below that
+// leaves room for the request size limit.
+const MaxBatchSize = 500
+
+// Key identifies a record set within a zone.
+func Key(rrs *route53.ResourceRecordSet) string {
+	return normalizeName(aws.StringValue(rrs.Name)) + " " + aws.StringValue(rrs.Type) + " " + aws.StringValue(rrs.SetIdentifier)
+}
+
+// Equal reports whether two record sets have the same name, type and values.
+// Names and values are compared the way Route53 compares them, ignoring case,
+// the trailing dot and the order of values.
+func Equal(a, b *route53.ResourceRecordSet) bool {
+	return reflect.DeepEqual(normalize(a), normalize(b))
+}
+
+// SameName reports whether two DNS names are equal, ignoring case and the
+// trailing dot.
+func SameName(a, b string) bool {
+	return normalizeName(a) == normalizeName(b)
+}
+
+// Editable returns the record sets that can be changed in a zone, leaving out
+// the SOA and NS records at the apex that Route53 manages.
+func Editable(zoneName string, rrsets []*route53.ResourceRecordSet) []*route53.ResourceRecordSet {
+	apex := normalizeName(zoneName)
+
+	var editable []*route53.ResourceRecordSet
+	for _, rrs := range rrsets {
+		rrType := aws.StringValue(rrs.Type)
+		if normalizeName(aws.StringValue(rrs.Name)) == apex && (rrType == route53.RRTypeSoa || rrType == route53.RRTypeNs) {
+			continue
+		}
+		editable = append(editable, rrs)
+	}
+	return editable
+}
+
+// Diff returns the changes that turn current into desired. Record sets that
+// only exist in current are deleted, record sets that only exist in desired
+// are created and record sets with different values are upserted. Deletes
+// come first so a name can change type, e.g. from CNAME to an A alias.
+func Diff(current, desired []*route53.ResourceRecordSet) []*route53.Change {
+	currentByKey := map[string]*route53.ResourceRecordSet{}
+	for _, rrs := range current {
+		currentByKey[Key(rrs)] = rrs
+	}
+	desiredByKey := map[string]*route53.ResourceRecordSet{}
+	for _, rrs := range desired {
+		desiredByKey[Key(rrs)] = rrs
+	}
+
+	var deletes, upserts, creates []*route53.Change
+	for _, rrs := range current {
+		if _, ok := desiredByKey[Key(rrs)]; !ok {
+			deletes = append(deletes, change(route53.ChangeActionDelete, rrs))
+		}
+	}
+	for _, rrs := range desired {
+		existing, ok := currentByKey[Key(rrs)]
+		switch {
+		case !ok:
+			creates = append(creates, change(route53.ChangeActionCreate, rrs))
+		case !Equal(existing, rrs):
+			upserts = append(upserts, change(route53.ChangeActionUpsert, rrs))
+		}
+	}
+
+	changes := append(deletes, upserts...)
+	return append(changes, creates...)
+}
+
+// Batches splits changes into batches of at most size record values. The
+// changes to one name stay in the same batch, so a record that is deleted and
+// replaced, e.g. a CNAME by an A alias, is never missing when a later batch
+// fails. Names keep the order they first appear in and their changes keep
+// their order.
+func Batches(changes []*route53.Change, size int) [][]*route53.Change {
+	var groups [][]*route53.Change
+	index := map[string]int{}
+	for _, c := range changes {
+		name := normalizeName(aws.StringValue(c.ResourceRecordSet.Name))
+		i, ok := index[name]
+		if !ok {
+			i = len(groups)
+			index[name] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], c)
+	}
+
+	var batches [][]*route53.Change
+	var batch []*route53.Change
+	count := 0
+
+	for _, group := range groups {
+		n := 0
+		for _, c := range group {
+			n += Weight(c)
+		}
+		if len(batch) > 0 && count+n > size {
+			batches = append(batches, batch)
+			batch, count = nil, 0
+		}
+		batch = append(batch, group...)
+		count += n
+	}
+	if len(batch) > 0 {
+		batches = append(batches, batch)
+	}
+
+	return batches
+}
+
+// Weight is what a change counts against the Route53 batch limit.
+func Weight(c *route53.Change) int {
+	n := len(c.ResourceRecordSet.ResourceRecords)
+	if n == 0 {
+		n = 1
+	}
+	if aws.StringValue(c.Action) == route53.ChangeActionUpsert {
+		n *= 2
+	}
+	return n
+}
+
+func change(action string, rrs *route53.ResourceRecordSet) *route53.Change {
+	return &route53.Change{Action: aws.String(action), ResourceRecordSet: rrs}
+}
+
+func normalize(rrs *route53.ResourceRecordSet) *route53.ResourceRecordSet {
+	n := awsutil.CopyOf(rrs).(*route53.ResourceRecordSet)
+	n.Name = aws.String(normalizeName(aws.StringValue(n.Name)))
+	if n.AliasTarget != nil {
+		n.AliasTarget.DNSName = aws.String(normalizeName(aws.StringValue(n.AliasTarget.DNSName)))
+		n.AliasTarget.HostedZoneId = aws.String(trimZoneID(aws.StringValue(n.AliasTarget.HostedZoneId)))
+	}
+	if len(n.ResourceRecords) == 0 {
+		n.ResourceRecords = nil
+	}
+	sort.Slice(n.ResourceRecords, func(i, j int) bool {
+		return aws.StringValue(n.ResourceRecords[i].Value) < aws.StringValue(n.ResourceRecords[j].Value)
+	})
+	return n
+}
+
+func normalizeName(name string) string {
+	return strings.ToLower(strings.TrimSuffix(name, ".")) + "."
+}
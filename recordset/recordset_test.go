@@ -0,0 +1,322 @@
+package recordset
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/ryane/takethe53/awsclient"
+	"github.com/ryane/takethe53/awsclient/fake"
+	"github.com/stretchr/testify/assert"
+)
+
+func a(name string, ttl int64, values ...string) *route53.ResourceRecordSet {
+	rrs := &route53.ResourceRecordSet{
+		Name: aws.String(name),
+		Type: aws.String(route53.RRTypeA),
+		TTL:  aws.Int64(ttl),
+	}
+	for _, v := range values {
+		rrs.ResourceRecords = append(rrs.ResourceRecords, &route53.ResourceRecord{Value: aws.String(v)})
+	}
+	return rrs
+}
+
+func actions(changes []*route53.Change) []string {
+	var out []string
+	for _, c := range changes {
+		out = append(out, aws.StringValue(c.Action)+" "+aws.StringValue(c.ResourceRecordSet.Name))
+	}
+	return out
+}
+
+func TestEqual(t *testing.T) {
+	assert.True(t, Equal(a("www.example.com.", 60, "10.0.0.1", "10.0.0.2"), a("WWW.example.com", 60, "10.0.0.2", "10.0.0.1")))
+	assert.False(t, Equal(a("www.example.com.", 60, "10.0.0.1"), a("www.example.com.", 300, "10.0.0.1")))
+	assert.False(t, Equal(a("www.example.com.", 60, "10.0.0.1"), a("www.example.com.", 60, "10.0.0.2")))
+}
+
+func TestDiff(t *testing.T) {
+	current := []*route53.ResourceRecordSet{
+		a("keep.example.com.", 60, "10.0.0.1"),
+		a("change.example.com.", 60, "10.0.0.1"),
+		a("gone.example.com.", 60, "10.0.0.1"),
+	}
+	desired := []*route53.ResourceRecordSet{
+		a("new.example.com.", 60, "10.0.0.1"),
+		a("keep.example.com", 60, "10.0.0.1"),
+		a("change.example.com.", 60, "10.0.0.2"),
+	}
+
+	assert.Equal(t, []string{
+		"DELETE gone.example.com.",
+		"UPSERT change.example.com.",
+		"CREATE new.example.com.",
+	}, actions(Diff(current, desired)))
+
+	assert.Empty(t, Diff(current, current))
+}
+
+func TestEditable(t *testing.T) {
+	rrsets := []*route53.ResourceRecordSet{
+		{Name: aws.String("example.com."), Type: aws.String(route53.RRTypeSoa)},
+		{Name: aws.String("example.com."), Type: aws.String(route53.RRTypeNs)},
+		{Name: aws.String("sub.example.com."), Type: aws.String(route53.RRTypeNs)},
+		a("example.com.", 60, "10.0.0.1"),
+	}
+
+	editable := Editable("example.com", rrsets)
+	assert.Equal(t, 2, len(editable), "delegations and apex A records are editable")
+}
+
+func TestBatches(t *testing.T) {
+	changes := []*route53.Change{
+		change(route53.ChangeActionDelete, a("1.example.com.", 60, "10.0.0.1", "10.0.0.2")),
+		change(route53.ChangeActionUpsert, a("2.example.com.", 60, "10.0.0.1")),
+		change(route53.ChangeActionCreate, a("3.example.com.", 60, "10.0.0.1")),
+		change(route53.ChangeActionCreate, a("4.example.com.", 60, "10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.4", "10.0.0.5")),
+	}
+
+	batches := Batches(changes, 4)
+	assert.Equal(t, 3, len(batches))
+	assert.Equal(t, []string{"DELETE 1.example.com.", "UPSERT 2.example.com."}, actions(batches[0]))
+	assert.Equal(t, []string{"CREATE 3.example.com."}, actions(batches[1]))
+	assert.Equal(t, []string{"CREATE 4.example.com."}, actions(batches[2]), "oversized changes get a batch of their own")
+}
+
+func TestBatchesKeepNamesTogether(t *testing.T) {
+	cname := &route53.ResourceRecordSet{
+		Name:            aws.String("www.example.com."),
+		Type:            aws.String(route53.RRTypeCname),
+		TTL:             aws.Int64(60),
+		ResourceRecords: []*route53.ResourceRecord{{Value: aws.String("web.example.net")}},
+	}
+	changes := Diff(
+		[]*route53.ResourceRecordSet{cname, a("old.example.com.", 60, "10.0.0.1")},
+		[]*route53.ResourceRecordSet{a("www.example.com.", 60, "10.0.0.2"), a("new.example.com.", 60, "10.0.0.3")},
+	)
+
+	batches := Batches(changes, 2)
+	assert.Equal(t, 2, len(batches))
+	assert.Equal(t, []string{"DELETE www.example.com.", "CREATE www.example.com."}, actions(batches[0]))
+	assert.Equal(t, []string{"DELETE old.example.com.", "CREATE new.example.com."}, actions(batches[1]))
+}
+
+func names(rrsets []*route53.ResourceRecordSet) []string {
+	var out []string
+	for _, rrs := range rrsets {
+		out = append(out, aws.StringValue(rrs.Name))
+	}
+	return out
+}
+
+func TestMatch(t *testing.T) {
+	rrsets := []*route53.ResourceRecordSet{
+		a("example.com.", 60, "10.0.0.1"),
+		a("www.example.com.", 60, "10.0.0.1"),
+		a("web.app.example.com.", 60, "10.0.0.1"),
+		a("a.b.app.example.com.", 60, "10.0.0.1"),
+	}
+
+	assert.Equal(t, 4, len(Match("example.com.", nil, rrsets)))
+	assert.Equal(t, []string{"example.com.", "www.example.com."}, names(Match("example.com.", []string{"@", "WWW"}, rrsets)))
+	assert.Equal(t, []string{"web.app.example.com.", "a.b.app.example.com."}, names(Match("example.com", []string{"*.app"}, rrsets)))
+	assert.Equal(t, []string{"web.app.example.com."}, names(Match("example.com", []string{"web.app.example.com."}, rrsets)))
+}
+
+func TestRename(t *testing.T) {
+	alias := &route53.ResourceRecordSet{
+		Name: aws.String("www.example.com."),
+		Type: aws.String(route53.RRTypeA),
+		AliasTarget: &route53.AliasTarget{
+			HostedZoneId:         aws.String("Z1"),
+			DNSName:              aws.String("web.example.com."),
+			EvaluateTargetHealth: aws.Bool(false),
+		},
+	}
+	elbAlias := &route53.ResourceRecordSet{
+		Name: aws.String("lb.example.com."),
+		Type: aws.String(route53.RRTypeA),
+		AliasTarget: &route53.AliasTarget{
+			HostedZoneId:         aws.String("Z35SXDOTRQ7X7K"),
+			DNSName:              aws.String("web-1.us-east-1.elb.amazonaws.com."),
+			EvaluateTargetHealth: aws.Bool(true),
+		},
+	}
+	rrsets := []*route53.ResourceRecordSet{a("example.com.", 60, "10.0.0.1"), alias, elbAlias}
+
+	renamed := Rename(rrsets, "example.com.", "/hostedzone/Z1", "example.org", "/hostedzone/Z2")
+	assert.Equal(t, []string{"example.org.", "www.example.org.", "lb.example.org."}, names(renamed))
+	assert.Equal(t, "Z2", aws.StringValue(renamed[1].AliasTarget.HostedZoneId))
+	assert.Equal(t, "web.example.org.", aws.StringValue(renamed[1].AliasTarget.DNSName))
+	assert.Equal(t, "Z35SXDOTRQ7X7K", aws.StringValue(renamed[2].AliasTarget.HostedZoneId))
+	assert.Equal(t, "web-1.us-east-1.elb.amazonaws.com.", aws.StringValue(renamed[2].AliasTarget.DNSName))
+
+	assert.Equal(t, "www.example.com.", aws.StringValue(alias.Name), "the originals are not modified")
+}
+
+func TestMerge(t *testing.T) {
+	current := []*route53.ResourceRecordSet{
+		a("keep.example.com.", 60, "10.0.0.1"),
+		a("change.example.com.", 60, "10.0.0.1"),
+		a("other.example.com.", 60, "10.0.0.1"),
+	}
+	desired := []*route53.ResourceRecordSet{
+		a("new.example.com.", 60, "10.0.0.1"),
+		a("keep.example.com", 60, "10.0.0.1"),
+		a("change.example.com.", 60, "10.0.0.2"),
+	}
+
+	changes, skipped := Merge(current, desired, false)
+	assert.Equal(t, []string{"CREATE new.example.com."}, actions(changes))
+	assert.Equal(t, []string{"change.example.com."}, names(skipped))
+
+	changes, skipped = Merge(current, desired, true)
+	assert.Equal(t, []string{"UPSERT change.example.com.", "CREATE new.example.com."}, actions(changes))
+	assert.Empty(t, skipped)
+}
+
+func TestCompare(t *testing.T) {
+	alias := func(name, zoneID, target string) *route53.ResourceRecordSet {
+		return &route53.ResourceRecordSet{
+			Name: aws.String(name),
+			Type: aws.String(route53.RRTypeA),
+			AliasTarget: &route53.AliasTarget{
+				HostedZoneId:         aws.String(zoneID),
+				DNSName:              aws.String(target),
+				EvaluateTargetHealth: aws.Bool(false),
+			},
+		}
+	}
+	weighted := func(rrs *route53.ResourceRecordSet, id string, weight int64) *route53.ResourceRecordSet {
+		rrs.SetIdentifier = aws.String(id)
+		rrs.Weight = aws.Int64(weight)
+		return rrs
+	}
+
+	staging := []*route53.ResourceRecordSet{
+		a("staging.example.com.", 60, "10.0.0.1"),
+		alias("www.staging.example.com.", "Z1", "staging.example.com."),
+		a("changed.staging.example.com.", 60, "10.0.0.1"),
+		weighted(a("api.staging.example.com.", 60, "10.0.0.1"), "blue", 10),
+		a("removed.staging.example.com.", 60, "10.0.0.1"),
+	}
+	prod := []*route53.ResourceRecordSet{
+		a("example.com.", 60, "10.0.0.1"),
+		alias("WWW.example.com.", "/hostedzone/Z2", "example.com"),
+		a("changed.example.com.", 300, "10.0.0.1"),
+		weighted(a("api.example.com.", 60, "10.0.0.1"), "blue", 20),
+		a("added.example.com.", 60, "10.0.0.1"),
+	}
+
+	diffs := Compare("staging.example.com.", "Z1", staging, "example.com.", "Z2", prod)
+
+	var got []string
+	for _, d := range diffs {
+		got = append(got, fmt.Sprintf("%s %s %s a=%v b=%v", d.Name, d.Type, d.SetIdentifier, d.A != nil, d.B != nil))
+	}
+	assert.Equal(t, []string{
+		"added A  a=false b=true",
+		"api A blue a=true b=true",
+		"changed A  a=true b=true",
+		"removed A  a=true b=false",
+	}, got, "aliases within each zone are equal")
+
+	assert.Equal(t, "changed.example.com.", aws.StringValue(diffs[2].B.Name), "B is reported with its own name")
+	assert.Empty(t, Compare("example.com.", "Z2", prod, "example.com.", "Z2", prod))
+}
+
+func TestRelativeName(t *testing.T) {
+	assert.Equal(t, "@", RelativeName("Example.com", "example.com."))
+	assert.Equal(t, "www", RelativeName("www.example.com.", "example.com"))
+	assert.Equal(t, "a.b", RelativeName("a.b.example.com.", "example.com"))
+	assert.Equal(t, "www.example.org.", RelativeName("www.example.org.", "example.com"))
+}
+
+func TestSnapshotRestore(t *testing.T) {
+	ctx := context.Background()
+	r53 := fake.NewRoute53()
+	zoneID, _ := r53.AddZone("example.com")
+	zone := &awsclient.Zone{ID: zoneID, Name: "example.com."}
+	client := awsclient.NewWithServices(r53, fake.NewELB())
+
+	r53.AddRecordSet(zoneID, a("www.example.com.", 60, "10.0.0.1"))
+	r53.AddRecordSet(zoneID, a("api.example.com.", 60, "10.0.0.2"))
+
+	dir, err := ioutil.TempDir("", "snapshots")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	rrsets, err := client.RecordSetsWithContext(ctx, zone)
+	assert.Nil(t, err)
+	snap := NewSnapshot(zone.ID, zone.Name, rrsets)
+	path := SnapshotDir(dir).Path(snap)
+	assert.Nil(t, snap.Save(path))
+	assert.Equal(t, filepath.Join(dir, "example.com"), filepath.Dir(path))
+
+	// accidental mass edit
+	_, err = client.ChangeRecordSets(ctx, zone, "test", []*route53.Change{
+		change(route53.ChangeActionDelete, a("www.example.com.", 60, "10.0.0.1")),
+		change(route53.ChangeActionUpsert, a("api.example.com.", 60, "10.9.9.9")),
+		change(route53.ChangeActionCreate, a("junk.example.com.", 60, "10.9.9.9")),
+	})
+	assert.Nil(t, err)
+
+	found, err := SnapshotDir(dir).Find("example.com", "latest")
+	assert.Nil(t, err)
+	loaded, err := LoadSnapshot(found)
+	assert.Nil(t, err)
+	assert.WithinDuration(t, snap.Time, loaded.Time, time.Second)
+
+	current, _ := client.RecordSetsWithContext(ctx, zone)
+	changes := Diff(Editable(zone.Name, current), Editable(zone.Name, loaded.RecordSets))
+	assert.Equal(t, 3, len(changes))
+
+	for _, batch := range Batches(changes, 1) {
+		_, err := client.ChangeRecordSets(ctx, zone, "restore", batch)
+		assert.Nil(t, err)
+	}
+
+	current, _ = client.RecordSetsWithContext(ctx, zone)
+	assert.Empty(t, Diff(current, loaded.RecordSets))
+}
+
+func TestSnapshotDirFind(t *testing.T) {
+	dir, err := ioutil.TempDir("", "snapshots")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	d := SnapshotDir(dir)
+	_, err = d.Find("example.com", "latest")
+	assert.NotNil(t, err)
+
+	older := &Snapshot{Version: SnapshotVersion, ZoneName: "example.com.", Time: time.Date(2017, 3, 1, 10, 0, 0, 0, time.UTC)}
+	newer := &Snapshot{Version: SnapshotVersion, ZoneName: "example.com.", Time: time.Date(2017, 3, 2, 10, 0, 0, 0, time.UTC)}
+	older.Save(d.Path(older))
+	newer.Save(d.Path(newer))
+
+	path, err := d.Find("example.com.", "latest")
+	assert.Nil(t, err)
+	assert.Equal(t, d.Path(newer), path)
+
+	path, err = d.Find("example.com", "20170301T100000.000Z")
+	assert.Nil(t, err)
+	assert.Equal(t, d.Path(older), path)
+
+	// snapshots taken in the same second
+	first := &Snapshot{Version: SnapshotVersion, ZoneName: "example.com.", Time: time.Date(2017, 3, 3, 10, 0, 0, 5e6, time.UTC)}
+	second := &Snapshot{Version: SnapshotVersion, ZoneName: "example.com.", Time: time.Date(2017, 3, 3, 10, 0, 0, 40e6, time.UTC)}
+	assert.NotEqual(t, d.Path(first), d.Path(second))
+	first.Save(d.Path(first))
+	second.Save(d.Path(second))
+
+	paths, err := d.List("example.com")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{d.Path(older), d.Path(newer), d.Path(first), d.Path(second)}, paths)
+}
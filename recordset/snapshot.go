@@ -0,0 +1,118 @@
+package recordset
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/route53"
+)
+
+// SnapshotVersion is the version of the snapshot document format.
+const SnapshotVersion = 1
+
+// snapshotTimeFormat names snapshot files. The fractional seconds have a
+// fixed width so that the names sort by time.
+const snapshotTimeFormat = "20060102T150405.000Z"
+
+// Snapshot is every record set of a zone at a point in time.
+type Snapshot struct {
+	Version    int                          `json:"version"`
+	ZoneID     string                       `json:"zone_id"`
+	ZoneName   string                       `json:"zone_name"`
+	Time       time.Time                    `json:"time"`
+	RecordSets []*route53.ResourceRecordSet `json:"record_sets"`
+}
+
+func NewSnapshot(zoneID, zoneName string, rrsets []*route53.ResourceRecordSet) *Snapshot {
+	return &Snapshot{
+		Version:    SnapshotVersion,
+		ZoneID:     zoneID,
+		ZoneName:   zoneName,
+		Time:       time.Now().UTC(),
+		RecordSets: rrsets,
+	}
+}
+
+// LoadSnapshot reads a snapshot document.
+func LoadSnapshot(path string) (*Snapshot, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Snapshot{}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, err
+	}
+	if s.Version != SnapshotVersion {
+		return nil, fmt.Errorf("Unsupported snapshot version %d.", s.Version)
+	}
+
+	return s, nil
+}
+
+// Save writes the snapshot to path.
+func (s *Snapshot) Save(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(data, '\n'), 0600)
+}
+
+// SnapshotDir keeps the snapshots of every zone in a directory per zone, one
+// file per snapshot named after the time it was taken, to the millisecond so
+// snapshots taken in the same second don't overwrite each other.
+type SnapshotDir string
+
+// Path returns the file a new snapshot of s's zone is saved to.
+func (d SnapshotDir) Path(s *Snapshot) string {
+	return filepath.Join(d.zoneDir(s.ZoneName), s.Time.UTC().Format(snapshotTimeFormat)+".json")
+}
+
+// List returns the snapshot files of a zone, oldest first.
+func (d SnapshotDir) List(zoneName string) ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(d.zoneDir(zoneName), "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// Find returns the snapshot file for ref, which is either a path or the
+// name of a snapshot of the zone. "latest" is the newest snapshot.
+func (d SnapshotDir) Find(zoneName, ref string) (string, error) {
+	if _, err := os.Stat(ref); err == nil {
+		return ref, nil
+	}
+
+	paths, err := d.List(zoneName)
+	if err != nil {
+		return "", err
+	}
+	if ref == "latest" && len(paths) > 0 {
+		return paths[len(paths)-1], nil
+	}
+	for _, p := range paths {
+		if strings.TrimSuffix(filepath.Base(p), ".json") == strings.TrimSuffix(ref, ".json") {
+			return p, nil
+		}
+	}
+
+	return "", fmt.Errorf("Snapshot %s of %s does not exist.", ref, zoneName)
+}
+
+func (d SnapshotDir) zoneDir(zoneName string) string {
+	return filepath.Join(string(d), strings.ToLower(strings.TrimSuffix(zoneName, ".")))
+}
@@ -0,0 +1,234 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/ryane/takethe53/awsclient"
+	"github.com/ryane/takethe53/policy"
+)
+
+// maxRequestBody limits the size of API request bodies.
+const maxRequestBody = 1 << 20
+
+// AliasRequest is the body of POST /v1/aliases.
+type AliasRequest struct {
+	Zone         string `json:"zone"`
+	Alias        string `json:"alias"`
+	ELBDNSName   string `json:"elb_dns_name"`
+	Force        bool   `json:"force,omitempty"`
+	IfNotExists  bool   `json:"if_not_exists,omitempty"`
+	ExpectTarget string `json:"expect_target,omitempty"`
+	// Confirm confirms changes to names the policy protects.
+	Confirm bool `json:"confirm,omitempty"`
+}
+
+type api struct {
+	client  *awsclient.AWSClient
+	tracker *Tracker
+	// writeTimeout is the server's write timeout, event streams end before
+	// it so that clients reconnect instead of seeing a broken connection.
+	writeTimeout time.Duration
+	// stopping is closed when the server shuts down, to end event streams.
+	stopping <-chan struct{}
+}
+
+func (a *api) register(mux *http.ServeMux, auth *Authenticator) {
+	mux.Handle("/v1/aliases", auth.requireAuth(http.HandlerFunc(a.aliases)))
+	mux.Handle("/v1/changes/", auth.requireAuth(http.HandlerFunc(a.change)))
+	mux.Handle("/v1/events", auth.requireAuth(http.HandlerFunc(a.streamEvents)))
+}
+
+func (a *api) aliases(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		a.setAlias(w, r)
+	case http.MethodDelete:
+		a.removeAlias(w, r)
+	default:
+		w.Header().Set("Allow", "POST, DELETE")
+		writeError(w, http.StatusMethodNotAllowed, errors.New("Method not allowed."))
+	}
+}
+
+func (a *api) setAlias(w http.ResponseWriter, r *http.Request) {
+	var req AliasRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBody)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, errors.New("Invalid request body: "+err.Error()))
+		return
+	}
+	if req.Zone == "" || req.Alias == "" || req.ELBDNSName == "" {
+		writeError(w, http.StatusBadRequest, errors.New("zone, alias and elb_dns_name are required."))
+		return
+	}
+
+	zone, ok := a.authorize(w, r, req.Zone, req.Alias)
+	if !ok {
+		return
+	}
+
+	lb, err := a.client.FindLoadBalancerWithContext(r.Context(), req.ELBDNSName)
+	if err != nil {
+		writeAWSError(w, err)
+		return
+	}
+
+	opts := awsclient.AliasOptions{
+		Force:        req.Force,
+		IfNotExists:  req.IfNotExists,
+		ExpectTarget: req.ExpectTarget,
+	}
+	ctx := r.Context()
+	if req.Confirm {
+		ctx = policy.WithConfirmation(ctx)
+	}
+	status, err := a.client.SetAliasWithOptions(ctx, zone, lb.HostedZoneID, lb.Name, req.Alias, opts)
+	if err != nil {
+		writeAWSError(w, err)
+		return
+	}
+
+	a.writeChange(w, awsclient.OpSetAlias, zone, status)
+}
+
+func (a *api) removeAlias(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	zoneName, alias := q.Get("zone"), q.Get("alias")
+	if zoneName == "" || alias == "" {
+		writeError(w, http.StatusBadRequest, errors.New("zone and alias are required."))
+		return
+	}
+
+	zone, ok := a.authorize(w, r, zoneName, alias)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+	if q.Get("confirm") == "true" {
+		ctx = policy.WithConfirmation(ctx)
+	}
+	opts := awsclient.AliasOptions{Force: q.Get("force") == "true"}
+	status, err := a.client.RemoveAliasWithOptions(ctx, zone, alias, opts)
+	if err != nil {
+		writeAWSError(w, err)
+		return
+	}
+
+	a.writeChange(w, awsclient.OpRemoveAlias, zone, status)
+}
+
+// authorize looks up the zone and checks that the caller's token may change
+// alias in it.
+func (a *api) authorize(w http.ResponseWriter, r *http.Request, zoneName, alias string) (*awsclient.Zone, bool) {
+	token, _ := TokenFromContext(r.Context())
+
+	// check the requested zone name first so callers can't probe zones
+	// they have no access to
+	if token == nil || !token.allowsZone(zoneName) {
+		writeError(w, http.StatusForbidden, ErrForbidden)
+		return nil, false
+	}
+
+	zone, err := a.client.FindZoneWithContext(r.Context(), zoneName)
+	if err != nil {
+		writeAWSError(w, err)
+		return nil, false
+	}
+
+	name := zone.RecordName(alias)
+	if !token.Allows(zone.Name, name) {
+		logrus.WithFields(logrus.Fields{"type": "api", "token": token.Name, "zone": zone.Name, "name": name}).Warn("Forbidden")
+		writeError(w, http.StatusForbidden, ErrForbidden)
+		return nil, false
+	}
+
+	return zone, true
+}
+
+func (a *api) writeChange(w http.ResponseWriter, op string, zone *awsclient.Zone, status *awsclient.ChangeStatus) {
+	tc, ok := a.tracker.Get(status.ID)
+	if !ok {
+		tc = &TrackedChange{
+			ID:          status.ID,
+			Zone:        zone.Name,
+			Op:          op,
+			Status:      status.Status,
+			SubmittedAt: status.SubmittedAt,
+			UpdatedAt:   time.Now(),
+		}
+	}
+	writeJSON(w, http.StatusAccepted, tc)
+}
+
+func (a *api) change(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		writeError(w, http.StatusMethodNotAllowed, errors.New("Method not allowed."))
+		return
+	}
+
+	// changes in zones the token has no access to are reported as not
+	// found, so callers can't probe them
+	token, _ := TokenFromContext(r.Context())
+	id := strings.TrimPrefix(r.URL.Path, "/v1/changes/")
+	if tc, ok := a.tracker.Get(id); ok {
+		if token == nil || !token.allowsZone(tc.Zone) {
+			writeAWSError(w, awsclient.ErrChangeNotFound)
+			return
+		}
+		writeJSON(w, http.StatusOK, tc)
+		return
+	}
+
+	// not submitted by this server, or too long ago. The zone is unknown,
+	// only tokens for all zones may look it up.
+	if token == nil || len(token.Zones) > 0 {
+		writeAWSError(w, awsclient.ErrChangeNotFound)
+		return
+	}
+	status, err := a.client.GetChangeStatusWithContext(r.Context(), id)
+	if err != nil {
+		writeAWSError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, &TrackedChange{
+		ID:          status.ID,
+		Status:      status.Status,
+		SubmittedAt: status.SubmittedAt,
+		UpdatedAt:   time.Now(),
+	})
+}
+
+func writeAWSError(w http.ResponseWriter, err error) {
+	code := http.StatusBadGateway
+	switch err {
+	case awsclient.ErrZoneNotFound, awsclient.ErrRecordNotFound, awsclient.ErrELBNotFound, awsclient.ErrChangeNotFound:
+		code = http.StatusNotFound
+	case awsclient.ErrConflict, awsclient.ErrNotOwner:
+		code = http.StatusConflict
+	case awsclient.ErrConflictingAliasOptions:
+		code = http.StatusBadRequest
+	}
+	if policy.IsViolation(err) {
+		code = http.StatusForbidden
+	}
+	if code == http.StatusBadGateway {
+		logrus.WithField("type", "api").Error("AWS error: ", err)
+	}
+	writeError(w, code, err)
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,216 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+// HMAC signed requests carry the signature in the Authorization header:
+//
+//	Authorization: HMAC-SHA256 <token name>:<base64 signature>
+//	X-Takethe53-Date: <RFC 3339 time>
+//
+// The signature is the HMAC-SHA256 with the token's secret of
+//
+//	<method>\n<request URI>\n<date>\n<hex SHA-256 of the body>
+const (
+	hmacScheme     = "HMAC-SHA256"
+	hmacDateHeader = "X-Takethe53-Date"
+	hmacMaxSkew    = 5 * time.Minute
+)
+
+var (
+	ErrUnauthenticated = errors.New("Missing or invalid credentials.")
+	ErrForbidden       = errors.New("Token is not allowed to change this record.")
+)
+
+// Token is an API credential and the records it may change.
+type Token struct {
+	Name string `mapstructure:"name" json:"name"`
+	// Token is a bearer token.
+	Token string `mapstructure:"token" json:"-"`
+	// HMACSecret is the secret for HMAC signed requests.
+	HMACSecret string `mapstructure:"hmac-secret" json:"-"`
+	// Zones the token may change, all zones if empty.
+	Zones []string `mapstructure:"zones" json:"zones,omitempty"`
+	// Names are patterns of record names the token may change, like
+	// "*.app.example.com". * matches any sequence of characters, including
+	// dots. All names if empty.
+	Names []string `mapstructure:"names" json:"names,omitempty"`
+}
+
+// Allows reports whether the token may change record name in zone.
+func (t *Token) Allows(zone, name string) bool {
+	return t.allowsZone(zone) && t.allowsName(name)
+}
+
+func (t *Token) allowsZone(zone string) bool {
+	if len(t.Zones) == 0 {
+		return true
+	}
+	for _, z := range t.Zones {
+		if normalizeName(z) == normalizeName(zone) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *Token) allowsName(name string) bool {
+	if len(t.Names) == 0 {
+		return true
+	}
+	for _, pattern := range t.Names {
+		if ok, _ := path.Match(normalizeName(pattern), normalizeName(name)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// AuthConfig is the "auth" section of the config file.
+type AuthConfig struct {
+	// AllowAnonymous disables authentication. Only meant for local
+	// development.
+	AllowAnonymous bool     `mapstructure:"allow-anonymous"`
+	Tokens         []*Token `mapstructure:"tokens"`
+}
+
+// Authenticator checks the credentials of API requests.
+type Authenticator struct {
+	cfg AuthConfig
+	now func() time.Time
+}
+
+func NewAuthenticator(cfg AuthConfig) *Authenticator {
+	return &Authenticator{cfg: cfg, now: time.Now}
+}
+
+// anonymous is the token of unauthenticated callers when AllowAnonymous is
+// set.
+var anonymous = &Token{Name: "anonymous"}
+
+// Authenticate returns the token of the request.
+func (a *Authenticator) Authenticate(r *http.Request) (*Token, error) {
+	auth := r.Header.Get("Authorization")
+	switch {
+	case strings.HasPrefix(auth, "Bearer "):
+		return a.bearer(strings.TrimPrefix(auth, "Bearer "))
+	case strings.HasPrefix(auth, hmacScheme+" "):
+		return a.hmac(r, strings.TrimPrefix(auth, hmacScheme+" "))
+	case auth == "" && a.cfg.AllowAnonymous:
+		return anonymous, nil
+	}
+	return nil, ErrUnauthenticated
+}
+
+func (a *Authenticator) bearer(token string) (*Token, error) {
+	for _, t := range a.cfg.Tokens {
+		if t.Token != "" && subtle.ConstantTimeCompare([]byte(t.Token), []byte(token)) == 1 {
+			return t, nil
+		}
+	}
+	return nil, ErrUnauthenticated
+}
+
+func (a *Authenticator) hmac(r *http.Request, credential string) (*Token, error) {
+	parts := strings.SplitN(credential, ":", 2)
+	if len(parts) != 2 {
+		return nil, ErrUnauthenticated
+	}
+
+	var token *Token
+	for _, t := range a.cfg.Tokens {
+		if t.HMACSecret != "" && t.Name == parts[0] {
+			token = t
+		}
+	}
+	if token == nil {
+		return nil, ErrUnauthenticated
+	}
+
+	date := r.Header.Get(hmacDateHeader)
+	signedAt, err := time.Parse(time.RFC3339, date)
+	if err != nil {
+		return nil, ErrUnauthenticated
+	}
+	if skew := a.now().Sub(signedAt); skew > hmacMaxSkew || skew < -hmacMaxSkew {
+		return nil, ErrUnauthenticated
+	}
+
+	var body []byte
+	if r.Body != nil {
+		body, err = ioutil.ReadAll(r.Body)
+		if err != nil {
+			return nil, ErrUnauthenticated
+		}
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil || !hmac.Equal(signature, Sign(token.HMACSecret, r.Method, r.URL.RequestURI(), date, body)) {
+		return nil, ErrUnauthenticated
+	}
+
+	return token, nil
+}
+
+// Sign returns the HMAC signature of a request.
+func Sign(secret, method, requestURI, date string, body []byte) []byte {
+	sum := sha256.Sum256(body)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(method + "\n" + requestURI + "\n" + date + "\n" + hex.EncodeToString(sum[:])))
+	return mac.Sum(nil)
+}
+
+type tokenKey struct{}
+
+// TokenFromContext returns the token of the caller.
+func TokenFromContext(ctx context.Context) (*Token, bool) {
+	t, ok := ctx.Value(tokenKey{}).(*Token)
+	return t, ok
+}
+
+type requestKey struct{}
+
+// RequestFromContext returns the method and path of the API request, like
+// "POST /v1/aliases".
+func RequestFromContext(ctx context.Context) (string, bool) {
+	r, ok := ctx.Value(requestKey{}).(string)
+	return r, ok
+}
+
+// requireAuth rejects requests without valid credentials and stores the
+// caller's token and the request in the request context.
+func (a *Authenticator) requireAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// HMAC signatures cover the body, limit it before it is read
+		if r.Body != nil {
+			r.Body = http.MaxBytesReader(w, r.Body, maxRequestBody)
+		}
+		token, err := a.Authenticate(r)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="takethe53"`)
+			writeError(w, http.StatusUnauthorized, err)
+			return
+		}
+		ctx := context.WithValue(r.Context(), tokenKey{}, token)
+		ctx = context.WithValue(ctx, requestKey{}, r.Method+" "+r.URL.Path)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func normalizeName(name string) string {
+	return strings.ToLower(strings.TrimSuffix(name, ".")) + "."
+}
@@ -0,0 +1,201 @@
+package server
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ryane/takethe53/awsclient"
+	"github.com/ryane/takethe53/awsclient/fake"
+	"github.com/stretchr/testify/assert"
+)
+
+var testAuthConfig = AuthConfig{
+	Tokens: []*Token{
+		{Name: "app", Token: "app-token", Zones: []string{"example.com"}, Names: []string{"*.app.example.com"}},
+		{Name: "ci", HMACSecret: "ci-secret"},
+		{Name: "org", Token: "org-token", Zones: []string{"example.org"}},
+	},
+}
+
+func signedRequest(method, uri, secret string, date time.Time, body string) *http.Request {
+	r := httptest.NewRequest(method, uri, strings.NewReader(body))
+	d := date.Format(time.RFC3339)
+	sig := Sign(secret, method, uri, d, []byte(body))
+	r.Header.Set("Authorization", "HMAC-SHA256 ci:"+base64.StdEncoding.EncodeToString(sig))
+	r.Header.Set(hmacDateHeader, d)
+	return r
+}
+
+func TestAuthenticate(t *testing.T) {
+	auth := NewAuthenticator(testAuthConfig)
+
+	r := httptest.NewRequest("GET", "/v1/changes/C1", nil)
+	_, err := auth.Authenticate(r)
+	assert.Equal(t, ErrUnauthenticated, err)
+
+	r.Header.Set("Authorization", "Bearer wrong")
+	_, err = auth.Authenticate(r)
+	assert.Equal(t, ErrUnauthenticated, err)
+
+	r.Header.Set("Authorization", "Bearer app-token")
+	token, err := auth.Authenticate(r)
+	assert.Nil(t, err)
+	assert.Equal(t, "app", token.Name)
+
+	// HMAC
+	r = signedRequest("POST", "/v1/aliases", "ci-secret", time.Now(), `{"zone":"example.com"}`)
+	token, err = auth.Authenticate(r)
+	assert.Nil(t, err)
+	assert.Equal(t, "ci", token.Name)
+
+	// the body is still readable after verifying the signature
+	var buf bytes.Buffer
+	buf.ReadFrom(r.Body)
+	assert.Equal(t, `{"zone":"example.com"}`, buf.String())
+
+	r = signedRequest("POST", "/v1/aliases", "wrong-secret", time.Now(), `{}`)
+	_, err = auth.Authenticate(r)
+	assert.Equal(t, ErrUnauthenticated, err)
+
+	r = signedRequest("POST", "/v1/aliases", "ci-secret", time.Now().Add(-time.Hour), `{}`)
+	_, err = auth.Authenticate(r)
+	assert.Equal(t, ErrUnauthenticated, err)
+
+	// tampered body
+	r = signedRequest("POST", "/v1/aliases", "ci-secret", time.Now(), `{}`)
+	r.Body = httptest.NewRequest("POST", "/", strings.NewReader(`{"force":true}`)).Body
+	_, err = auth.Authenticate(r)
+	assert.Equal(t, ErrUnauthenticated, err)
+
+	// anonymous
+	_, err = auth.Authenticate(httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, ErrUnauthenticated, err)
+	token, err = NewAuthenticator(AuthConfig{AllowAnonymous: true}).Authenticate(httptest.NewRequest("GET", "/", nil))
+	assert.Nil(t, err)
+	assert.Equal(t, "anonymous", token.Name)
+}
+
+type endlessReader struct{}
+
+func (endlessReader) Read(p []byte) (int, error) {
+	return len(p), nil
+}
+
+func TestRequireAuthLimitsBody(t *testing.T) {
+	handler := NewAuthenticator(testAuthConfig).requireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("handler called")
+	}))
+
+	r := signedRequest("POST", "/v1/aliases", "ci-secret", time.Now(), `{}`)
+	r.Body = ioutil.NopCloser(endlessReader{})
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestTokenAllows(t *testing.T) {
+	token := testAuthConfig.Tokens[0]
+
+	assert.True(t, token.Allows("example.com.", "web.app.example.com."))
+	assert.True(t, token.Allows("Example.com", "WEB.app.example.com"))
+	assert.False(t, token.Allows("example.com.", "www.example.com."))
+	assert.False(t, token.Allows("example.org.", "web.app.example.com."))
+	// like DNS wildcards, * matches several labels
+	assert.True(t, token.Allows("example.com.", "a.b.app.example.com."))
+
+	assert.True(t, testAuthConfig.Tokens[1].Allows("example.org.", "anything.example.org."))
+}
+
+func TestAPIScopes(t *testing.T) {
+	r53 := fake.NewRoute53()
+	r53.AddZone("example.com")
+	r53.AddZone("example.org")
+	elb := fake.NewELB()
+	elb.AddLoadBalancer("web-1", "web-1.us-east-1.elb.amazonaws.com", "Z35SXDOTRQ7X7K")
+
+	client := awsclient.NewWithServices(r53, elb)
+	tracker := NewTracker(client, 5*time.Millisecond, time.Second)
+	client.AddChangeHandler(tracker.Handler())
+
+	mux := http.NewServeMux()
+	a := &api{client: client, tracker: tracker}
+	a.register(mux, NewAuthenticator(testAuthConfig))
+
+	post := func(body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", "/v1/aliases", strings.NewReader(body))
+		r.Header.Set("Authorization", "Bearer app-token")
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		return w
+	}
+
+	w := post(`{"zone":"example.com","alias":"web.app","elb_dns_name":"web-1.us-east-1.elb.amazonaws.com"}`)
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.Contains(t, w.Body.String(), `"op":"set-alias"`)
+
+	w = post(`{"zone":"example.com","alias":"www","elb_dns_name":"web-1.us-east-1.elb.amazonaws.com"}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = post(`{"zone":"example.org","alias":"web.app","elb_dns_name":"web-1.us-east-1.elb.amazonaws.com"}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = post(`{"zone":"example.com","alias":"web.app","elb_dns_name":"missing.us-east-1.elb.amazonaws.com"}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	r := httptest.NewRequest("DELETE", "/v1/aliases?zone=example.com&alias=web.app", nil)
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	r.Header.Set("Authorization", "Bearer app-token")
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.Contains(t, w.Body.String(), `"op":"remove-alias"`)
+}
+
+func TestChangeScopes(t *testing.T) {
+	r53 := fake.NewRoute53()
+	r53.AddZone("example.com")
+	r53.AddZone("example.org")
+	elb := fake.NewELB()
+	elb.AddLoadBalancer("web-1", "web-1.us-east-1.elb.amazonaws.com", "Z35SXDOTRQ7X7K")
+
+	client := awsclient.NewWithServices(r53, elb)
+	tracker := NewTracker(client, 5*time.Millisecond, time.Second)
+	client.AddChangeHandler(tracker.Handler())
+
+	mux := http.NewServeMux()
+	a := &api{client: client, tracker: tracker}
+	a.register(mux, NewAuthenticator(testAuthConfig))
+
+	r := httptest.NewRequest("POST", "/v1/aliases", strings.NewReader(`{"zone":"example.com","alias":"web.app","elb_dns_name":"web-1.us-east-1.elb.amazonaws.com"}`))
+	r.Header.Set("Authorization", "Bearer app-token")
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusAccepted, w.Code)
+
+	var change TrackedChange
+	assert.Nil(t, json.NewDecoder(w.Body).Decode(&change))
+
+	get := func(id, token string) int {
+		r := httptest.NewRequest("GET", "/v1/changes/"+id, nil)
+		r.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	id := strings.TrimPrefix(change.ID, "/change/")
+	assert.Equal(t, http.StatusOK, get(id, "app-token"))
+	assert.Equal(t, http.StatusNotFound, get(id, "org-token"))
+	// changes the server didn't track could be in any zone
+	assert.Equal(t, http.StatusNotFound, get("C-UNTRACKED", "org-token"))
+}
@@ -0,0 +1,106 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// eventKeepAlive is how often a comment is sent on idle event streams
+	// so proxies don't close them.
+	eventKeepAlive = 15 * time.Second
+	// eventStreamMargin is how long before the write timeout an event
+	// stream is ended.
+	eventStreamMargin = 5 * time.Second
+	// eventRetry is the reconnect delay suggested to clients, in
+	// milliseconds.
+	eventRetry = 1000
+)
+
+// streamEvents streams the status events of tracked changes as Server-Sent
+// Events, optionally only for the zone in the "zone" query parameter. Each
+// event's id is its Seq, so an EventSource that reconnects with
+// Last-Event-ID continues where it left off.
+func (a *api) streamEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		writeError(w, http.StatusMethodNotAllowed, errors.New("Method not allowed."))
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errors.New("Streaming is not supported."))
+		return
+	}
+
+	zone := r.URL.Query().Get("zone")
+	token, _ := TokenFromContext(r.Context())
+	if zone != "" && (token == nil || !token.allowsZone(zone)) {
+		writeError(w, http.StatusForbidden, ErrForbidden)
+		return
+	}
+
+	var lastSeq uint64
+	if id := r.Header.Get("Last-Event-ID"); id != "" {
+		lastSeq, _ = strconv.ParseUint(id, 10, 64)
+	}
+
+	sub := a.tracker.Subscribe(zone, lastSeq)
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", eventRetry)
+	flusher.Flush()
+
+	// the server's write timeout also applies to streams, end them in time
+	// and let the client reconnect
+	var deadline <-chan time.Time
+	if a.writeTimeout > 0 {
+		d := a.writeTimeout - eventStreamMargin
+		if d <= 0 {
+			d = a.writeTimeout / 2
+		}
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	keepAlive := time.NewTicker(eventKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-deadline:
+			return
+		case <-a.stopping:
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		case ev, ok := <-sub.Events:
+			if !ok {
+				// dropped for falling behind or the tracker stopped
+				return
+			}
+			if token != nil && !token.allowsZone(ev.Change.Zone) {
+				continue
+			}
+			data, err := json.Marshal(ev.Change)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "id: %d\nevent: change\ndata: %s\n\n", ev.Seq, data)
+			flusher.Flush()
+		}
+	}
+}
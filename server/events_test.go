@@ -0,0 +1,52 @@
+package server
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStreamEvents(t *testing.T) {
+	client, tracker, zone := newTrackedClient(10*time.Millisecond, time.Second)
+
+	mux := http.NewServeMux()
+	a := &api{client: client, tracker: tracker, writeTimeout: 10 * time.Second}
+	a.register(mux, NewAuthenticator(testAuthConfig))
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	req, _ := http.NewRequest("GET", srv.URL+"/v1/events?zone=example.org", nil)
+	req.Header.Set("Authorization", "Bearer app-token")
+	resp, err := http.DefaultClient.Do(req)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	resp.Body.Close()
+
+	req, _ = http.NewRequest("GET", srv.URL+"/v1/events?zone=example.com", nil)
+	req.Header.Set("Authorization", "Bearer app-token")
+	resp, err = http.DefaultClient.Do(req)
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	change, err := client.SetAlias(zone, "Z35SXDOTRQ7X7K", "web-1.us-east-1.elb.amazonaws.com", "web.app")
+	assert.Nil(t, err)
+
+	var data []string
+	scanner := bufio.NewScanner(resp.Body)
+	for len(data) < 2 && scanner.Scan() {
+		if line := scanner.Text(); strings.HasPrefix(line, "data: ") {
+			data = append(data, line)
+		}
+	}
+
+	assert.Len(t, data, 2)
+	assert.Contains(t, data[0], change.ID)
+	assert.Contains(t, data[0], `"status":"PENDING"`)
+	assert.Contains(t, data[1], `"status":"INSYNC"`)
+}
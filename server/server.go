@@ -1,12 +1,226 @@
 package server
 
 import (
-	_ "expvar"
-	"github.com/Sirupsen/logrus"
+	"context"
+	"expvar"
+	"fmt"
 	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/ryane/takethe53/awsclient"
 )
 
-func Run(addr string) {
-	logrus.Info("Started")
-	http.ListenAndServe(addr, nil)
+const readyCacheTTL = 10 * time.Second
+
+type Config struct {
+	Addr string
+	// Jobs run periodically while the server is running.
+	Jobs []*Job
+
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	IdleTimeout  time.Duration
+	// ShutdownTimeout is how long in-flight requests and tracked changes
+	// get to finish after SIGTERM.
+	ShutdownTimeout time.Duration
+
+	// Ready checks that the server can do its work, e.g. that the AWS
+	// credentials are valid. Results are cached for a few seconds.
+	Ready func(ctx context.Context) error
+	// Tracker, if set, is drained on shutdown.
+	Tracker *Tracker
+
+	// TLS, if set, serves HTTPS.
+	TLS *TLSConfig
+	// Client and Tracker enable the /v1 API, which, like /debug/vars, only
+	// accepts requests Auth authenticates.
+	Client *awsclient.AWSClient
+	Auth   *Authenticator
+}
+
+// Job is a task the server runs every Interval.
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context) error
+}
+
+type server struct {
+	cfg      Config
+	draining int32
+
+	readyMu      sync.Mutex
+	readyErr     error
+	readyChecked time.Time
+}
+
+// Run serves until the process receives SIGTERM or SIGINT, then shuts down
+// gracefully.
+func Run(cfg Config) error {
+	for _, job := range cfg.Jobs {
+		// time.NewTicker panics on intervals that are not positive
+		if job.Interval <= 0 {
+			return fmt.Errorf("Invalid interval %s for job %s, it must be positive.", job.Interval, job.Name)
+		}
+	}
+
+	s := &server{cfg: cfg}
+
+	auth := cfg.Auth
+	if auth == nil {
+		auth = NewAuthenticator(AuthConfig{})
+	}
+
+	mux := http.NewServeMux()
+	// expvar includes the command line, which can hold secrets
+	mux.Handle("/debug/vars", auth.requireAuth(expvar.Handler()))
+	mux.Handle("/metrics", promhttp.Handler())
+	mux.HandleFunc("/healthz", s.healthz)
+	mux.HandleFunc("/readyz", s.readyz)
+
+	stopping := make(chan struct{})
+	if cfg.Client != nil && cfg.Tracker != nil {
+		a := &api{client: cfg.Client, tracker: cfg.Tracker, writeTimeout: cfg.WriteTimeout, stopping: stopping}
+		a.register(mux, auth)
+	}
+
+	srv := &http.Server{
+		Addr:         cfg.Addr,
+		Handler:      mux,
+		ReadTimeout:  cfg.ReadTimeout,
+		WriteTimeout: cfg.WriteTimeout,
+		IdleTimeout:  cfg.IdleTimeout,
+	}
+
+	if cfg.TLS.Enabled() {
+		tlsConfig, err := cfg.TLS.tlsConfig()
+		if err != nil {
+			return err
+		}
+		srv.TLSConfig = tlsConfig
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var jobs sync.WaitGroup
+	for _, job := range cfg.Jobs {
+		jobs.Add(1)
+		go func(job *Job) {
+			defer jobs.Done()
+			runJob(ctx, job)
+		}(job)
+	}
+
+	errs := make(chan error, 1)
+	go func() {
+		logrus.WithFields(logrus.Fields{"address": cfg.Addr, "tls": cfg.TLS.Enabled()}).Info("Started")
+		if cfg.TLS.Enabled() {
+			// the certificate comes from TLSConfig.GetCertificate
+			errs <- srv.ListenAndServeTLS("", "")
+			return
+		}
+		errs <- srv.ListenAndServe()
+	}()
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigs)
+
+	select {
+	case err := <-errs:
+		return err
+	case sig := <-sigs:
+		logrus.WithField("signal", sig.String()).Info("Shutting down")
+	}
+
+	atomic.StoreInt32(&s.draining, 1)
+	shutdownCtx, shutdownCancel := context.WithCancel(context.Background())
+	if cfg.ShutdownTimeout > 0 {
+		shutdownCtx, shutdownCancel = context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	}
+	defer shutdownCancel()
+
+	close(stopping)
+	err := srv.Shutdown(shutdownCtx)
+	cancel()
+	jobs.Wait()
+
+	if cfg.Tracker != nil {
+		if n := cfg.Tracker.InFlight(); n > 0 {
+			logrus.WithField("changes", n).Info("Waiting for changes to sync")
+		}
+		if derr := cfg.Tracker.Drain(shutdownCtx); derr != nil && err == nil {
+			err = derr
+		}
+	}
+
+	if err != nil {
+		return err
+	}
+	logrus.Info("Stopped")
+	return nil
+}
+
+func (s *server) healthz(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte("ok\n"))
+}
+
+func (s *server) readyz(w http.ResponseWriter, r *http.Request) {
+	if atomic.LoadInt32(&s.draining) == 1 {
+		http.Error(w, "shutting down", http.StatusServiceUnavailable)
+		return
+	}
+
+	if err := s.ready(r.Context()); err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	w.Write([]byte("ok\n"))
+}
+
+func (s *server) ready(ctx context.Context) error {
+	if s.cfg.Ready == nil {
+		return nil
+	}
+
+	s.readyMu.Lock()
+	defer s.readyMu.Unlock()
+
+	if time.Since(s.readyChecked) < readyCacheTTL {
+		return s.readyErr
+	}
+
+	s.readyErr = s.cfg.Ready(ctx)
+	s.readyChecked = time.Now()
+	if s.readyErr != nil {
+		logrus.WithField("type", "server").Warn("Not ready: ", s.readyErr)
+	}
+	return s.readyErr
+}
+
+func runJob(ctx context.Context, job *Job) {
+	fields := logrus.Fields{"type": "job", "job": job.Name}
+	ticker := time.NewTicker(job.Interval)
+	defer ticker.Stop()
+
+	for {
+		logrus.WithFields(fields).Debug("job.run")
+		if err := job.Run(ctx); err != nil && ctx.Err() == nil {
+			logrus.WithFields(fields).Error("Job failed: ", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
 }
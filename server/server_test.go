@@ -0,0 +1,19 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunInvalidJobInterval(t *testing.T) {
+	err := Run(Config{
+		Addr: "127.0.0.1:0",
+		Jobs: []*Job{{
+			Name: "drift",
+			Run:  func(ctx context.Context) error { return nil },
+		}},
+	})
+	assert.EqualError(t, err, "Invalid interval 0s for job drift, it must be positive.")
+}
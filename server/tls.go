@@ -0,0 +1,128 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+)
+
+// certCheckInterval is how often the certificate files are checked for
+// changes.
+const certCheckInterval = 10 * time.Second
+
+// TLSConfig is the "tls" section of the config file.
+type TLSConfig struct {
+	CertFile string `mapstructure:"cert"`
+	KeyFile  string `mapstructure:"key"`
+	// ClientCAFile enables client certificate verification against the
+	// CAs in this file.
+	ClientCAFile string `mapstructure:"client-ca"`
+	// ClientAuth is "require" (the default with a client CA) or "optional".
+	ClientAuth string `mapstructure:"client-auth"`
+}
+
+func (c *TLSConfig) Enabled() bool {
+	return c != nil && c.CertFile != ""
+}
+
+func (c *TLSConfig) tlsConfig() (*tls.Config, error) {
+	reloader, err := newCertReloader(c.CertFile, c.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: reloader.GetCertificate,
+	}
+
+	if c.ClientCAFile != "" {
+		pem, err := ioutil.ReadFile(c.ClientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.New("No certificates found in " + c.ClientCAFile)
+		}
+		cfg.ClientCAs = pool
+
+		switch c.ClientAuth {
+		case "", "require":
+			cfg.ClientAuth = tls.RequireAndVerifyClientCert
+		case "optional":
+			cfg.ClientAuth = tls.VerifyClientCertIfGiven
+		default:
+			return nil, errors.New("Invalid tls.client-auth: " + c.ClientAuth)
+		}
+	}
+
+	return cfg, nil
+}
+
+// certReloader serves a certificate and reloads it when the files change,
+// so renewed certificates are picked up without a restart.
+type certReloader struct {
+	certFile, keyFile string
+
+	mu      sync.Mutex
+	cert    *tls.Certificate
+	modTime time.Time
+	checked time.Time
+}
+
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	r := &certReloader{certFile: certFile, keyFile: keyFile}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if time.Since(r.checked) > certCheckInterval {
+		r.checked = time.Now()
+		if modTime := r.latestModTime(); modTime.After(r.modTime) {
+			if err := r.load(); err != nil {
+				// keep serving the old certificate, the new files may
+				// still be being written
+				logrus.WithField("type", "tls").Warn("Error reloading certificate: ", err)
+			} else {
+				logrus.WithField("type", "tls").Info("Reloaded certificate")
+			}
+		}
+	}
+
+	return r.cert, nil
+}
+
+func (r *certReloader) load() error {
+	modTime := r.latestModTime()
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return err
+	}
+	r.cert = &cert
+	r.modTime = modTime
+	r.checked = time.Now()
+	return nil
+}
+
+func (r *certReloader) latestModTime() time.Time {
+	var latest time.Time
+	for _, f := range []string{r.certFile, r.keyFile} {
+		if info, err := os.Stat(f); err == nil && info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest
+}
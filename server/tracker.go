@@ -0,0 +1,286 @@
+package server
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/ryane/takethe53/awsclient"
+)
+
+// ChangeStatusFailed is the status of a tracked change that could not be
+// followed to INSYNC, e.g. because it took longer than the tracking timeout.
+const ChangeStatusFailed = "FAILED"
+
+// trackedChangeRetention is how long finished changes can still be looked
+// up.
+const trackedChangeRetention = time.Hour
+
+const (
+	// eventHistory is how many status events are kept for subscribers that
+	// reconnect.
+	eventHistory = 256
+	// subscriptionBuffer is how many events a subscriber can fall behind
+	// before it is dropped.
+	subscriptionBuffer = 64
+)
+
+// TrackedChange is a change the server submitted.
+type TrackedChange struct {
+	ID          string    `json:"id"`
+	Zone        string    `json:"zone"`
+	Op          string    `json:"op"`
+	Status      string    `json:"status"`
+	SubmittedAt time.Time `json:"submitted_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	Error       string    `json:"error,omitempty"`
+}
+
+// Tracker follows submitted changes until they are INSYNC.
+type Tracker struct {
+	client   *awsclient.AWSClient
+	interval time.Duration
+	timeout  time.Duration
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	onTimeout func(TrackedChange)
+
+	mu          sync.Mutex
+	changes     map[string]*TrackedChange
+	seq         uint64
+	events      []*StatusEvent
+	subscribers map[*Subscription]struct{}
+}
+
+// StatusEvent is published when a tracked change is submitted and when its
+// status changes.
+type StatusEvent struct {
+	// Seq increases with every event.
+	Seq    uint64
+	Change TrackedChange
+}
+
+// Subscription receives status events.
+type Subscription struct {
+	// Events is closed when the subscription is closed, or when the
+	// subscriber falls too far behind.
+	Events <-chan *StatusEvent
+
+	events  chan *StatusEvent
+	zone    string
+	tracker *Tracker
+}
+
+func NewTracker(client *awsclient.AWSClient, interval, timeout time.Duration) *Tracker {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Tracker{
+		client:   client,
+		interval: interval,
+		timeout:  timeout,
+		ctx:      ctx,
+		cancel:   cancel,
+		changes:  map[string]*TrackedChange{},
+
+		subscribers: map[*Subscription]struct{}{},
+	}
+}
+
+// Handler returns a change handler that tracks every submitted change.
+func (t *Tracker) Handler() awsclient.ChangeHandler {
+	return func(ctx context.Context, ev *awsclient.ChangeEvent) {
+		if ev.Err == nil && ev.Status != nil {
+			t.Track(ev.Op, ev.Zone, ev.Status)
+		}
+	}
+}
+
+// OnTimeout sets a function that is called when a change is not INSYNC
+// within the tracking timeout. It must be set before changes are tracked.
+func (t *Tracker) OnTimeout(f func(TrackedChange)) {
+	t.onTimeout = f
+}
+
+// Track follows a change in the background.
+func (t *Tracker) Track(op string, zone *awsclient.Zone, status *awsclient.ChangeStatus) {
+	tc := &TrackedChange{
+		ID:          status.ID,
+		Zone:        zone.Name,
+		Op:          op,
+		Status:      status.Status,
+		SubmittedAt: status.SubmittedAt,
+		UpdatedAt:   time.Now(),
+	}
+
+	t.mu.Lock()
+	for id, old := range t.changes {
+		if old.Status != awsclient.ChangeStatusPending && time.Since(old.UpdatedAt) > trackedChangeRetention {
+			delete(t.changes, id)
+		}
+	}
+	t.changes[tc.ID] = tc
+	t.publish(tc)
+	t.mu.Unlock()
+
+	if tc.Status == awsclient.ChangeStatusInSync {
+		return
+	}
+
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+
+		ctx, cancel := context.WithTimeout(t.ctx, t.timeout)
+		defer cancel()
+
+		_, err := t.client.WaitUntilInSync(ctx, tc.ID, t.interval)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{"type": "tracker", "change": tc.ID, "zone": tc.Zone}).Warn("Error tracking change: ", err)
+			t.update(tc.ID, ChangeStatusFailed, err)
+			if err == context.DeadlineExceeded && t.onTimeout != nil {
+				if failed, ok := t.Get(tc.ID); ok {
+					t.onTimeout(*failed)
+				}
+			}
+			return
+		}
+		t.update(tc.ID, awsclient.ChangeStatusInSync, nil)
+	}()
+}
+
+func (t *Tracker) update(id, status string, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	tc := t.changes[id]
+	tc.Status = status
+	tc.UpdatedAt = time.Now()
+	if err != nil {
+		tc.Error = err.Error()
+	}
+	t.publish(tc)
+}
+
+// publish sends the change's current state to the subscribers. t.mu must be
+// held.
+func (t *Tracker) publish(tc *TrackedChange) {
+	t.seq++
+	ev := &StatusEvent{Seq: t.seq, Change: *tc}
+
+	t.events = append(t.events, ev)
+	if len(t.events) > eventHistory {
+		t.events = t.events[len(t.events)-eventHistory:]
+	}
+
+	for sub := range t.subscribers {
+		if !sub.matches(ev) {
+			continue
+		}
+		select {
+		case sub.events <- ev:
+		default:
+			logrus.WithFields(logrus.Fields{"type": "tracker", "zone": sub.zone}).Warn("Dropping slow subscriber")
+			t.unsubscribe(sub)
+		}
+	}
+}
+
+// Subscribe returns a subscription to the status events of changes in zone,
+// or of all changes if zone is empty. Retained events after afterSeq are
+// replayed first, so a subscriber that reconnects with the last Seq it saw
+// doesn't miss events. Pass 0 to only receive new events.
+func (t *Tracker) Subscribe(zone string, afterSeq uint64) *Subscription {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if zone != "" && !strings.HasSuffix(zone, ".") {
+		zone += "."
+	}
+
+	events := make(chan *StatusEvent, subscriptionBuffer+eventHistory)
+	sub := &Subscription{Events: events, events: events, zone: zone, tracker: t}
+
+	if afterSeq > 0 {
+		for _, ev := range t.events {
+			if ev.Seq > afterSeq && sub.matches(ev) {
+				events <- ev
+			}
+		}
+	}
+
+	t.subscribers[sub] = struct{}{}
+	return sub
+}
+
+// Close stops the subscription.
+func (s *Subscription) Close() {
+	s.tracker.mu.Lock()
+	defer s.tracker.mu.Unlock()
+	s.tracker.unsubscribe(s)
+}
+
+func (s *Subscription) matches(ev *StatusEvent) bool {
+	return s.zone == "" || strings.EqualFold(s.zone, ev.Change.Zone)
+}
+
+// unsubscribe removes a subscriber. t.mu must be held.
+func (t *Tracker) unsubscribe(sub *Subscription) {
+	if _, ok := t.subscribers[sub]; ok {
+		delete(t.subscribers, sub)
+		close(sub.events)
+	}
+}
+
+// Get returns a copy of a tracked change. The ID may leave out the
+// "/change/" prefix Route53 returns.
+func (t *Tracker) Get(id string) (*TrackedChange, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	tc, ok := t.changes[id]
+	if !ok {
+		tc, ok = t.changes["/change/"+id]
+	}
+	if !ok {
+		return nil, false
+	}
+	c := *tc
+	return &c, true
+}
+
+// InFlight returns the number of changes that are not INSYNC yet.
+func (t *Tracker) InFlight() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	n := 0
+	for _, tc := range t.changes {
+		if tc.Status == awsclient.ChangeStatusPending {
+			n++
+		}
+	}
+	return n
+}
+
+// Drain waits until every tracked change is INSYNC or failed. When ctx is
+// done first, tracking is stopped and the remaining changes fail.
+func (t *Tracker) Drain(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		t.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		t.cancel()
+		<-done
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,92 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ryane/takethe53/awsclient"
+	"github.com/ryane/takethe53/awsclient/fake"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTrackedClient(syncDelay, timeout time.Duration) (*awsclient.AWSClient, *Tracker, *awsclient.Zone) {
+	r53 := fake.NewRoute53()
+	r53.SyncDelay = syncDelay
+	zoneID, _ := r53.AddZone("example.com")
+
+	client := awsclient.NewWithServices(r53, fake.NewELB())
+	tracker := NewTracker(client, 5*time.Millisecond, timeout)
+	client.AddChangeHandler(tracker.Handler())
+
+	return client, tracker, &awsclient.Zone{ID: zoneID, Name: "example.com."}
+}
+
+func TestTrackerDrain(t *testing.T) {
+	client, tracker, zone := newTrackedClient(30*time.Millisecond, time.Second)
+
+	change, err := client.SetAlias(zone, "Z35SXDOTRQ7X7K", "web-1.us-east-1.elb.amazonaws.com", "www")
+	assert.Nil(t, err)
+
+	tc, ok := tracker.Get(change.ID)
+	assert.True(t, ok)
+	assert.Equal(t, awsclient.ChangeStatusPending, tc.Status)
+	assert.Equal(t, awsclient.OpSetAlias, tc.Op)
+	assert.Equal(t, 1, tracker.InFlight())
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.Nil(t, tracker.Drain(ctx))
+
+	tc, _ = tracker.Get(change.ID)
+	assert.Equal(t, awsclient.ChangeStatusInSync, tc.Status)
+	assert.Equal(t, 0, tracker.InFlight())
+}
+
+func TestTrackerDrainTimeout(t *testing.T) {
+	client, tracker, zone := newTrackedClient(time.Hour, time.Hour)
+
+	change, err := client.SetAlias(zone, "Z35SXDOTRQ7X7K", "web-1.us-east-1.elb.amazonaws.com", "www")
+	assert.Nil(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, tracker.Drain(ctx))
+
+	tc, _ := tracker.Get(change.ID)
+	assert.Equal(t, ChangeStatusFailed, tc.Status)
+	assert.NotEmpty(t, tc.Error)
+}
+
+func TestTrackerSubscribe(t *testing.T) {
+	client, tracker, zone := newTrackedClient(10*time.Millisecond, time.Second)
+
+	all := tracker.Subscribe("", 0)
+	defer all.Close()
+	other := tracker.Subscribe("example.org", 0)
+	defer other.Close()
+
+	change, err := client.SetAlias(zone, "Z35SXDOTRQ7X7K", "web-1.us-east-1.elb.amazonaws.com", "www")
+	assert.Nil(t, err)
+
+	ev := <-all.Events
+	assert.Equal(t, change.ID, ev.Change.ID)
+	assert.Equal(t, awsclient.ChangeStatusPending, ev.Change.Status)
+
+	select {
+	case ev = <-all.Events:
+	case <-time.After(time.Second):
+		t.Fatal("no INSYNC event")
+	}
+	assert.Equal(t, awsclient.ChangeStatusInSync, ev.Change.Status)
+	assert.Len(t, other.Events, 0)
+
+	// reconnecting replays the events after the last one seen
+	replay := tracker.Subscribe("example.com", ev.Seq-1)
+	defer replay.Close()
+	assert.Equal(t, ev.Seq, (<-replay.Events).Seq)
+
+	all.Close()
+	_, ok := <-all.Events
+	assert.False(t, ok)
+}